@@ -0,0 +1,40 @@
+package benchmarks
+
+import (
+	"testing"
+
+	"github.com/tuan78/jsonconv/v2"
+)
+
+func sampleArray() []map[string]any {
+	arr := make([]map[string]any, 0, 100)
+	for i := 0; i < 100; i++ {
+		arr = append(arr, sampleObject())
+	}
+	return arr
+}
+
+func BenchmarkToCsv(b *testing.B) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			data := sampleArray()
+			jsonconv.ToCsv(data, &jsonconv.ToCsvOption{
+				FlattenOption: jsonconv.DefaultFlattenOption,
+			})
+		}
+	})
+}
+
+func BenchmarkToCsv_KeepInput(b *testing.B) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			data := sampleArray()
+			jsonconv.ToCsv(data, &jsonconv.ToCsvOption{
+				FlattenOption: jsonconv.DefaultFlattenOption,
+				KeepInput:     true,
+			})
+		}
+	})
+}
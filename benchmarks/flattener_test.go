@@ -18,3 +18,16 @@ func BenchmarkFlatten(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkFlattened(b *testing.B) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			data := sampleObject()
+			_, _ = jsonconv.Flattened(data, &jsonconv.FlattenOption{
+				Level: jsonconv.FlattenLevelUnlimited,
+				Gap:   "__",
+			})
+		}
+	})
+}
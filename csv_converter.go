@@ -0,0 +1,85 @@
+package jsonconv
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// A FromCsvOption converts CSV data to a JSON array.
+type FromCsvOption struct {
+	// Set it to apply JSON unflattening to CSV headers
+	FlattenOption *FlattenOption
+
+	// Omit empty CSV cells instead of converting them to empty strings
+	OmitEmpty bool
+
+	// Convert CSV cells which look like JSON numbers, booleans or null to
+	// json.Number, bool and nil. Other cells are kept as strings
+	InferTypes bool
+}
+
+// FromCsv converts [][]string to a JSON array with given opt.
+// The first record of data is used as headers.
+//
+// If opt.FlattenOption is set, headers are unflattened using its gap and the
+// "[i]" syntax for array elements. If the headers of a record cannot be
+// unflattened (see Unflatten), it returns the error with the row of the record,
+// where the headers are row 1.
+func FromCsv(data [][]string, opt *FromCsvOption) ([]map[string]any, error) {
+	if len(data) < 2 {
+		return []map[string]any{}, nil
+	}
+	if opt == nil {
+		opt = &FromCsvOption{}
+	}
+
+	hs := data[0]
+	arr := make([]map[string]any, 0, len(data)-1)
+	for r, row := range data[1:] {
+		obj := make(map[string]any, len(hs))
+		for i, h := range hs {
+			if i >= len(row) {
+				break
+			}
+			cell := row[i]
+			if cell == "" && opt.OmitEmpty {
+				continue
+			}
+			if opt.InferTypes {
+				obj[h] = inferCsvValue(cell)
+				continue
+			}
+			obj[h] = cell
+		}
+		if opt.FlattenOption != nil {
+			if err := Unflatten(obj, opt.FlattenOption); err != nil {
+				return nil, fmt.Errorf("row %d: %w", r+2, err)
+			}
+		}
+		arr = append(arr, obj)
+	}
+	return arr, nil
+}
+
+// inferCsvValue converts s to a JSON value if it is a JSON number, boolean or null.
+func inferCsvValue(s string) any {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	case "":
+		return s
+	}
+	first, last := s[0], s[len(s)-1]
+	if (first == '-' || isDigit(first)) && isDigit(last) && json.Valid([]byte(s)) {
+		return json.Number(s)
+	}
+	return s
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
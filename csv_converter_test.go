@@ -0,0 +1,157 @@
+package jsonconv
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFromCsv_EmptyData(t *testing.T) {
+	// Prepare
+	data := [][]string{
+		{"id", "user"},
+	}
+
+	// Process
+	arr, err := FromCsv(data, nil)
+
+	// Check
+	if err != nil || len(arr) != 0 {
+		t.Fatalf("It should be safe to put CSV data without records as param")
+	}
+}
+
+func TestFromCsv_NonUnflatten(t *testing.T) {
+	// Prepare
+	data := [][]string{
+		{"id", "user", "nested__a", "f[0]"},
+		{"b042ab5c-ca73-4460-b739-96410ea9d3a6", "Jon Doe", "1", ""},
+		{"ce06f5b1-5721-42c0-91e1-9f72a09c250a", "Tuấn"},
+	}
+
+	// Process
+	arr, err := FromCsv(data, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert CSV, err: %v", err)
+	}
+	expected := []map[string]any{
+		{
+			"id":        "b042ab5c-ca73-4460-b739-96410ea9d3a6",
+			"user":      "Jon Doe",
+			"nested__a": "1",
+			"f[0]":      "",
+		},
+		{
+			"id":   "ce06f5b1-5721-42c0-91e1-9f72a09c250a",
+			"user": "Tuấn",
+		},
+	}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Fatalf("converted JSON array is incorrect, %v is not equal expected value %v", arr, expected)
+	}
+}
+
+func TestFromCsv_WithUnflattening(t *testing.T) {
+	// Prepare
+	data := [][]string{
+		{"id", "is active", "nested|a", "nested|b", "nested|f[0]", "nested|f[1]", "score", "user"},
+		{"b042ab5c-ca73-4460-b739-96410ea9d3a6", "false", "1", "x", "4", "5", "-100", "Jon Doe"},
+		{"ce06f5b1-5721-42c0-91e1-9f72a09c250a", "true", "", "", "4", "", "100000000000000000000000", "null"},
+	}
+
+	// Process
+	arr, err := FromCsv(data, &FromCsvOption{
+		FlattenOption: &FlattenOption{
+			Level: FlattenLevelUnlimited,
+			Gap:   "|",
+		},
+		OmitEmpty:  true,
+		InferTypes: true,
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert CSV, err: %v", err)
+	}
+	expected := []map[string]any{
+		{
+			"id":        "b042ab5c-ca73-4460-b739-96410ea9d3a6",
+			"is active": false,
+			"nested": map[string]any{
+				"a": json.Number("1"),
+				"b": "x",
+				"f": []any{json.Number("4"), json.Number("5")},
+			},
+			"score": json.Number("-100"),
+			"user":  "Jon Doe",
+		},
+		{
+			"id":        "ce06f5b1-5721-42c0-91e1-9f72a09c250a",
+			"is active": true,
+			"nested": map[string]any{
+				"f": []any{json.Number("4")},
+			},
+			"score": json.Number("100000000000000000000000"),
+			"user":  nil,
+		},
+	}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Fatalf("converted JSON array is incorrect, %v is not equal expected value %v", arr, expected)
+	}
+}
+
+func TestFromCsv_UnflatteningError(t *testing.T) {
+	// Prepare
+	data := [][]string{
+		{"a", "a__b"},
+		{"", ""},
+		{"1", "2"},
+	}
+
+	// Process
+	arr, err := FromCsv(data, &FromCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		OmitEmpty:     true,
+	})
+
+	// Check
+	expMsg := `row 3: conflicting flattened keys: "a__b"`
+	if err == nil || err.Error() != expMsg || !errors.Is(err, ErrUnflattenConflict) {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+	if arr != nil {
+		t.Fatalf("It should not return a JSON array with an error, current: %v", arr)
+	}
+}
+
+func TestInferCsvValue(t *testing.T) {
+	// Prepare
+	cases := map[string]any{
+		"":      "",
+		"true":  true,
+		"false": false,
+		"null":  nil,
+		"0":     json.Number("0"),
+		"-1.5":  json.Number("-1.5"),
+		"1e21":  json.Number("1e21"),
+		"01":    "01",
+		"+1":    "+1",
+		"1 ":    "1 ",
+		"-":     "-",
+		"NaN":   "NaN",
+		"True":  "True",
+	}
+
+	for s, expected := range cases {
+		// Process
+		v := inferCsvValue(s)
+
+		// Check
+		if v != expected {
+			t.Fatalf("inferred value is incorrect, %#v is not equal expected value %#v for %q", v, expected, s)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package jsonconv
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// A CsvReader reads records from a CSV-encoded input stream.
+type CsvReader struct {
+	reader io.Reader
+
+	// Field delimiter. Set to ',' (CsvComma) by default in NewCsvReader
+	Delimiter rune
+
+	// If LazyQuotes is true, a quote may appear in an unquoted field and a
+	// non-doubled quote may appear in a quoted field
+	LazyQuotes bool
+}
+
+// NewCsvReader returns a new CsvReader that reads from r.
+func NewCsvReader(r io.Reader) *CsvReader {
+	return &CsvReader{
+		reader:    r,
+		Delimiter: CsvComma,
+	}
+}
+
+// Read reads all the remaining CSV records from r.
+// Every record must have the same number of fields as the first one.
+func (r *CsvReader) Read() ([][]string, error) {
+	reader := csv.NewReader(r.reader)
+	reader.Comma = r.Delimiter
+	reader.LazyQuotes = r.LazyQuotes
+	return reader.ReadAll()
+}
@@ -0,0 +1,69 @@
+package jsonconv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCsvReader_InvalidDelimiter(t *testing.T) {
+	// Prepare
+	raw := "id,user\n1,Jon Doe\n"
+	re := NewCsvReader(strings.NewReader(raw))
+	re.Delimiter = '\n'
+
+	// Process
+	_, err := re.Read()
+
+	// Check
+	if err == nil {
+		t.Fatalf("Should throw an error for invalid delimiter")
+	}
+}
+
+func TestCsvReader_WrongNumberOfFields(t *testing.T) {
+	// Prepare
+	raw := "id,user\n1,Jon Doe,extra\n"
+	re := NewCsvReader(strings.NewReader(raw))
+
+	// Process
+	_, err := re.Read()
+
+	// Check
+	if err == nil {
+		t.Fatalf("Should throw an error for wrong number of fields")
+	}
+}
+
+func TestCsvReader(t *testing.T) {
+	// Prepare
+	raw := `id|user|score|is active
+ce06f5b1-5721-42c0-91e1-9f72a09c250a|Tuấn|1.5|true
+b042ab5c-ca73-4460-b739-96410ea9d3a6|Jon Doe|-100|false
+3fbae214-006d-4ac5-9eea-76c5d611f54a|"Quoted|"|0|false
+`
+	re := NewCsvReader(strings.NewReader(raw))
+	re.Delimiter = '|'
+
+	// Process
+	data, err := re.Read()
+	if err != nil {
+		t.Fatalf("failed to read csv, err: %v", err)
+	}
+
+	// Check
+	if len(data) != 4 {
+		t.Fatalf("csv data has wrong length %v", len(data))
+	}
+	expected := []string{
+		"id,user,score,is active",
+		"ce06f5b1-5721-42c0-91e1-9f72a09c250a,Tuấn,1.5,true",
+		"b042ab5c-ca73-4460-b739-96410ea9d3a6,Jon Doe,-100,false",
+		"3fbae214-006d-4ac5-9eea-76c5d611f54a,Quoted|,0,false",
+	}
+	for i, row := range data {
+		s := strings.Join(row, ",")
+		if s != expected[i] {
+			t.Fatalf("csv row is incorrect, %s is not equal expected %s", s, expected[i])
+		}
+	}
+}
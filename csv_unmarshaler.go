@@ -0,0 +1,474 @@
+package jsonconv
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ErrUnknownCsvHeader is the error of the *CsvUnmarshalError returned by UnmarshalCsv
+// with DisallowUnknownHeaders for a CSV header which matches no field (use errors.Is).
+var ErrUnknownCsvHeader = errors.New("unknown CSV header")
+
+// An UnmarshalCsvOption decodes CSV data into Go values with UnmarshalCsv.
+type UnmarshalCsvOption struct {
+	// Field delimiter. CsvComma is used if it is zero
+	Delimiter rune
+
+	// Options of flattened CSV headers, i.e. Gap, ArrayKeyStyle, OneBasedIndex
+	// and KeyCollision. DefaultFlattenOption is used if it is nil
+	FlattenOption *FlattenOption
+
+	// Return ErrUnknownCsvHeader for a CSV header which matches no field of T,
+	// before any record is decoded, instead of ignoring its column
+	DisallowUnknownHeaders bool
+}
+
+// A CsvUnmarshalError describes a CSV cell which UnmarshalCsv failed to decode,
+// or an unknown CSV header (see ErrUnknownCsvHeader).
+type CsvUnmarshalError struct {
+	// Row of the cell, where the headers are row 1
+	Row int
+
+	// Column of the cell, starting from 1
+	Column int
+
+	// Header of the column
+	Header string
+
+	Err error
+}
+
+func (e *CsvUnmarshalError) Error() string {
+	return fmt.Sprintf("row %d, column %d (%q): %v", e.Row, e.Column, e.Header, e.Err)
+}
+
+func (e *CsvUnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// UnmarshalCsv reads CSV data from r and decodes every record after the headers
+// into a T, which is typically a struct. Headers are unflattened with the options
+// of opt.FlattenOption (see Unflatten), and matched to nested struct fields by
+// their `json` struct tags like encoding/json does, or to map keys and slice
+// indices. Cells are converted to the types of their fields: numbers, booleans,
+// strings, types implementing encoding.TextUnmarshaler (e.g. time.Time) or
+// json.Unmarshaler, and JSON-encoded objects and arrays. Empty cells leave their
+// fields with zero values. Errors of cells are returned as *CsvUnmarshalError,
+// and so are headers whose slice indices have a gap (ErrUnflattenSparseArray)
+// or exceed the number of columns.
+// If opt is nil, default options are used.
+func UnmarshalCsv[T any](r io.Reader, opt *UnmarshalCsvOption) ([]T, error) {
+	if opt == nil {
+		opt = &UnmarshalCsvOption{}
+	}
+	fo := opt.FlattenOption
+	if fo == nil {
+		fo = DefaultFlattenOption
+	}
+
+	cr := csv.NewReader(r)
+	if opt.Delimiter != 0 {
+		cr.Comma = opt.Delimiter
+	}
+	hs, err := cr.Read()
+	if err == io.EOF {
+		return []T{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Resolve headers against T once, so that unknown ones are found before any record.
+	segs := make([][]keySegment, len(hs))
+	known := make([]bool, len(hs))
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	for i, h := range hs {
+		segs[i] = parseFlattenedKey(h, fo)
+		known[i] = knownCsvHeader(typ, segs[i])
+		if !known[i] && opt.DisallowUnknownHeaders {
+			return nil, &CsvUnmarshalError{Row: 1, Column: i + 1, Header: h, Err: ErrUnknownCsvHeader}
+		}
+	}
+	if err := checkCsvSliceIndices(typ, hs, segs, known, fo); err != nil {
+		return nil, err
+	}
+
+	res := []T{}
+	for row := 2; ; row++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var v T
+		dst := reflect.ValueOf(&v).Elem()
+		for col, cell := range rec {
+			if cell == "" || !known[col] {
+				continue
+			}
+			if err := setCsvCell(dst, segs[col], cell); err != nil {
+				return nil, &CsvUnmarshalError{Row: row, Column: col + 1, Header: hs[col], Err: err}
+			}
+		}
+		res = append(res, v)
+	}
+}
+
+// knownCsvHeader reports whether the path segs of a CSV header matches a field of type t.
+// Headers which do not match are the ones setCsvCell returns ErrUnknownCsvHeader for.
+func knownCsvHeader(t reflect.Type, segs []keySegment) bool {
+	for ; len(segs) > 0; segs = segs[1:] {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			if segs[0].isIdx {
+				return true
+			}
+			f, ok := findStructField(cachedStructFields(t), segs[0].key)
+			if !ok {
+				return false
+			}
+			t = t.FieldByIndex(f.index).Type
+		case reflect.Map, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+// checkCsvSliceIndices checks the indices of slices in the known headers hs, whose paths
+// are segs, so that slices are never grown beyond the number of columns. Like Unflatten,
+// it returns ErrUnflattenSparseArray if the indices of a slice have a gap.
+func checkCsvSliceIndices(t reflect.Type, hs []string, segs [][]keySegment, known []bool, opt *FlattenOption) error {
+	idxs := make(map[string]map[int]struct{})
+	for i, h := range hs {
+		if !known[i] {
+			continue
+		}
+		for _, j := range csvSliceIndices(t, segs[i]) {
+			if idx := segs[i][j].idx; idx >= len(hs) {
+				err := fmt.Errorf("index %d out of range of %d columns", idx, len(hs))
+				return &CsvUnmarshalError{Row: 1, Column: i + 1, Header: h, Err: err}
+			}
+			path := csvSegmentsPath(segs[i][:j], opt.Gap)
+			if idxs[path] == nil {
+				idxs[path] = make(map[int]struct{})
+			}
+			idxs[path][segs[i][j].idx] = struct{}{}
+		}
+	}
+
+	for i, h := range hs {
+		if !known[i] {
+			continue
+		}
+		for _, j := range csvSliceIndices(t, segs[i]) {
+			path := csvSegmentsPath(segs[i][:j], opt.Gap)
+			set := idxs[path]
+			if segs[i][j].idx < len(set) {
+				continue
+			}
+			missing := 0
+			for _, exist := set[missing]; exist; _, exist = set[missing] {
+				missing++
+			}
+			err := fmt.Errorf("%w: %q is missing index %d", ErrUnflattenSparseArray, path, missing)
+			return &CsvUnmarshalError{Row: 1, Column: i + 1, Header: h, Err: err}
+		}
+	}
+	return nil
+}
+
+// csvSliceIndices returns the positions of the segments of segs which are indices
+// of slices when decoded into type t, including slices of empty interfaces.
+func csvSliceIndices(t reflect.Type, segs []keySegment) []int {
+	var res []int
+	dynamic := false
+	for j, seg := range segs {
+		if dynamic {
+			if seg.isIdx {
+				res = append(res, j)
+			}
+			continue
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := findStructField(cachedStructFields(t), seg.key)
+			if seg.isIdx || !ok {
+				return res
+			}
+			t = t.FieldByIndex(f.index).Type
+		case reflect.Slice:
+			if seg.isIdx {
+				res = append(res, j)
+			}
+			t = t.Elem()
+		case reflect.Map, reflect.Array:
+			t = t.Elem()
+		case reflect.Interface:
+			dynamic = true
+			if seg.isIdx {
+				res = append(res, j)
+			}
+		default:
+			return res
+		}
+	}
+	return res
+}
+
+// csvSegmentsPath returns the path segs joined like the errors of Unflatten, e.g. "a__b[0]".
+func csvSegmentsPath(segs []keySegment, gap string) string {
+	path := ""
+	for _, seg := range segs {
+		switch {
+		case seg.isIdx:
+			path = fmt.Sprintf("%s[%d]", path, seg.idx)
+		case path == "":
+			path = seg.key
+		default:
+			path += gap + seg.key
+		}
+	}
+	return path
+}
+
+// setCsvCell sets the value at path segs of dst, which must be settable, from cell.
+func setCsvCell(dst reflect.Value, segs []keySegment, cell string) error {
+	if len(segs) == 0 {
+		return setCsvValue(dst, cell)
+	}
+	seg := segs[0]
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return setCsvCell(dst.Elem(), segs, cell)
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return fmt.Errorf("cannot decode into %v", dst.Type())
+		}
+		// Nested values of interfaces are decoded as JSON objects and JSON arrays.
+		var cur reflect.Value
+		switch {
+		case !dst.IsNil() && reflect.TypeOf(dst.Interface()) == reflect.TypeOf(map[string]any{}) && !seg.isIdx:
+			cur = reflect.ValueOf(dst.Interface())
+		case !dst.IsNil() && reflect.TypeOf(dst.Interface()) == reflect.TypeOf([]any{}) && seg.isIdx:
+			cur = reflect.New(reflect.TypeOf([]any{})).Elem()
+			cur.Set(reflect.ValueOf(dst.Interface()))
+		case !dst.IsNil():
+			return fmt.Errorf("cannot decode nested value into %T", dst.Interface())
+		case seg.isIdx:
+			cur = reflect.New(reflect.TypeOf([]any{})).Elem()
+		default:
+			cur = reflect.ValueOf(map[string]any{})
+		}
+		if err := setCsvCell(cur, segs, cell); err != nil {
+			return err
+		}
+		dst.Set(cur)
+		return nil
+	case reflect.Struct:
+		if seg.isIdx {
+			return fmt.Errorf("cannot decode array element into %v", dst.Type())
+		}
+		fields := cachedStructFields(dst.Type())
+		f, ok := findStructField(fields, seg.key)
+		if !ok {
+			return ErrUnknownCsvHeader
+		}
+		fv, err := structFieldForSet(dst, f.index)
+		if err != nil {
+			return err
+		}
+		return setCsvCell(fv, segs[1:], cell)
+	case reflect.Map:
+		if seg.isIdx {
+			return fmt.Errorf("cannot decode array element into %v", dst.Type())
+		}
+		key, err := mapKeyValue(dst.Type().Key(), seg.key)
+		if err != nil {
+			return err
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		elem := reflect.New(dst.Type().Elem()).Elem()
+		if old := dst.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := setCsvCell(elem, segs[1:], cell); err != nil {
+			return err
+		}
+		dst.SetMapIndex(key, elem)
+		return nil
+	case reflect.Slice:
+		if !seg.isIdx {
+			return fmt.Errorf("cannot decode object key %q into %v", seg.key, dst.Type())
+		}
+		if seg.idx >= dst.Len() {
+			grown := reflect.MakeSlice(dst.Type(), seg.idx+1, seg.idx+1)
+			reflect.Copy(grown, dst)
+			dst.Set(grown)
+		}
+		return setCsvCell(dst.Index(seg.idx), segs[1:], cell)
+	case reflect.Array:
+		if !seg.isIdx {
+			return fmt.Errorf("cannot decode object key %q into %v", seg.key, dst.Type())
+		}
+		if seg.idx >= dst.Len() {
+			return fmt.Errorf("index %d out of range of %v", seg.idx, dst.Type())
+		}
+		return setCsvCell(dst.Index(seg.idx), segs[1:], cell)
+	}
+	return fmt.Errorf("cannot decode nested value into %v", dst.Type())
+}
+
+// setCsvValue converts cell to the type of dst and sets it.
+func setCsvValue(dst reflect.Value, cell string) error {
+	if dst.Kind() != reflect.Pointer && dst.CanAddr() {
+		pt := dst.Addr().Type()
+		if pt.Implements(textUnmarshalerType) {
+			return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(cell))
+		}
+		if pt.Implements(jsonUnmarshalerType) {
+			data := []byte(cell)
+			if !json.Valid(data) {
+				data = []byte(strconv.Quote(cell))
+			}
+			return dst.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(data)
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return setCsvValue(dst.Elem(), cell)
+	case reflect.String:
+		dst.SetString(cell)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", cell)
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell, 10, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid %v %q", dst.Type(), cell)
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(cell, 10, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid %v %q", dst.Type(), cell)
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(cell, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid %v %q", dst.Type(), cell)
+		}
+		dst.SetFloat(n)
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return fmt.Errorf("cannot decode into %v", dst.Type())
+		}
+		dst.Set(reflect.ValueOf(inferCsvValue(cell)))
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		// Such cells are JSON-encoded, see FormatCsvValue.
+		if err := json.Unmarshal([]byte(cell), dst.Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid JSON for %v, %v", dst.Type(), err)
+		}
+	default:
+		return fmt.Errorf("cannot decode into %v", dst.Type())
+	}
+	return nil
+}
+
+// findStructField returns the field named key, preferring an exact match
+// to a case-insensitive one like encoding/json does.
+func findStructField(fields []structField, key string) (structField, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return structField{}, false
+}
+
+// structFieldForSet returns the field of struct v at index,
+// allocating nil pointers to embedded structs on the way.
+func structFieldForSet(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// mapKeyValue converts key to a map key of type t.
+func mapKeyValue(t reflect.Type, key string) (reflect.Value, error) {
+	kv := reflect.New(t).Elem()
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		err := kv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key))
+		return kv, err
+	}
+	switch t.Kind() {
+	case reflect.String:
+		kv.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return kv, fmt.Errorf("invalid %v map key %q", t, key)
+		}
+		kv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return kv, fmt.Errorf("invalid %v map key %q", t, key)
+		}
+		kv.SetUint(n)
+	default:
+		return kv, fmt.Errorf("unsupported map key type %v", t)
+	}
+	return kv, nil
+}
@@ -0,0 +1,185 @@
+package jsonconv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalCsv(t *testing.T) {
+	// Prepare
+	raw := `id,created,name,-,address__city,tags[0],tags[1],labels__1,Untagged,extra
+1,2024-01-02T03:04:05Z,Jon Doe,-,HCM,a,b,x,false,ignored
+2,,Tuấn,,,c,,,true,`
+
+	// Process
+	users, err := UnmarshalCsv[testUser](strings.NewReader(raw), nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unmarshal CSV, err: %v", err)
+	}
+	first := sampleUser()
+	first.Password = ""
+	first.internal = ""
+	expected := []testUser{
+		first,
+		{testBase: testBase{ID: 2}, Name: "Tuấn", Tags: []string{"c"}, Untagged: true},
+	}
+	if !reflect.DeepEqual(users, expected) {
+		t.Fatalf("unmarshaled structs are incorrect, %+v is not equal expected value %+v", users, expected)
+	}
+}
+
+func TestUnmarshalCsv_RoundTrip(t *testing.T) {
+	// Prepare
+	type item struct {
+		Sku   string  `json:"sku"`
+		Price float64 `json:"price"`
+	}
+	type order struct {
+		ID     *int            `json:"id"`
+		Items  []item          `json:"items"`
+		Scores [2]uint8        `json:"scores"`
+		Meta   map[string]any  `json:"meta"`
+		Extra  json.RawMessage `json:"extra"`
+		Any    any             `json:"any"`
+	}
+	id := 7
+	orders := []order{{
+		ID:     &id,
+		Items:  []item{{Sku: "A1", Price: 1.5}, {Sku: "B2", Price: 2}},
+		Scores: [2]uint8{3, 4},
+		Meta:   map[string]any{"source": "web", "tags": []any{"x"}},
+		Extra:  json.RawMessage(`{"k":1}`),
+		Any:    map[string]any{"n": json.Number("1")},
+	}}
+	opt := &FlattenOption{Level: FlattenLevelUnlimited, Gap: ".", ArrayKeyStyle: ArrayKeyGap, OneBasedIndex: true}
+	data, err := ToCsvStructs(orders, &ToCsvOption{FlattenOption: opt})
+	if err != nil {
+		t.Fatalf("failed to convert structs to CSV, err: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := NewCsvWriter(buf).Write(data); err != nil {
+		t.Fatalf("failed to write CSV, err: %v", err)
+	}
+
+	// Process
+	res, err := UnmarshalCsv[*order](buf, &UnmarshalCsvOption{FlattenOption: opt})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unmarshal CSV, err: %v", err)
+	}
+	orders[0].Meta["tags"] = []any{"x"}
+	if len(res) != 1 || !reflect.DeepEqual(*res[0], orders[0]) {
+		t.Fatalf("unmarshaled structs are incorrect, %+v is not equal expected value %+v", *res[0], orders[0])
+	}
+}
+
+func TestUnmarshalCsv_Errors(t *testing.T) {
+	// Prepare
+	type row struct {
+		ID    int       `json:"id"`
+		OK    bool      `json:"ok"`
+		Tags  []string  `json:"tags"`
+		Times [1]string `json:"times"`
+		At    time.Time `json:"at"`
+	}
+	cases := map[string]string{
+		"id,ok\n1,true\nx,true":             `row 3, column 1 ("id"): invalid int "x"`,
+		"id,ok\n1,yes":                      `row 2, column 2 ("ok"): invalid bool "yes"`,
+		"tags__a\nx":                        `row 2, column 1 ("tags__a"): cannot decode object key "a" into []string`,
+		"id[0]\n1":                          `row 2, column 1 ("id[0]"): cannot decode nested value into int`,
+		"times[1]\nx":                       `row 2, column 1 ("times[1]"): index 1 out of range of [1]string`,
+		"at\nyesterday":                     `row 2, column 1 ("at"): parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`,
+		"tags\nx":                           `row 2, column 1 ("tags"): invalid JSON for []string, invalid character 'x' looking for beginning of value`,
+		"id,unknown\n1,x":                   `row 1, column 2 ("unknown"): unknown CSV header`,
+		"id,unknown\n1,":                    `row 1, column 2 ("unknown"): unknown CSV header`,
+		"id,at__x":                          `row 1, column 2 ("at__x"): unknown CSV header`,
+		"unknown":                           `row 1, column 1 ("unknown"): unknown CSV header`,
+		"id,tags[9223372036854775807]\n1,x": `row 1, column 2 ("tags[9223372036854775807]"): index 9223372036854775807 out of range of 2 columns`,
+		"tags[2000000000]\nx":               `row 1, column 1 ("tags[2000000000]"): index 2000000000 out of range of 1 columns`,
+		"tags[0],tags[2],id\nx,y,1":         `row 1, column 2 ("tags[2]"): sparse array indices: "tags" is missing index 1`,
+	}
+
+	for raw, expMsg := range cases {
+		// Process
+		_, err := UnmarshalCsv[row](strings.NewReader(raw), &UnmarshalCsvOption{DisallowUnknownHeaders: true})
+
+		// Check
+		var cerr *CsvUnmarshalError
+		if !errors.As(err, &cerr) || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestUnmarshalCsv_SparseIndices(t *testing.T) {
+	// Prepare
+	cases := map[string]string{
+		"a[1],b\nx,y":           `row 1, column 1 ("a[1]"): sparse array indices: "a" is missing index 0`,
+		"a__b[0],a__b[2],c\n,,": `row 1, column 2 ("a__b[2]"): sparse array indices: "a__b" is missing index 1`,
+		"a[0][1],a[1]\nx,y":     `row 1, column 1 ("a[0][1]"): sparse array indices: "a[0]" is missing index 0`,
+	}
+
+	for raw, expMsg := range cases {
+		// Process
+		_, err := UnmarshalCsv[map[string]any](strings.NewReader(raw), nil)
+
+		// Check
+		if !errors.Is(err, ErrUnflattenSparseArray) || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestUnmarshalCsv_InvalidCsv(t *testing.T) {
+	// Prepare
+	raw := "id;ok\n1;true;x"
+
+	// Process
+	_, err := UnmarshalCsv[map[string]any](strings.NewReader(raw), &UnmarshalCsvOption{Delimiter: ';'})
+
+	// Check
+	var perr *csv.ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("It should throw a CSV parse error, current: %v", err)
+	}
+}
+
+func TestUnmarshalCsv_Map(t *testing.T) {
+	// Prepare
+	raw := "id,a__b,c[0],c[1]__d\n1,x,true,"
+
+	// Process
+	res, err := UnmarshalCsv[map[string]any](strings.NewReader(raw), nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unmarshal CSV, err: %v", err)
+	}
+	expected := []map[string]any{{
+		"id": json.Number("1"),
+		"a":  map[string]any{"b": "x"},
+		"c":  []any{true},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("unmarshaled maps are incorrect, %v is not equal expected value %v", res, expected)
+	}
+}
+
+func TestUnmarshalCsv_Empty(t *testing.T) {
+	// Process
+	res, err := UnmarshalCsv[testUser](strings.NewReader(""), nil)
+
+	// Check
+	if err != nil || len(res) != 0 {
+		t.Fatalf("It should return no values, current: %v, err: %v", res, err)
+	}
+}
@@ -9,6 +9,9 @@ import (
 type CsvWriter struct {
 	writer io.Writer
 
+	// csvWriter is created on the first WriteRecord call and reused until Flush.
+	csvWriter *csv.Writer
+
 	// Field delimiter. Set to ',' (CsvComma) by default in NewCsvWriter
 	Delimiter rune
 
@@ -41,3 +44,23 @@ func (w *CsvWriter) Write(data [][]string) error {
 	}
 	return nil
 }
+
+// WriteRecord writes a single CSV record to w. Records are buffered,
+// so Flush must be called to ensure they are written to the underlying writer.
+func (w *CsvWriter) WriteRecord(record []string) error {
+	if w.csvWriter == nil {
+		w.csvWriter = csv.NewWriter(w.writer)
+		w.csvWriter.Comma = w.Delimiter
+		w.csvWriter.UseCRLF = w.UseCRLF
+	}
+	return w.csvWriter.Write(record)
+}
+
+// Flush writes any buffered records written by WriteRecord to the underlying writer.
+func (w *CsvWriter) Flush() error {
+	if w.csvWriter == nil {
+		return nil
+	}
+	w.csvWriter.Flush()
+	return w.csvWriter.Error()
+}
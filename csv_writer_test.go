@@ -73,3 +73,33 @@ b042ab5c-ca73-4460-b739-96410ea9d3a6|Jon Doe|-100|false
 		t.Fatalf("csv output is not correct")
 	}
 }
+
+func TestCsvWriter_WriteRecord(t *testing.T) {
+	// Prepare
+	data := [][]string{
+		{"id", "user"},
+		{"ce06f5b1-5721-42c0-91e1-9f72a09c250a", "Tuấn"},
+		{"3fbae214-006d-4ac5-9eea-76c5d611f54a", "Comma,"},
+	}
+	buf := &bytes.Buffer{}
+	wr := NewCsvWriter(buf)
+	wr.UseCRLF = true
+
+	// Process
+	for _, record := range data {
+		if err := wr.WriteRecord(record); err != nil {
+			t.Fatalf("failed to write csv record, err: %v", err)
+		}
+	}
+	err := wr.Flush()
+	if err != nil {
+		t.Fatalf("failed to flush csv, err: %v", err)
+	}
+
+	// Check
+	s := buf.String()
+	expect := "id,user\r\nce06f5b1-5721-42c0-91e1-9f72a09c250a,Tuấn\r\n3fbae214-006d-4ac5-9eea-76c5d611f54a,\"Comma,\"\r\n"
+	if s != expect {
+		t.Fatalf("csv output is not correct, current: %q", s)
+	}
+}
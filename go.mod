@@ -3,6 +3,7 @@ module github.com/tuan78/jsonconv/v2
 go 1.22
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.10.2
 	github.com/spf13/pflag v1.0.10
 )
@@ -2,8 +2,12 @@ package cli
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -22,6 +26,31 @@ func NewCsvCmd() *cobra.Command {
 		fga    string
 		fsm    bool
 		fsa    bool
+		stream bool
+		sample int
+		strmHs []string
+		cols   []string
+		excl   []string
+		strict bool
+		rename string
+		null   string
+		vfmt   string
+		hsOrd  string
+		expl   []string
+		explM  string
+		norm   bool
+		keyCol string
+		parCol string
+		ak     string
+		ob     bool
+		kc     string
+		rules  string
+		ofmt   string
+		frzR   int
+		frzC   int
+		hsSty  bool
+		str    bool
+		pqc    string
 	)
 
 	cmd := &cobra.Command{
@@ -29,86 +58,448 @@ func NewCsvCmd() *cobra.Command {
 		Short: "Convert JSON to CSV",
 		Long:  "Convert JSON to CSV",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			inFormat, err := jsonconv.ParseJsonFormat(rootFlags.InputFormat)
+			if err != nil {
+				return err
+			}
+			if vfmt != valueFormatDefault && vfmt != valueFormatGo {
+				return fmt.Errorf("unsupported value format %q", vfmt)
+			}
+			// Array indices are sorted as integers by default when arrays are flattened.
+			if hsOrd == "" {
+				hsOrd = "alphabetical"
+				if !noft && !fsa && flv != jsonconv.FlattenLevelNonNested {
+					hsOrd = "natural"
+				}
+			}
+			hsOrder, err := jsonconv.ParseHeaderOrder(hsOrd)
+			if err != nil {
+				return err
+			}
+			explMode, err := jsonconv.ParseExplodeMode(explM)
+			if err != nil {
+				return err
+			}
+			akStyle, err := jsonconv.ParseArrayKeyStyle(ak)
+			if err != nil {
+				return err
+			}
+			collision, err := jsonconv.ParseKeyCollision(kc)
+			if err != nil {
+				return err
+			}
+			if norm && rootFlags.OutputDir == "" {
+				return fmt.Errorf("need to set '--out-dir' for '--normalize'")
+			}
+			if norm && stream {
+				return fmt.Errorf("cannot use '--normalize' with '--stream'")
+			}
+			if norm && noft {
+				return fmt.Errorf("cannot use '--normalize' with '--noft'")
+			}
+			if ofmt != outputFormatCsv && ofmt != outputFormatXlsx && ofmt != outputFormatParquet {
+				return fmt.Errorf("unsupported output format %q", ofmt)
+			}
+			if ofmt == outputFormatXlsx || ofmt == outputFormatParquet {
+				if stream || norm {
+					return fmt.Errorf("cannot use '--stream' or '--normalize' with '--format %s'", ofmt)
+				}
+				if rootFlags.OutputPath == "" && rootFlags.OutputDir == "" {
+					return fmt.Errorf("need to set '--out' or '--out-dir' for '--format %s'", ofmt)
+				}
+			}
+			parquetCompression, err := jsonconv.ParseParquetCompression(pqc)
+			if err != nil {
+				return err
+			}
 			in := &csvCmdInput{
-				inputPath:  rootFlags.InputPath,
-				outputPath: rootFlags.OutputPath,
-				raw:        rootFlags.RawData,
-				baseHs:     baseHs,
-				delim:      delim,
-				useCRLF:    crlf,
+				inputPaths:   rootFlags.InputPaths,
+				outputPath:   rootFlags.OutputPath,
+				outDir:       rootFlags.OutputDir,
+				workers:      rootFlags.Workers,
+				raw:          rootFlags.RawData,
+				inputFormat:  inFormat,
+				useNumber:    rootFlags.UseNumber,
+				baseHs:       baseHs,
+				delim:        delim,
+				useCRLF:      crlf,
+				stream:       stream,
+				sampleSize:   sample,
+				streamHs:     strmHs,
+				columns:      cols,
+				excludeCols:  excl,
+				strictCols:   strict,
+				renamePath:   rename,
+				rulesPath:    rules,
+				nullValue:    null,
+				valueFormat:  vfmt,
+				headerOrder:  hsOrder,
+				explode:      expl,
+				explodeMode:  explMode,
+				normalize:    norm,
+				keyColumn:    keyCol,
+				parentKeyCol: parCol,
+				outputFormat: ofmt,
+				xlsxOption: xlsxOption{
+					headerStyle: hsSty,
+					freezeRows:  frzR,
+					freezeCols:  frzC,
+					text:        str,
+				},
+				parquetCompression: parquetCompression,
 			}
 			if !noft {
 				in.flattenOpt = &jsonconv.FlattenOption{
-					Level:     flv,
-					Gap:       fga,
-					SkipMap:   fsm,
-					SkipArray: fsa,
+					Level:         flv,
+					Gap:           fga,
+					SkipMap:       fsm,
+					SkipArray:     fsa,
+					ArrayKeyStyle: akStyle,
+					OneBasedIndex: ob,
+					KeyCollision:  collision,
 				}
 			}
 			logger := logger.NewLogger(cmd)
-			repo := repository.NewRepository()
+			compression := rootFlags.compression
+			if ofmt == outputFormatXlsx || ofmt == outputFormatParquet {
+				// Compressed XLSX and Parquet files cannot be opened by their readers.
+				compression = repository.CompressionNone
+			}
+			repo := repository.NewRepositoryWithCompression(compression)
 			return processCsvCmd(logger, repo, in)
 		},
 	}
 
 	cmd.PersistentFlags().SortFlags = false
 	cmd.PersistentFlags().StringSliceVar(&baseHs, "hs", nil, "headers in CSV that always appears before dynamic headers (auto detected from JSON)")
+	cmd.PersistentFlags().StringVar(&ofmt, "format", outputFormatCsv, "output format: csv, xlsx (an Excel workbook, with a sheet per input file when several inputs are merged) or parquet (with column types inferred from JSON values). xlsx and parquet need '--out' or '--out-dir'")
 	cmd.PersistentFlags().StringVar(&delim, "delim", ",", "field delimiter")
 	cmd.PersistentFlags().BoolVar(&crlf, "crlf", false, "set it true to use \\r\\n as the line terminator")
+	cmd.PersistentFlags().StringVar(&hsOrd, "hs-order", "", "order of dynamic headers: alphabetical, natural (array indices sorted as integers), first-seen (by first appearance) or source (merged key order of the source JSON). If not set, uses natural when flattening arrays, otherwise alphabetical")
+	cmd.PersistentFlags().StringSliceVar(&cols, "columns", nil, "headers or glob patterns (e.g. 'items[*]__price', 'meta__*') put right after '--hs' headers, in the given order")
+	cmd.PersistentFlags().StringSliceVar(&excl, "exclude", nil, "headers or glob patterns (e.g. 'meta__*') to leave out of CSV")
+	cmd.PersistentFlags().BoolVar(&strict, "strict", false, "set it true to only output '--hs' and '--columns' headers")
+	cmd.PersistentFlags().StringVar(&rename, "rename", "", "path to a JSON file which maps headers to display names, e.g. {\"customer__address__zip\": \"ZIP\"}")
+	cmd.PersistentFlags().StringVar(&null, "null", "", "text of CSV cells for null JSON values")
+	cmd.PersistentFlags().StringVar(&vfmt, "value-format", valueFormatDefault, "format of CSV cells: default (exact decimal numbers, JSON-encoded objects and arrays) or go (Go's fmt syntax)")
+	cmd.PersistentFlags().StringSliceVar(&expl, "explode", nil, "paths of JSON arrays (e.g. 'items', 'items__parts') to explode into one CSV row per array element, repeating the other fields")
+	cmd.PersistentFlags().StringVar(&explM, "explode-mode", "cartesian", "how to combine exploded JSON arrays which are not nested in one another: cartesian (every combination) or zip (elements with the same index)")
+	cmd.PersistentFlags().BoolVar(&norm, "normalize", false, "set it true to write a CSV table per nested JSON array, linked to its parent table by generated '--key-column' and '--parent-key-column' columns")
+	cmd.PersistentFlags().StringVar(&keyCol, "key-column", jsonconv.DefaultNormalizeKeyColumn, "name of the generated key column of every table when '--normalize' is set")
+	cmd.PersistentFlags().StringVar(&parCol, "parent-key-column", jsonconv.DefaultNormalizeParentKeyColumn, "name of the generated column of child tables which refers to the key of the parent row when '--normalize' is set")
+	cmd.PersistentFlags().BoolVar(&hsSty, "header-style", true, "set it false to write the XLSX header row without the bold header style")
+	cmd.PersistentFlags().IntVar(&frzR, "freeze-rows", 1, "number of top XLSX rows which stay visible when scrolling")
+	cmd.PersistentFlags().IntVar(&frzC, "freeze-cols", 0, "number of left XLSX columns which stay visible when scrolling")
+	cmd.PersistentFlags().BoolVar(&str, "str", false, "set it true to write all XLSX cells as text instead of detecting numbers, booleans and dates (date-times with a non-UTC offset always stay text)")
+	cmd.PersistentFlags().StringVar(&pqc, "parquet-compression", "none", "compression codec of Parquet column data: none or gzip")
 	cmd.PersistentFlags().BoolVar(&noft, "noft", false, "set it true to skip JSON flattening")
 	cmd.PersistentFlags().IntVar(&flv, "flv", jsonconv.DefaultFlattenLevel, "flatten level for flattening a nested JSON (-1: unlimited, 0: no nested, [1...n]: n level of nested JSON)")
 	cmd.PersistentFlags().StringVar(&fga, "fga", jsonconv.DefaultFlattenGap, "flatten gap for separating JSON object with its nested data")
 	cmd.PersistentFlags().BoolVar(&fsm, "fsm", false, "set it true to flatten but skip map type")
 	cmd.PersistentFlags().BoolVar(&fsa, "fsa", false, "set it true to flatten but skip array type")
+	cmd.PersistentFlags().StringVar(&rules, "rules", "", "path to a JSON file of flatten rules for some paths, e.g. [{\"path\": \"metadata\", \"level\": 1}, {\"path\": \"tags\", \"skipArray\": true}]")
+	cmd.PersistentFlags().StringVar(&ak, "array-key", "bracket", "style of keys of flattened array elements: bracket (a[0]) or gap (a__0, separated by '--fga')")
+	cmd.PersistentFlags().BoolVar(&ob, "one-based", false, "set it true to start indices of flattened array elements from 1")
+	cmd.PersistentFlags().StringVar(&kc, "key-collision", "overwrite", "what to do when two values are flattened to the same header: overwrite, error, escape (escape the gap in original keys with '\\'), suffix (append #2, #3, ...) or keep-first")
+	cmd.PersistentFlags().BoolVar(&stream, "stream", false, "set it true to convert JSON objects one at a time instead of loading the whole input in memory")
+	cmd.PersistentFlags().IntVar(&sample, "sample", jsonconv.DefaultCsvStreamSampleSize, "number of JSON objects used to detect CSV headers when '--stream' is set")
+	cmd.PersistentFlags().StringSliceVar(&strmHs, "stream-hs", nil, "fixed CSV headers when '--stream' is set. If set, headers are not detected from JSON")
 	return cmd
 }
 
 type csvCmdInput struct {
-	inputPath  string
-	outputPath string
-	raw        string
-	baseHs     []string
-	delim      string
-	useCRLF    bool
-	flattenOpt *jsonconv.FlattenOption
+	inputPaths  []string
+	outputPath  string
+	outDir      string
+	workers     int
+	raw         string
+	inputFormat jsonconv.JsonFormat
+	useNumber   bool
+	baseHs      []string
+	delim       string
+	useCRLF     bool
+	flattenOpt  *jsonconv.FlattenOption
+	stream      bool
+	sampleSize  int
+	streamHs    []string
+	columns     []string
+	excludeCols []string
+	strictCols  bool
+	renamePath  string
+	rulesPath   string
+	headerNames map[string]string
+	nullValue   string
+	valueFormat string
+	headerOrder jsonconv.HeaderOrder
+	explode     []string
+	explodeMode jsonconv.ExplodeMode
+	normalize   bool
+
+	keyColumn    string
+	parentKeyCol string
+
+	outputFormat       string
+	xlsxOption         xlsxOption
+	parquetCompression jsonconv.ParquetCompression
+}
+
+// An xlsxOption is the options of XLSX output.
+type xlsxOption struct {
+	headerStyle bool
+	freezeRows  int
+	freezeCols  int
+	text        bool
+}
+
+// Formats of CSV cells.
+const (
+	valueFormatDefault = "default"
+	valueFormatGo      = "go"
+)
+
+// Output formats of the csv command.
+const (
+	outputFormatCsv     = "csv"
+	outputFormatXlsx    = "xlsx"
+	outputFormatParquet = "parquet"
+)
+
+// toCsvOption returns options of JSON to CSV conversion from in.
+func (in *csvCmdInput) toCsvOption() *jsonconv.ToCsvOption {
+	return &jsonconv.ToCsvOption{
+		FlattenOption:  in.flattenOpt,
+		Explode:        in.explode,
+		ExplodeMode:    in.explodeMode,
+		BaseHeaders:    in.baseHs,
+		Columns:        in.columns,
+		ExcludeColumns: in.excludeCols,
+		StrictColumns:  in.strictCols,
+		HeaderOrder:    in.headerOrder,
+		HeaderNames:    in.headerNames,
+		NullValue:      in.nullValue,
+		FormatValue:    formatValueFunc(in.valueFormat),
+	}
+}
+
+// toParquetOption returns options of JSON to Parquet conversion from in.
+func (in *csvCmdInput) toParquetOption() *jsonconv.ToParquetOption {
+	return &jsonconv.ToParquetOption{
+		ToCsvOption: *in.toCsvOption(),
+		Compression: in.parquetCompression,
+	}
+}
+
+// formatValueFunc returns the function to format CSV cells in valueFormat,
+// or nil for the default one.
+func formatValueFunc(valueFormat string) func(val any) string {
+	if valueFormat == valueFormatGo {
+		return func(val any) string {
+			return fmt.Sprintf("%v", val)
+		}
+	}
+	return nil
+}
+
+// loadHeaderNames reads the JSON object of header display names at filePath.
+func loadHeaderNames(repo repository.Repository, filePath string) (map[string]string, error) {
+	fi, err := repo.GetFileReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	names := make(map[string]string)
+	if err := json.NewDecoder(fi).Decode(&names); err != nil {
+		return nil, fmt.Errorf("invalid header names file, %v", err)
+	}
+	return names, nil
 }
 
 func processCsvCmd(logger logger.Logger, repo repository.Repository, in *csvCmdInput) error {
-	var err error
-
-	// Create JSON reader.
-	var jr *jsonconv.JsonReader
-	switch {
-	case in.raw != "":
-		jr = jsonconv.NewJsonReader(strings.NewReader(in.raw))
-	case in.inputPath != "":
-		fi, err := repo.GetFileReader(in.inputPath)
+	if in.renamePath != "" {
+		names, err := loadHeaderNames(repo, in.renamePath)
 		if err != nil {
 			return err
 		}
-		defer fi.Close()
-		data, err := io.ReadAll(fi)
+		in.headerNames = names
+	}
+	if in.rulesPath != "" && in.flattenOpt != nil {
+		rules, err := loadFlattenRules(repo, in.rulesPath)
 		if err != nil {
-			return fmt.Errorf("failed to read input file: %w", err)
+			return err
+		}
+		fo := *in.flattenOpt
+		fo.Rules = rules
+		in.flattenOpt = &fo
+	}
+	inputPaths, err := expandInputPaths(repo, in.inputPaths)
+	if err != nil {
+		return err
+	}
+	in.inputPaths = inputPaths
+	if in.normalize {
+		return processCsvNormalize(logger, repo, in)
+	}
+	if in.outDir != "" {
+		sl := &syncLogger{logger: logger}
+		ext := ".csv"
+		if in.outputFormat == outputFormatXlsx || in.outputFormat == outputFormatParquet {
+			ext = "." + in.outputFormat
 		}
-		jr = jsonconv.NewJsonReader(bytes.NewReader(data))
-	case !repo.IsStdinEmpty():
-		fi := repo.GetStdinReader()
-		defer fi.Close()
-		data, err := io.ReadAll(fi)
+		return eachInput(in.inputPaths, in.outDir, ext, in.workers, func(inputPath, outputPath string) error {
+			each := *in
+			each.inputPaths = []string{inputPath}
+			each.outputPath = outputPath
+			each.outDir = ""
+			each.renamePath = ""
+			each.rulesPath = ""
+			return processCsvCmd(sl, repo, &each)
+		})
+	}
+	if in.stream {
+		if in.raw == "" && len(in.inputPaths) > 1 {
+			return fmt.Errorf("cannot merge several inputs with '--stream', set '--out-dir' to convert them separately")
+		}
+		return processCsvStream(logger, repo, in)
+	}
+
+	// Read JSON data of all inputs and convert it to CSV, merging the inputs with a union
+	// of headers. XLSX output has a sheet per input instead, and Parquet output is
+	// converted from the JSON objects.
+	separate := in.outputFormat == outputFormatXlsx && in.raw == "" && len(in.inputPaths) > 1
+	var tables [][][]string
+	if in.headerOrder == jsonconv.HeaderOrderFirstSeen || in.headerOrder == jsonconv.HeaderOrderSource {
+		arrs, err := readInputs(repo, in.raw, in.inputPaths, in.workers, func(r io.Reader) ([]*jsonconv.OrderedObject, error) {
+			return readOrderedJsonObjects(in.jsonReader(r))
+		})
 		if err != nil {
-			return fmt.Errorf("failed to read stdin: %w", err)
+			return err
 		}
-		jr = jsonconv.NewJsonReader(bytes.NewReader(data))
-	default:
-		return fmt.Errorf("need to input either raw data, input file path or data from stdin")
+		if in.outputFormat == outputFormatParquet {
+			return outputParquetContent(logger, repo, in.outputPath, func(w io.Writer) error {
+				return jsonconv.ToParquetOrdered(w, slices.Concat(arrs...), in.toParquetOption())
+			})
+		}
+		tables, err = convertInputs(arrs, separate, func(arr []*jsonconv.OrderedObject) ([][]string, error) {
+			return jsonconv.ToCsvOrdered(arr, in.toCsvOption())
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		arrs, err := readInputs(repo, in.raw, in.inputPaths, in.workers, func(r io.Reader) ([]map[string]any, error) {
+			return readJsonObjects(in.jsonReader(r))
+		})
+		if err != nil {
+			return err
+		}
+		if in.outputFormat == outputFormatParquet {
+			return outputParquetContent(logger, repo, in.outputPath, func(w io.Writer) error {
+				return jsonconv.ToParquet(w, slices.Concat(arrs...), in.toParquetOption())
+			})
+		}
+		tables, err = convertInputs(arrs, separate, func(arr []map[string]any) ([][]string, error) {
+			return jsonconv.ToCsvStrict(arr, in.toCsvOption())
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Output the content.
+	if in.outputFormat == outputFormatXlsx {
+		names := []string{"Sheet1"}
+		if separate || (in.raw == "" && len(in.inputPaths) == 1) {
+			names = make([]string, len(in.inputPaths))
+			for i, p := range in.inputPaths {
+				names[i] = inputName(p)
+			}
+		}
+		return outputXlsxContent(logger, repo, names, tables, in.outputPath, in.xlsxOption)
 	}
+	return outputCsvContent(logger, repo, tables[0], in.outputPath, in.delimRune(), in.useCRLF)
+}
+
+// convertInputs converts the JSON objects of every input with convert,
+// one input at a time if separate, otherwise all of them together.
+func convertInputs[T any](arrs [][]T, separate bool, convert func(arr []T) ([][]string, error)) ([][][]string, error) {
+	if !separate {
+		data, err := convert(slices.Concat(arrs...))
+		if err != nil {
+			return nil, err
+		}
+		return [][][]string{data}, nil
+	}
+	tables := make([][][]string, len(arrs))
+	for i, arr := range arrs {
+		data, err := convert(arr)
+		if err != nil {
+			return nil, err
+		}
+		tables[i] = data
+	}
+	return tables, nil
+}
+
+// jsonReader returns a JSON reader of r with the input options of in.
+func (in *csvCmdInput) jsonReader(r io.Reader) *jsonconv.JsonReader {
+	jr := jsonconv.NewJsonReader(r)
+	jr.Format = in.inputFormat
+	jr.UseNumber = in.useNumber
+	return jr
+}
+
+// delimRune returns in.delim as a rune, or nil if it is empty.
+func (in *csvCmdInput) delimRune() *rune {
+	runes := []rune(in.delim)
+	if len(runes) == 0 {
+		return nil
+	}
+	return &runes[0]
+}
+
+func processCsvNormalize(logger logger.Logger, repo repository.Repository, in *csvCmdInput) error {
+	// Read and parse JSON data of all inputs.
+	arrs, err := readInputs(repo, in.raw, in.inputPaths, in.workers, func(r io.Reader) ([]map[string]any, error) {
+		return readJsonObjects(in.jsonReader(r))
+	})
+	if err != nil {
+		return err
+	}
+	arr := slices.Concat(arrs...)
+
+	// Convert JSON to tables and write one CSV file per table.
+	tables, err := jsonconv.Normalize(arr, &jsonconv.NormalizeOption{
+		ToCsvOption:     *in.toCsvOption(),
+		KeyColumn:       in.keyColumn,
+		ParentKeyColumn: in.parentKeyCol,
+	})
+	if errors.Is(err, jsonconv.ErrNormalizeKeyColumn) {
+		return fmt.Errorf("cannot normalize JSON, %v, set '--key-column' or '--parent-key-column' to another name", err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, tb := range tables {
+		filePath := filepath.Join(in.outDir, tableFileName(tb.Name))
+		if err := outputCsvContent(logger, repo, tb.Data, filePath, in.delimRune(), in.useCRLF); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Read and parse JSON data.
+// tableFileName returns the CSV file name of a table, without path separators.
+func tableFileName(name string) string {
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(name) + ".csv"
+}
+
+// readJsonObjects reads non-empty JSON objects from jr.
+func readJsonObjects(jr *jsonconv.JsonReader) ([]map[string]any, error) {
 	var encoded any
-	err = jr.Read(&encoded)
+	err := jr.Read(&encoded)
 	if err != nil {
-		return fmt.Errorf("invalid JSON data, %v", err)
+		return nil, fmt.Errorf("invalid JSON data, %v", err)
 	}
 
 	var arr []map[string]any
@@ -117,7 +508,7 @@ func processCsvCmd(logger logger.Logger, repo repository.Repository, in *csvCmdI
 		for _, v := range val {
 			obj, ok := v.(map[string]any)
 			if !ok {
-				return fmt.Errorf("unsupport type of JSON data")
+				return nil, fmt.Errorf("unsupport type of JSON data")
 			}
 			if len(obj) == 0 {
 				continue
@@ -129,24 +520,63 @@ func processCsvCmd(logger logger.Logger, repo repository.Repository, in *csvCmdI
 			arr = append(arr, val)
 		}
 	default:
-		return fmt.Errorf("unsupport type of JSON data")
+		return nil, fmt.Errorf("unsupport type of JSON data")
 	}
+	return arr, nil
+}
 
-	// Convert JSON to CSV.
-	data := jsonconv.ToCsv(arr, &jsonconv.ToCsvOption{
-		FlattenOption: in.flattenOpt,
-		BaseHeaders:   in.baseHs,
-	})
+// readOrderedJsonObjects reads non-empty JSON objects from jr, keeping the order of their keys.
+func readOrderedJsonObjects(jr *jsonconv.JsonReader) ([]*jsonconv.OrderedObject, error) {
+	var arr []*jsonconv.OrderedObject
+	for {
+		obj, err := jr.NextOrdered()
+		if err == io.EOF {
+			return arr, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON data, %v", err)
+		}
+		if obj.Len() != 0 {
+			arr = append(arr, obj)
+		}
+	}
+}
 
-	// Convert in.delim to rune.
-	runes := []rune(in.delim)
-	var delimRune *rune
-	if len(runes) > 0 {
-		delimRune = &runes[0]
+func processCsvStream(l logger.Logger, repo repository.Repository, in *csvCmdInput) error {
+	// Get JSON input stream.
+	var inputPath string
+	if len(in.inputPaths) > 0 {
+		inputPath = in.inputPaths[0]
+	}
+	r, err := openInput(repo, in.raw, inputPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Convert JSON to CSV written to w.
+	convert := func(w io.Writer) error {
+		cw := jsonconv.NewCsvWriter(w)
+		if runes := []rune(in.delim); len(runes) > 0 {
+			cw.Delimiter = runes[0]
+		}
+		cw.UseCRLF = in.useCRLF
+		return jsonconv.ToCsvStream(in.jsonReader(r), cw, &jsonconv.ToCsvStreamOption{
+			ToCsvOption: *in.toCsvOption(),
+			Headers:     in.streamHs,
+			SampleSize:  in.sampleSize,
+		})
 	}
 
-	// Output the CSV content.
-	return outputCsvContent(logger, repo, data, in.outputPath, delimRune, in.useCRLF)
+	// Get CSV output stream.
+	if in.outputPath == "" {
+		return convert(logger.NewWriter(l))
+	}
+	if err := writeFile(repo, in.outputPath, convert); err != nil {
+		return err
+	}
+	l.Printf("The CSV file is located at %s\n", in.outputPath)
+	return nil
 }
 
 func outputCsvContent(logger logger.Logger, repo repository.Repository, data [][]string, filePath string, delim *rune, useCRLF bool) error {
@@ -167,20 +597,15 @@ func outputCsvContent(logger logger.Logger, repo repository.Repository, data [][
 		}
 		logger.Printf("%s\n", buf.String())
 	} else {
-		// Create CSV writer with output file.
-		fi, err := repo.CreateFileWriter(filePath)
-		if err != nil {
-			return err
-		}
-		defer fi.Close()
-		cw := jsonconv.NewCsvWriter(fi)
-		if delim != nil {
-			cw.Delimiter = *delim
-		}
-		cw.UseCRLF = useCRLF
-
 		// Write to CSV file.
-		err = cw.Write(data)
+		err := writeFile(repo, filePath, func(w io.Writer) error {
+			cw := jsonconv.NewCsvWriter(w)
+			if delim != nil {
+				cw.Delimiter = *delim
+			}
+			cw.UseCRLF = useCRLF
+			return cw.Write(data)
+		})
 		if err != nil {
 			return err
 		}
@@ -188,3 +613,37 @@ func outputCsvContent(logger logger.Logger, repo repository.Repository, data [][
 	}
 	return nil
 }
+
+// outputXlsxContent writes tables to an XLSX file at filePath, each one to the sheet with the same index in names.
+func outputXlsxContent(logger logger.Logger, repo repository.Repository, names []string, tables [][][]string, filePath string, opt xlsxOption) error {
+	err := writeFile(repo, filePath, func(w io.Writer) error {
+		// Create XLSX writer with output file.
+		xw := jsonconv.NewXlsxWriter(w)
+		xw.HeaderStyle = opt.headerStyle
+		xw.FreezeRows = opt.freezeRows
+		xw.FreezeColumns = opt.freezeCols
+		xw.InferTypes = !opt.text
+
+		// Write a sheet per table.
+		for i, data := range tables {
+			if err := xw.WriteSheet(names[i], data); err != nil {
+				return err
+			}
+		}
+		return xw.Close()
+	})
+	if err != nil {
+		return err
+	}
+	logger.Printf("The XLSX file is located at %s\n", filePath)
+	return nil
+}
+
+// outputParquetContent writes a Parquet file at filePath with write.
+func outputParquetContent(logger logger.Logger, repo repository.Repository, filePath string, write func(w io.Writer) error) error {
+	if err := writeFile(repo, filePath, write); err != nil {
+		return err
+	}
+	logger.Printf("The Parquet file is located at %s\n", filePath)
+	return nil
+}
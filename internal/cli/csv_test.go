@@ -1,7 +1,11 @@
 package cli
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
+	"io"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -27,7 +31,7 @@ func TestProcessCsvCmd_NoInputData(t *testing.T) {
 func TestProcessCsvCmd_ReadFileError(t *testing.T) {
 	// Prepare
 	in := &csvCmdInput{
-		inputPath: "test.json",
+		inputPaths: []string{"test.json"},
 	}
 	logger := NewMockLogger()
 	repo := NewMockRepository()
@@ -361,7 +365,7 @@ func TestProcessCsvCmd_JsonArray_ToConsole(t *testing.T) {
 func TestProcessCsvCmd_ReadFromJsonFile(t *testing.T) {
 	// Prepare
 	in := &csvCmdInput{
-		inputPath:  "test.json",
+		inputPaths: []string{"test.json"},
 		baseHs:     []string{"z", "y", "x"},
 		delim:      "|",
 		flattenOpt: jsonconv.DefaultFlattenOption,
@@ -507,3 +511,895 @@ func TestProcessCsvCmd_ToCsvFile(t *testing.T) {
 		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
 	}
 }
+
+func TestProcessCsvCmd_Stream_NoInputData(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		stream: true,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := "need to input either raw data, input file path or data from stdin"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessCsvCmd_Stream_ReadFileError(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		inputPaths: []string{"test.json"},
+		stream:     true,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileOpeningError = fmt.Errorf("mock open file error")
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := repo.fileOpeningError.Error()
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessCsvCmd_Stream_CreateFileError(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:        `[{"id": 1}]`,
+		outputPath: "test.csv",
+		stream:     true,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileCreatingError = fmt.Errorf("mock create file error")
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := repo.fileCreatingError.Error()
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessCsvCmd_Stream_InvalidJsonArrayType(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:    "[ { \"a\": [] }, 1, 2, 3 ]",
+		stream: true,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := "line 1: unsupported type of JSON data"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessCsvCmd_Stream_ReadFromStdin_ToConsole(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		baseHs:     []string{"z"},
+		delim:      "|",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+		stream:     true,
+		sampleSize: 1,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.isStdinEmpty = false // fake stdin data
+	repo.readerContent = `
+	[
+		{"id": "b042ab5c-ca73-4460-b739-96410ea9d3a6", "user": "Jon Doe", "nested": {"a": 1, "f": [4, 5]}},
+		{"id": "ce06f5b1-5721-42c0-91e1-9f72a09c250a", "user": "Tuấn", "score": 1.5}
+	]`
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `z|id|nested__a|nested__f[0]|nested__f[1]|user
+|b042ab5c-ca73-4460-b739-96410ea9d3a6|1|4|5|Jon Doe
+|ce06f5b1-5721-42c0-91e1-9f72a09c250a||||Tuấn`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessCsvCmd_Stream_ReadFromJsonFile_ToCsvFile(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		inputPaths: []string{"test.json"},
+		outputPath: "test.csv",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+		stream:     true,
+		streamHs:   []string{"user", "score"},
+		useCRLF:    true,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.readerContent = `
+		{"id": "b042ab5c-ca73-4460-b739-96410ea9d3a6", "user": "Jon Doe", "score": -100}
+		{"id": "ce06f5b1-5721-42c0-91e1-9f72a09c250a", "user": "Tuấn", "score": 1.5}`
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	msg := repo.writerBuffer.String()
+	expMsg := "user,score\r\nJon Doe,-100\r\nTuấn,1.5\r\n"
+	if msg != expMsg {
+		t.Fatalf("It should write content: %q\ncurrent: %q", expMsg, msg)
+	}
+	expLog := "The CSV file is located at test.csv\n"
+	if logger.msg != expLog {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expLog, logger.msg)
+	}
+}
+
+func TestProcessCsvCmd_Columns(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw: `
+		[
+			{"id": 1, "user": "Jon Doe", "items": [{"sku": "A1", "price": 1.5}, {"sku": "B2", "price": 2}], "meta": {"ip": "127.0.0.1"}},
+			{"id": 2, "user": "Tuấn", "items": [{"sku": "C3", "price": 3}], "note": "gift"}
+		]`,
+		baseHs:      []string{"id"},
+		columns:     []string{"items[*]__price"},
+		excludeCols: []string{"meta__*"},
+		flattenOpt:  jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `id,items[0]__price,items[1]__price,items[0]__sku,items[1]__sku,note,user
+1,1.5,2,A1,B2,,Jon Doe
+2,3,,C3,,gift,Tuấn`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessCsvCmd_Stream_StrictColumns(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw: `
+		[
+			{"id": 1, "user": "Jon Doe", "items": [{"sku": "A1", "price": 1.5}, {"sku": "B2", "price": 2}]},
+			{"id": 2, "user": "Tuấn", "items": [{"sku": "C3", "price": 3}]}
+		]`,
+		columns:    []string{"user", "items[*]__sku"},
+		strictCols: true,
+		flattenOpt: jsonconv.DefaultFlattenOption,
+		stream:     true,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `user,items[0]__sku,items[1]__sku
+Jon Doe,A1,B2
+Tuấn,C3,`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessCsvCmd_Rename(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:        `{"id": 1, "customer": {"address": {"zip": "70000"}}}`,
+		renamePath: "names.json",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.readerContent = `{"customer__address__zip": "ZIP", "id": "ID"}`
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := "ZIP,ID\n70000,1"
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessCsvCmd_Rename_ReadFileError(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:        `{"id": 1}`,
+		renamePath: "names.json",
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileOpeningError = fmt.Errorf("mock open file error")
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := repo.fileOpeningError.Error()
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessCsvCmd_Rename_InvalidFile(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:        `{"id": 1}`,
+		renamePath: "names.json",
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.readerContent = `["id", "ID"]`
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := "invalid header names file"
+	if err == nil || !strings.HasPrefix(err.Error(), expMsg) {
+		t.Fatalf("It should throw an error starts with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessCsvCmd_ValueFormat(t *testing.T) {
+	// Prepare
+	raw := `{"id": 1e21, "score": null, "nested": {"a": 1, "b": [2, 3]}}`
+	cases := map[string]string{
+		valueFormatDefault: `NULL|1000000000000000000000|"{""a"":1,""b"":[2,3]}"`,
+		valueFormatGo:      "<nil>|1e+21|map[a:1 b:[2 3]]",
+	}
+
+	for vfmt, expRow := range cases {
+		in := &csvCmdInput{
+			raw:         raw,
+			baseHs:      []string{"score", "id"},
+			delim:       "|",
+			nullValue:   "NULL",
+			valueFormat: vfmt,
+		}
+		logger := NewMockLogger()
+		repo := NewMockRepository()
+
+		// Process
+		err := processCsvCmd(logger, repo, in)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to process CSV cmd, err: %v", err)
+		}
+		msg := strings.TrimSpace(logger.msg)
+		expMsg := "score|id|nested\n" + expRow
+		if msg != expMsg {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+		}
+	}
+}
+
+func TestProcessCsvCmd_UseNumber(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		// Prepare
+		in := &csvCmdInput{
+			raw:        `[{"id": 1234567890123456789}, {"id": 9007199254740993}]`,
+			stream:     stream,
+			useNumber:  true,
+			flattenOpt: jsonconv.DefaultFlattenOption,
+		}
+		logger := NewMockLogger()
+		repo := NewMockRepository()
+
+		// Process
+		err := processCsvCmd(logger, repo, in)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to process csv cmd, err: %v", err)
+		}
+		msg := strings.TrimSpace(logger.msg)
+		expMsg := "id\n1234567890123456789\n9007199254740993"
+		if msg != expMsg {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+		}
+	}
+}
+
+func TestProcessCsvCmd_HeaderOrder(t *testing.T) {
+	// Prepare
+	raw := `[{"id": 1, "user": "Jon Doe", "total": 3.5}, {"id": 2, "meta": {"source": "web"}, "total": 3}]`
+	cases := map[jsonconv.HeaderOrder]string{
+		jsonconv.HeaderOrderFirstSeen: "id,user,total,meta__source\n1,Jon Doe,3.5,\n2,,3,web",
+		jsonconv.HeaderOrderSource:    "id,meta__source,user,total\n1,,Jon Doe,3.5\n2,web,,3",
+	}
+
+	for order, expMsg := range cases {
+		for _, stream := range []bool{false, true} {
+			in := &csvCmdInput{
+				raw:         raw,
+				stream:      stream,
+				headerOrder: order,
+				flattenOpt:  jsonconv.DefaultFlattenOption,
+			}
+			logger := NewMockLogger()
+			repo := NewMockRepository()
+
+			// Process
+			err := processCsvCmd(logger, repo, in)
+
+			// Check
+			if err != nil {
+				t.Fatalf("failed to process csv cmd, err: %v", err)
+			}
+			msg := strings.TrimSpace(logger.msg)
+			if msg != expMsg {
+				t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+			}
+		}
+	}
+}
+
+func TestProcessCsvCmd_HeaderOrder_InvalidJson(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:         `[{"id": 1}, 2]`,
+		headerOrder: jsonconv.HeaderOrderFirstSeen,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := "invalid JSON data, line 1: unsupported type of JSON data"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessCsvCmd_Explode(t *testing.T) {
+	// Prepare
+	raw := `[{"id": 1, "items": [{"sku": "A1", "qty": 2}, {"sku": "B2", "qty": 1}]}, {"id": 2, "items": [{"sku": "C3", "qty": 5}]}]`
+
+	for _, stream := range []bool{false, true} {
+		in := &csvCmdInput{
+			raw:        raw,
+			stream:     stream,
+			explode:    []string{"items"},
+			flattenOpt: jsonconv.DefaultFlattenOption,
+		}
+		logger := NewMockLogger()
+		repo := NewMockRepository()
+
+		// Process
+		err := processCsvCmd(logger, repo, in)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to process csv cmd, err: %v", err)
+		}
+		msg := strings.TrimSpace(logger.msg)
+		expMsg := "id,items__qty,items__sku\n1,2,A1\n1,1,B2\n2,5,C3"
+		if msg != expMsg {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+		}
+	}
+}
+
+func TestProcessCsvCmd_Normalize(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:        `[{"id": "o1", "items": [{"sku": "A1"}, {"sku": "B2"}]}, {"id": "o2", "items": [{"sku": "C3"}]}]`,
+		normalize:  true,
+		outDir:     "out",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process csv cmd, err: %v", err)
+	}
+	expected := map[string]string{
+		filepath.Join("out", "root.csv"):  "_id,id\n1,o1\n2,o2\n",
+		filepath.Join("out", "items.csv"): "_id,_parent_id,sku\n1,1,A1\n2,1,B2\n3,2,C3\n",
+	}
+	if len(repo.writerBuffers) != len(expected) {
+		t.Fatalf("It should write %d files, current: %v", len(expected), repo.writerBuffers)
+	}
+	for path, exp := range expected {
+		if buf := repo.writerBuffers[path]; buf == nil || buf.String() != exp {
+			t.Fatalf("It should write %s with content: %s\ncurrent: %v", path, exp, buf)
+		}
+	}
+	expMsg := fmt.Sprintf("The CSV file is located at %s\nThe CSV file is located at %s\n",
+		filepath.Join("out", "root.csv"), filepath.Join("out", "items.csv"))
+	if logger.msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, logger.msg)
+	}
+}
+
+func TestProcessCsvCmd_Normalize_Errors(t *testing.T) {
+	// Prepare
+	cases := map[string]*mockRepository{
+		"need to input either raw data, input file path or data from stdin": NewMockRepository(),
+		"invalid JSON data, line 1: unexpected EOF":                         {readerContent: `[{"id": 1}`, isStdinEmpty: false},
+		"failed to create file":                                             {readerContent: `{"id": 1}`, isStdinEmpty: false, fileCreatingError: fmt.Errorf("failed to create file")},
+	}
+
+	for expMsg, repo := range cases {
+		in := &csvCmdInput{
+			normalize: true,
+			outDir:    "out",
+		}
+
+		// Process
+		err := processCsvCmd(NewMockLogger(), repo, in)
+
+		// Check
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestProcessCsvCmd_NormalizeKeyColumn(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:        `[{"_id": "abc-123", "items": [{"sku": "A1"}]}]`,
+		normalize:  true,
+		outDir:     "out",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	expMsg := `cannot normalize JSON, field clashes with a key column: "_id" of table "root", set '--key-column' or '--parent-key-column' to another name`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+
+	// Process
+	in.keyColumn = "pk"
+	in.parentKeyCol = "order_pk"
+	err = processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process csv cmd, err: %v", err)
+	}
+	expected := map[string]string{
+		filepath.Join("out", "root.csv"):  "pk,_id\n1,abc-123\n",
+		filepath.Join("out", "items.csv"): "pk,order_pk,sku\n1,1,A1\n",
+	}
+	for path, exp := range expected {
+		if buf := repo.writerBuffers[path]; buf == nil || buf.String() != exp {
+			t.Fatalf("It should write %s with content: %s\ncurrent: %v", path, exp, buf)
+		}
+	}
+}
+
+func TestTableFileName(t *testing.T) {
+	// Prepare
+	cases := map[string]string{
+		"items":        "items.csv",
+		"items__parts": "items__parts.csv",
+		"a/b\\c":       "a_b_c.csv",
+	}
+
+	for name, expected := range cases {
+		// Process
+		fileName := tableFileName(name)
+
+		// Check
+		if fileName != expected {
+			t.Fatalf("file name is incorrect for %q, current: %q", name, fileName)
+		}
+	}
+}
+
+func TestProcessCsvCmd_Rules(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		raw:        `{"id": 1, "tags": ["a", "b"], "metadata": {"x": {"y": 1}}}`,
+		rulesPath:  "rules.json",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.readerContent = `[{"path": "tags", "level": 0}, {"path": "metadata", "level": 1}]`
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `id,metadata__x,tags
+1,"{""y"":1}","[""a"",""b""]"`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessCsvCmd_MultipleInputs(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		inputPaths: []string{"dumps", "extra.json"},
+		flattenOpt: jsonconv.DefaultFlattenOption,
+		workers:    2,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.listedFiles = map[string][]string{
+		"dumps": {filepath.Join("dumps", "a.json"), filepath.Join("dumps", "b.json")},
+	}
+	repo.fileContents = map[string]string{
+		filepath.Join("dumps", "a.json"): `[{"id": 1, "a": {"x": 1}}, {"id": 2}]`,
+		filepath.Join("dumps", "b.json"): `{"id": 3, "b": true}`,
+		"extra.json":                     `{"id": 4, "a": {"y": 2}}`,
+	}
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `a__x,a__y,b,id
+1,,,1
+,,,2
+,,true,3
+,2,,4`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessCsvCmd_MultipleInputs_Errors(t *testing.T) {
+	// Prepare
+	cases := map[string]*csvCmdInput{
+		`a.json: invalid JSON data, line 1: unexpected EOF`: {
+			inputPaths: []string{"b.json", "a.json"},
+		},
+		`no input files match "*.csv"`: {
+			inputPaths: []string{"*.csv"},
+		},
+		"cannot merge several inputs with '--stream', set '--out-dir' to convert them separately": {
+			inputPaths: []string{"b.json", "c.json"},
+			stream:     true,
+		},
+	}
+
+	for expMsg, in := range cases {
+		repo := NewMockRepository()
+		repo.readerContent = `{"id": 1}`
+		repo.fileContents = map[string]string{"a.json": `[{"id": 1}`}
+		repo.listedFiles = map[string][]string{"*.csv": nil}
+
+		// Process
+		err := processCsvCmd(NewMockLogger(), repo, in)
+
+		// Check
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestProcessCsvCmd_OutDir(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		inputPaths: []string{"*.json"},
+		outDir:     "out",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+		stream:     true,
+		sampleSize: jsonconv.DefaultCsvStreamSampleSize,
+		workers:    4,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.listedFiles = map[string][]string{
+		"*.json": {"2024-01-01.json.gz", "2024-01-02.json", "2024-01-03.json", "2024-01-04.json.bz2"},
+	}
+	repo.fileContents = map[string]string{
+		"2024-01-01.json.gz":  `{"id": 1}`,
+		"2024-01-02.json":     `{"id": 2, "a": {"b": 3}}`,
+		"2024-01-03.json":     `[{"id": 3}, {"id": 4}]`,
+		"2024-01-04.json.bz2": `{"id": 5}`,
+	}
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	expected := map[string]string{
+		filepath.Join("out", "2024-01-01.csv.gz"): "id\n1\n",
+		filepath.Join("out", "2024-01-02.csv"):    "a__b,id\n3,2\n",
+		filepath.Join("out", "2024-01-03.csv"):    "id\n3\n4\n",
+		filepath.Join("out", "2024-01-04.csv"):    "id\n5\n",
+	}
+	if len(repo.writerBuffers) != len(expected) {
+		t.Fatalf("It should write %d files, current: %v", len(expected), repo.writerBuffers)
+	}
+	for path, exp := range expected {
+		if buf := repo.writerBuffers[path]; buf == nil || buf.String() != exp {
+			t.Fatalf("It should write %s with content: %s\ncurrent: %v", path, exp, buf)
+		}
+		if expMsg := fmt.Sprintf("The CSV file is located at %s\n", path); !strings.Contains(logger.msg, expMsg) {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, logger.msg)
+		}
+	}
+}
+
+func TestProcessCsvCmd_OutDir_BinaryFormats(t *testing.T) {
+	for _, format := range []string{outputFormatXlsx, outputFormatParquet} {
+		// Prepare
+		in := &csvCmdInput{
+			inputPaths:   []string{"a.json.gz", "b.json.zst"},
+			outDir:       "out",
+			flattenOpt:   jsonconv.DefaultFlattenOption,
+			outputFormat: format,
+		}
+		repo := NewMockRepository()
+		repo.readerContent = `{"id": 1}`
+
+		// Process
+		err := processCsvCmd(NewMockLogger(), repo, in)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to process CSV cmd, err: %v", err)
+		}
+		for _, name := range []string{"a." + format, "b." + format} {
+			if path := filepath.Join("out", name); repo.writerBuffers[path] == nil {
+				t.Fatalf("It should write %s without compression extension, current: %v", path, repo.writerBuffers)
+			}
+		}
+	}
+}
+
+func TestProcessCsvCmd_OutDir_Errors(t *testing.T) {
+	// Prepare
+	cases := map[string]*csvCmdInput{
+		"need to set '--in' for '--out-dir'": {
+			raw:    `{"id": 1}`,
+			outDir: "out",
+		},
+		fmt.Sprintf("inputs %s and %s have the same output file %s",
+			filepath.Join("a", "x.json"), filepath.Join("b", "x.json"), filepath.Join("out", "x.csv")): {
+			inputPaths: []string{filepath.Join("a", "x.json"), filepath.Join("b", "x.json")},
+			outDir:     "out",
+		},
+		"b.json: failed to create file": {
+			inputPaths: []string{"b.json"},
+			outDir:     "out",
+		},
+	}
+
+	for expMsg, in := range cases {
+		repo := NewMockRepository()
+		repo.readerContent = `{"id": 1}`
+		repo.fileCreatingError = fmt.Errorf("failed to create file")
+
+		// Process
+		err := processCsvCmd(NewMockLogger(), repo, in)
+
+		// Check
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestProcessCsvCmd_Xlsx(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		inputPaths:   []string{"a.json", filepath.Join("dumps", "b.json.gz")},
+		outputPath:   "out.xlsx",
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+		outputFormat: outputFormatXlsx,
+		xlsxOption:   xlsxOption{headerStyle: true, freezeRows: 1},
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json":                            `{"id": 1, "zip": "007"}`,
+		filepath.Join("dumps", "b.json.gz"): `[{"id": 2, "a": {"b": true}}]`,
+	}
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	data := repo.writerBuffers["out.xlsx"].Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open XLSX file, err: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		r, _ := f.Open()
+		b, _ := io.ReadAll(r)
+		r.Close()
+		parts[f.Name] = string(b)
+	}
+	expected := map[string][]string{
+		"xl/workbook.xml":          {`<sheet name="a" sheetId="1" r:id="rId1"/>`, `<sheet name="b" sheetId="2" r:id="rId2"/>`},
+		"xl/worksheets/sheet1.xml": {`<c r="A2"><v>1</v></c>`, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">007</t></is></c>`},
+		"xl/worksheets/sheet2.xml": {`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">a__b</t></is></c>`, `<c r="A2" t="b"><v>1</v></c>`},
+	}
+	for name, exps := range expected {
+		for _, exp := range exps {
+			if !strings.Contains(parts[name], exp) {
+				t.Fatalf("%s should contain %s\ncurrent: %s", name, exp, parts[name])
+			}
+		}
+	}
+	expMsg := "The XLSX file is located at out.xlsx\n"
+	if logger.msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, logger.msg)
+	}
+}
+
+func TestProcessCsvCmd_Parquet(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		inputPaths:         []string{"a.json", "b.json"},
+		outputPath:         "out.parquet",
+		flattenOpt:         jsonconv.DefaultFlattenOption,
+		headerOrder:        jsonconv.HeaderOrderFirstSeen,
+		outputFormat:       outputFormatParquet,
+		parquetCompression: jsonconv.ParquetGzip,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json": `{"id": 1, "user": {"name": "Jon"}}`,
+		"b.json": `[{"id": 2, "score": 1.5}]`,
+	}
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	data := repo.writerBuffers["out.parquet"].Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatalf("out.parquet should be a Parquet file, current: %q", data)
+	}
+	for _, name := range []string{"id", "user__name", "score"} {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Fatalf("out.parquet should have column %s", name)
+		}
+	}
+	expMsg := "The Parquet file is located at out.parquet\n"
+	if logger.msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, logger.msg)
+	}
+}
+
+func TestProcessCsvCmd_ParquetOutDir(t *testing.T) {
+	// Prepare
+	in := &csvCmdInput{
+		inputPaths:   []string{"a.json", "b.json"},
+		outDir:       "out",
+		workers:      2,
+		outputFormat: outputFormatParquet,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json": `{"id": 1}`,
+		"b.json": `{"id": "x"}`,
+	}
+
+	// Process
+	err := processCsvCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process CSV cmd, err: %v", err)
+	}
+	for _, name := range []string{"a.parquet", "b.parquet"} {
+		buf, exist := repo.writerBuffers[filepath.Join("out", name)]
+		if !exist || !bytes.HasPrefix(buf.Bytes(), []byte("PAR1")) {
+			t.Fatalf("It should write Parquet file %s", filepath.Join("out", name))
+		}
+	}
+}
+
+func TestProcessCsvCmd_FileClosingError(t *testing.T) {
+	// Prepare
+	raw := `[{"id": 1, "items": [{"sku": "A1"}]}]`
+	cases := map[string]*csvCmdInput{
+		"csv":       {raw: raw, outputPath: "a.csv"},
+		"stream":    {raw: raw, outputPath: "a.csv", stream: true, sampleSize: jsonconv.DefaultCsvStreamSampleSize},
+		"xlsx":      {raw: raw, outputPath: "a.xlsx", outputFormat: outputFormatXlsx},
+		"parquet":   {raw: raw, outputPath: "a.parquet", outputFormat: outputFormatParquet},
+		"normalize": {raw: raw, outDir: "out", normalize: true},
+	}
+
+	for name, in := range cases {
+		in.flattenOpt = jsonconv.DefaultFlattenOption
+		logger := NewMockLogger()
+		repo := NewMockRepository()
+		repo.fileClosingError = fmt.Errorf("failed to close file")
+
+		// Process
+		err := processCsvCmd(logger, repo, in)
+
+		// Check
+		expMsg := "failed to close file"
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s for %s\ncurrent: %v", expMsg, name, err)
+		}
+		if logger.msg != "" {
+			t.Fatalf("It should not show message for %s, current: %s", name, logger.msg)
+		}
+	}
+}
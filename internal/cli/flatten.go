@@ -2,8 +2,10 @@ package cli
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -14,10 +16,17 @@ import (
 
 func NewFlattenCmd() *cobra.Command {
 	var (
-		lvl int
-		gap string
-		sm  bool
-		sa  bool
+		lvl  int
+		gap  string
+		sm   bool
+		sa   bool
+		ofmt string
+		ind  string
+		sk   bool
+		ak   string
+		ob   bool
+		kc   string
+		rule string
 	)
 
 	cmd := &cobra.Command{
@@ -25,19 +34,45 @@ func NewFlattenCmd() *cobra.Command {
 		Short: "Flatten JSON object and JSON array",
 		Long:  "Flatten JSON object and JSON array",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			inFormat, err := jsonconv.ParseJsonFormat(rootFlags.InputFormat)
+			if err != nil {
+				return err
+			}
+			if ofmt != outputFormatJson && ofmt != outputFormatNdjson {
+				return fmt.Errorf("unsupported output format %q", ofmt)
+			}
+			akStyle, err := jsonconv.ParseArrayKeyStyle(ak)
+			if err != nil {
+				return err
+			}
+			collision, err := jsonconv.ParseKeyCollision(kc)
+			if err != nil {
+				return err
+			}
 			in := &flattenCmdInput{
-				inputPath:  rootFlags.InputPath,
-				outputPath: rootFlags.OutputPath,
-				raw:        rootFlags.RawData,
+				inputPaths:   rootFlags.InputPaths,
+				outputPath:   rootFlags.OutputPath,
+				outDir:       rootFlags.OutputDir,
+				workers:      rootFlags.Workers,
+				raw:          rootFlags.RawData,
+				inputFormat:  inFormat,
+				useNumber:    rootFlags.UseNumber,
+				outputFormat: ofmt,
+				indent:       parseIndent(ind),
+				sortKeys:     sk,
+				rulesPath:    rule,
 				flattenOpt: &jsonconv.FlattenOption{
-					Level:     lvl,
-					Gap:       gap,
-					SkipMap:   sm,
-					SkipArray: sa,
+					Level:         lvl,
+					Gap:           gap,
+					SkipMap:       sm,
+					SkipArray:     sa,
+					ArrayKeyStyle: akStyle,
+					OneBasedIndex: ob,
+					KeyCollision:  collision,
 				},
 			}
 			logger := logger.NewLogger(cmd)
-			repo := repository.NewRepository()
+			repo := repository.NewRepositoryWithCompression(rootFlags.compression)
 			return processFlattenCmd(logger, repo, in)
 		},
 	}
@@ -46,85 +81,248 @@ func NewFlattenCmd() *cobra.Command {
 	cmd.PersistentFlags().StringVar(&gap, "ga", jsonconv.DefaultFlattenGap, "gap for separating JSON object with its nested data")
 	cmd.PersistentFlags().BoolVar(&sm, "sm", false, "set it true to skip map type")
 	cmd.PersistentFlags().BoolVar(&sa, "sa", false, "set it true to skip array type")
+	cmd.PersistentFlags().StringVar(&rule, "rules", "", "path to a JSON file of flatten rules for some paths, e.g. [{\"path\": \"metadata\", \"level\": 1}, {\"path\": \"tags\", \"skipArray\": true}]")
+	cmd.PersistentFlags().StringVar(&ak, "array-key", "bracket", "style of keys of array elements: bracket (a[0]) or gap (a__0, separated by '--ga')")
+	cmd.PersistentFlags().BoolVar(&ob, "one-based", false, "set it true to start indices of array elements from 1")
+	cmd.PersistentFlags().StringVar(&kc, "key-collision", "overwrite", "what to do when two values are flattened to the same key: overwrite, error, escape (escape the gap in original keys with '\\'), suffix (append #2, #3, ...) or keep-first")
+	cmd.PersistentFlags().StringVar(&ind, "indent", "", "indent for pretty-printing JSON, either a number of spaces or a literal string such as $'\\t'. If not set, prints compact JSON")
+	cmd.PersistentFlags().BoolVar(&sk, "sort-keys", false, "set it true to sort keys of JSON objects alphabetically")
+	cmd.PersistentFlags().StringVar(&ofmt, "format", outputFormatJson, "output format: json (a single JSON value) or ndjson (one flattened JSON object per line, streamed)")
 	return cmd
 }
 
 type flattenCmdInput struct {
-	inputPath  string
-	outputPath string
-	raw        string
-	flattenOpt *jsonconv.FlattenOption
+	inputPaths   []string
+	outputPath   string
+	outDir       string
+	workers      int
+	raw          string
+	inputFormat  jsonconv.JsonFormat
+	useNumber    bool
+	outputFormat string
+	indent       string
+	sortKeys     bool
+	rulesPath    string
+	flattenOpt   *jsonconv.FlattenOption
+}
+
+// A flattenRuleEntry is a jsonconv.FlattenRule in a flatten rules file.
+type flattenRuleEntry struct {
+	Path      string `json:"path"`
+	Level     *int   `json:"level"`
+	SkipMap   bool   `json:"skipMap"`
+	SkipArray bool   `json:"skipArray"`
+}
+
+// loadFlattenRules reads the JSON array of flatten rules at filePath.
+// The level of a rule is unlimited if it is not set.
+func loadFlattenRules(repo repository.Repository, filePath string) ([]jsonconv.FlattenRule, error) {
+	fi, err := repo.GetFileReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	var entries []flattenRuleEntry
+	if err := json.NewDecoder(fi).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid flatten rules file, %v", err)
+	}
+	rules := make([]jsonconv.FlattenRule, 0, len(entries))
+	for _, e := range entries {
+		if e.Path == "" {
+			return nil, fmt.Errorf("invalid flatten rules file, a rule has no path")
+		}
+		r := jsonconv.FlattenRule{
+			Path:      e.Path,
+			Level:     jsonconv.FlattenLevelUnlimited,
+			SkipMap:   e.SkipMap,
+			SkipArray: e.SkipArray,
+		}
+		if e.Level != nil {
+			r.Level = *e.Level
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
 }
 
 func processFlattenCmd(logger logger.Logger, repo repository.Repository, in *flattenCmdInput) error {
-	var err error
-
-	// Create JSON reader.
-	var jr *jsonconv.JsonReader
-	switch {
-	case in.raw != "":
-		jr = jsonconv.NewJsonReader(strings.NewReader(in.raw))
-	case in.inputPath != "":
-		fi, err := repo.GetFileReader(in.inputPath)
+	if in.rulesPath != "" {
+		rules, err := loadFlattenRules(repo, in.rulesPath)
 		if err != nil {
 			return err
 		}
-		defer fi.Close()
-		data, err := io.ReadAll(fi)
-		if err != nil {
-			return fmt.Errorf("failed to read input file: %w", err)
-		}
-		jr = jsonconv.NewJsonReader(bytes.NewReader(data))
-	case !repo.IsStdinEmpty():
-		fi := repo.GetStdinReader()
-		defer fi.Close()
-		data, err := io.ReadAll(fi)
-		if err != nil {
-			return fmt.Errorf("failed to read stdin: %w", err)
+		fo := *in.flattenOpt
+		fo.Rules = rules
+		in.flattenOpt = &fo
+	}
+	inputPaths, err := expandInputPaths(repo, in.inputPaths)
+	if err != nil {
+		return err
+	}
+	in.inputPaths = inputPaths
+	if in.outDir != "" {
+		ext := ".json"
+		if in.outputFormat == outputFormatNdjson {
+			ext = ".ndjson"
 		}
-		jr = jsonconv.NewJsonReader(bytes.NewReader(data))
-	default:
-		return fmt.Errorf("need to input either raw data, input file path or data from stdin")
+		sl := &syncLogger{logger: logger}
+		return eachInput(in.inputPaths, in.outDir, ext, in.workers, func(inputPath, outputPath string) error {
+			each := *in
+			each.inputPaths = []string{inputPath}
+			each.outputPath = outputPath
+			each.outDir = ""
+			each.rulesPath = ""
+			return processFlattenCmd(sl, repo, &each)
+		})
+	}
+	if in.outputFormat == outputFormatNdjson {
+		return processFlattenStream(logger, repo, in)
 	}
 
-	// Read and parse JSON data.
-	var encoded any
-	err = jr.Read(&encoded)
+	// Read and parse JSON data of all inputs.
+	vals, err := readInputs(repo, in.raw, in.inputPaths, in.workers, func(r io.Reader) (any, error) {
+		var encoded any
+		if err := in.jsonReader(r).Read(&encoded); err != nil {
+			return nil, fmt.Errorf("invalid JSON data, %v", err)
+		}
+		return encoded, nil
+	})
 	if err != nil {
-		return fmt.Errorf("invalid JSON data, %v", err)
+		return err
 	}
 
-	switch val := encoded.(type) {
-	case []any:
-		var arr []map[string]any
-		for _, v := range val {
-			if obj, ok := v.(map[string]any); ok {
+	// Flatten a single JSON object as it is.
+	if len(vals) == 1 {
+		if obj, ok := vals[0].(map[string]any); ok {
+			if err := jsonconv.FlattenStrict(obj, in.flattenOpt); err != nil {
+				return err
+			}
+			return outputJsonContent(logger, repo, obj, in.outputPath, in.indent, in.sortKeys)
+		}
+	}
+
+	// Merge JSON objects of all inputs into a JSON array and flatten them.
+	var arr []map[string]any
+	for _, val := range vals {
+		switch val := val.(type) {
+		case []any:
+			for _, v := range val {
+				obj, ok := v.(map[string]any)
+				if !ok {
+					return fmt.Errorf("unsupport type of JSON data")
+				}
 				arr = append(arr, obj)
-				continue
 			}
-			return fmt.Errorf("unsupport type of JSON data")
+		case map[string]any:
+			arr = append(arr, val)
+		default:
+			return fmt.Errorf("unsupported JSON data type")
+		}
+	}
+	for _, obj := range arr {
+		if err := jsonconv.FlattenStrict(obj, in.flattenOpt); err != nil {
+			return err
 		}
+	}
+	return outputJsonContent(logger, repo, arr, in.outputPath, in.indent, in.sortKeys)
+}
 
-		// Flatten JSON array.
-		for _, obj := range arr {
-			jsonconv.Flatten(obj, in.flattenOpt)
+// jsonReader returns a JSON reader of r with the input options of in.
+func (in *flattenCmdInput) jsonReader(r io.Reader) *jsonconv.JsonReader {
+	jr := jsonconv.NewJsonReader(r)
+	jr.Format = in.inputFormat
+	jr.UseNumber = in.useNumber
+	return jr
+}
+
+func processFlattenStream(l logger.Logger, repo repository.Repository, in *flattenCmdInput) error {
+	// Inputs are merged in order, so raw data and stdin are a single input without a path.
+	inputPaths := in.inputPaths
+	if in.raw != "" || len(inputPaths) == 0 {
+		inputPaths = []string{""}
+	}
+
+	// Get JSON input stream of the first input.
+	r, err := openInput(repo, in.raw, inputPaths[0])
+	if err != nil {
+		return err
+	}
+
+	// Flatten JSON objects of every input one at a time, writing them to w.
+	flatten := func(w io.Writer) error {
+		jw := jsonconv.NewJsonWriter(w)
+		jw.SortKeys = in.sortKeys
+		for i, p := range inputPaths {
+			if i > 0 {
+				r, err = repo.GetFileReader(p)
+				if err != nil {
+					return err
+				}
+			}
+			if err := in.flattenInput(r, jw); err != nil {
+				if len(inputPaths) > 1 {
+					return fmt.Errorf("%s: %v", p, err)
+				}
+				return err
+			}
 		}
-		return outputJsonContent(logger, repo, arr, in.outputPath)
-	case map[string]any:
-		// Flatten JSON object.
-		jsonconv.Flatten(val, in.flattenOpt)
-		return outputJsonContent(logger, repo, val, in.outputPath)
+		return jw.Flush()
 	}
 
-	return fmt.Errorf("unsupported JSON data type")
+	// Get JSON output stream.
+	if in.outputPath == "" {
+		return flatten(logger.NewWriter(l))
+	}
+	fi, err := repo.CreateFileWriter(in.outputPath)
+	if err != nil {
+		r.Close()
+		return err
+	}
+	if err := flatten(fi); err != nil {
+		fi.Close()
+		return err
+	}
+	if err := fi.Close(); err != nil {
+		return err
+	}
+	l.Printf("The JSON file is located at %s\n", in.outputPath)
+	return nil
+}
+
+// flattenInput flattens JSON objects of r one at a time, writes them to jw and closes r.
+func (in *flattenCmdInput) flattenInput(r io.ReadCloser, jw *jsonconv.JsonWriter) error {
+	defer r.Close()
+	return flattenJsonStream(in.jsonReader(r), jw, in.flattenOpt)
 }
 
-func outputJsonContent(logger logger.Logger, repo repository.Repository, data any, filePath string) error {
+// flattenJsonStream flattens JSON objects of jr one at a time and writes them to jw.
+func flattenJsonStream(jr *jsonconv.JsonReader, jw *jsonconv.JsonWriter, opt *jsonconv.FlattenOption) error {
+	for {
+		obj, err := jr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON data, %v", err)
+		}
+		if err := jsonconv.FlattenStrict(obj, opt); err != nil {
+			return err
+		}
+		if err := jw.WriteRecord(obj); err != nil {
+			return err
+		}
+	}
+}
+
+func outputJsonContent(logger logger.Logger, repo repository.Repository, data any, filePath string, indent string, sortKeys bool) error {
 	// Check and override outputPath if necessary.
 	if filePath == "" {
 		// Create JSON writer with byte buffer.
 		buf := &bytes.Buffer{}
 		jw := jsonconv.NewJsonWriter(buf)
+		jw.Indent = indent
+		jw.SortKeys = sortKeys
 
 		// Write to JSON file.
 		err := jw.Write(data)
@@ -133,16 +331,13 @@ func outputJsonContent(logger logger.Logger, repo repository.Repository, data an
 		}
 		logger.Printf("%s\n", buf.String())
 	} else {
-		// Create JSON writer with output file.
-		fi, err := repo.CreateFileWriter(filePath)
-		if err != nil {
-			return err
-		}
-		defer fi.Close()
-		jw := jsonconv.NewJsonWriter(fi)
-
 		// Write to JSON file.
-		err = jw.Write(data)
+		err := writeFile(repo, filePath, func(w io.Writer) error {
+			jw := jsonconv.NewJsonWriter(w)
+			jw.Indent = indent
+			jw.SortKeys = sortKeys
+			return jw.Write(data)
+		})
 		if err != nil {
 			return err
 		}
@@ -150,3 +345,11 @@ func outputJsonContent(logger logger.Logger, repo repository.Repository, data an
 	}
 	return nil
 }
+
+// parseIndent returns s as a JSON indent. A number is treated as that many spaces.
+func parseIndent(s string) string {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return strings.Repeat(" ", n)
+	}
+	return s
+}
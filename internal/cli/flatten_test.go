@@ -3,8 +3,11 @@ package cli
 import (
 	"fmt"
 	"math"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/tuan78/jsonconv/v2"
 )
 
 func TestProcessFlattenCmd_NoInputData(t *testing.T) {
@@ -26,7 +29,7 @@ func TestProcessFlattenCmd_NoInputData(t *testing.T) {
 func TestProcessFlattenCmd_ReadFileError(t *testing.T) {
 	// Prepare
 	in := &flattenCmdInput{
-		inputPath: "test.json",
+		inputPaths: []string{"test.json"},
 	}
 	logger := NewMockLogger()
 	repo := NewMockRepository()
@@ -129,7 +132,7 @@ func TestOutputJsonContent_InvalidJson_WhenOutputToConsole(t *testing.T) {
 	repo := NewMockRepository()
 
 	// Process
-	err := outputJsonContent(logger, repo, invalid, "")
+	err := outputJsonContent(logger, repo, invalid, "", "", false)
 
 	// Check
 	if err == nil {
@@ -144,7 +147,7 @@ func TestOutputJsonContent_InvalidJson_WhenOutputToJsonFile(t *testing.T) {
 	repo := NewMockRepository()
 
 	// Process
-	err := outputJsonContent(logger, repo, invalid, "test.json")
+	err := outputJsonContent(logger, repo, invalid, "test.json", "", false)
 
 	// Check
 	if err == nil {
@@ -259,7 +262,7 @@ func TestProcessFlattenCmd_JsonArray(t *testing.T) {
 func TestProcessFlattenCmd_ReadFromJsonFile(t *testing.T) {
 	// Prepare
 	in := &flattenCmdInput{
-		inputPath: "test.json",
+		inputPaths: []string{"test.json"},
 	}
 	logger := NewMockLogger()
 	repo := NewMockRepository()
@@ -392,3 +395,435 @@ func TestProcessFlattenCmd_ToJsonFile(t *testing.T) {
 		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
 	}
 }
+
+func TestProcessFlattenCmd_NdjsonInputFormat(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw: `{"id": 1, "nested": {"a": 1}}
+{"id": 2, "nested": {"b": 2}}`,
+		inputFormat: jsonconv.JsonFormatNdjson,
+		flattenOpt:  jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `[{"id":1,"nested__a":1},{"id":2,"nested__b":2}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessFlattenCmd_InputFormatMismatch(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw:         `[{"id": 1}]`,
+		inputFormat: jsonconv.JsonFormatNdjson,
+		flattenOpt:  jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	expMsg := "invalid JSON data, line 1: expected JSON object"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessFlattenCmd_NdjsonOutput_NoInputData(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		outputFormat: outputFormatNdjson,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	expMsg := "need to input either raw data, input file path or data from stdin"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessFlattenCmd_NdjsonOutput_CreateFileError(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw:          `{"id": 1}`,
+		outputPath:   "test.json",
+		outputFormat: outputFormatNdjson,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileCreatingError = fmt.Errorf("mock create file error")
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	expMsg := repo.fileCreatingError.Error()
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessFlattenCmd_NdjsonOutput_InvalidJson(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw:          "{\"id\": 1}\n{\"id\": }",
+		outputFormat: outputFormatNdjson,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	expMsg := "invalid JSON data, line 2"
+	if err == nil || !strings.HasPrefix(err.Error(), expMsg) {
+		t.Fatalf("It should throw an error starts with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessFlattenCmd_NdjsonOutput_ToConsole(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw: `
+		[
+			{"id": 1, "nested": {"a": 1, "f": [4, 5]}},
+			{"id": 2, "nested": {"b": "<b>"}}
+		]`,
+		outputFormat: outputFormatNdjson,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	expMsg := `{"id":1,"nested__a":1,"nested__f[0]":4,"nested__f[1]":5}
+{"id":2,"nested__b":"\u003cb\u003e"}
+`
+	if logger.msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, logger.msg)
+	}
+}
+
+func TestProcessFlattenCmd_NdjsonOutput_ReadFromStdin_ToJsonFile(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		outputPath:   "test.json",
+		outputFormat: outputFormatNdjson,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.isStdinEmpty = false // fake stdin data
+	repo.readerContent = `{"id": 1, "nested": {"a": 1}}
+{"id": 2, "nested": {"b": 2}}`
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	msg := repo.writerBuffer.String()
+	expMsg := `{"id":1,"nested__a":1}
+{"id":2,"nested__b":2}
+`
+	if msg != expMsg {
+		t.Fatalf("It should write content: %s\ncurrent: %s", expMsg, msg)
+	}
+	expLog := "The JSON file is located at test.json\n"
+	if logger.msg != expLog {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expLog, logger.msg)
+	}
+}
+
+func TestProcessFlattenCmd_IndentAndSortKeys(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw:        `{"user": "Jon Doe", "id": 1, "nested": {"b": 2, "a": 1}}`,
+		indent:     "  ",
+		sortKeys:   true,
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `{
+  "id": 1,
+  "nested__a": 1,
+  "nested__b": 2,
+  "user": "Jon Doe"
+}`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestParseIndent(t *testing.T) {
+	// Prepare
+	cases := map[string]string{
+		"":   "",
+		"0":  "",
+		"2":  "  ",
+		"\t": "\t",
+		"-1": "-1",
+		"ab": "ab",
+	}
+
+	for s, expected := range cases {
+		// Process
+		indent := parseIndent(s)
+
+		// Check
+		if indent != expected {
+			t.Fatalf("parsed indent is incorrect for %q, current: %q", s, indent)
+		}
+	}
+}
+
+func TestProcessFlattenCmd_UseNumber(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw:        `{"id": 1234567890123456789, "nested": {"a": 1.50}}`,
+		sortKeys:   true,
+		useNumber:  true,
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `{"id":1234567890123456789,"nested__a":1.50}`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessFlattenCmd_Rules(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		raw:        `{"address": {"geo": {"lat": 1}}, "tags": ["a"], "metadata": {"x": {"y": 1}}}`,
+		sortKeys:   true,
+		rulesPath:  "rules.json",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.readerContent = `[{"path": "address"}, {"path": "tags", "skipArray": true}, {"path": "metadata", "level": 1}]`
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `{"address__geo__lat":1,"metadata__x":{"y":1},"tags":["a"]}`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+	if len(jsonconv.DefaultFlattenOption.Rules) != 0 {
+		t.Fatalf("It should not modify DefaultFlattenOption, current rules: %v", jsonconv.DefaultFlattenOption.Rules)
+	}
+}
+
+func TestProcessFlattenCmd_Rules_InvalidFile(t *testing.T) {
+	// Prepare
+	cases := map[string]string{
+		`{"path": "a"}`:  "invalid flatten rules file, json: cannot unmarshal object into Go value of type []cli.flattenRuleEntry",
+		`[{"level": 1}]`: "invalid flatten rules file, a rule has no path",
+	}
+
+	for content, expMsg := range cases {
+		in := &flattenCmdInput{
+			raw:        `{"id": 1}`,
+			rulesPath:  "rules.json",
+			flattenOpt: jsonconv.DefaultFlattenOption,
+		}
+		logger := NewMockLogger()
+		repo := NewMockRepository()
+		repo.readerContent = content
+
+		// Process
+		err := processFlattenCmd(logger, repo, in)
+
+		// Check
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestProcessFlattenCmd_MultipleInputs(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		inputPaths: []string{"a.json", "b.json"},
+		flattenOpt: jsonconv.DefaultFlattenOption,
+		workers:    2,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json": `{"id": 1, "a": {"b": 2}}`,
+		"b.json": `[{"id": 2}, {"id": 3, "c": [4]}]`,
+	}
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `[{"a__b":2,"id":1},{"id":2},{"c[0]":4,"id":3}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessFlattenCmd_NdjsonOutput_MultipleInputs(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		inputPaths:   []string{"a.json", "b.json"},
+		outputFormat: outputFormatNdjson,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json": `{"id": 1, "a": {"b": 2}}`,
+		"b.json": `[{"id": 2}, {"id": 3}]`,
+	}
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `{"a__b":2,"id":1}
+{"id":2}
+{"id":3}`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+	if repo.openReaders != 0 || repo.maxOpenReaders != 1 {
+		t.Fatalf("It should close every input before opening the next one, open: %d, max open: %d", repo.openReaders, repo.maxOpenReaders)
+	}
+
+	// Errors are reported with the path of their input.
+	repo.fileContents["b.json"] = `[{"id": 2}, {"id": 3`
+	err = processFlattenCmd(NewMockLogger(), repo, in)
+	expMsg = "b.json: invalid JSON data, line 1: unexpected EOF"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessFlattenCmd_OutDir(t *testing.T) {
+	// Prepare
+	in := &flattenCmdInput{
+		inputPaths:   []string{"a.json", "b.json"},
+		outDir:       "out",
+		outputFormat: outputFormatNdjson,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+		workers:      2,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json": `{"id": 1, "a": {"b": 2}}`,
+		"b.json": `[{"id": 2}, {"id": 3}]`,
+	}
+
+	// Process
+	err := processFlattenCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process flatten cmd, err: %v", err)
+	}
+	expected := map[string]string{
+		filepath.Join("out", "a.ndjson"): "{\"a__b\":2,\"id\":1}\n",
+		filepath.Join("out", "b.ndjson"): "{\"id\":2}\n{\"id\":3}\n",
+	}
+	if len(repo.writerBuffers) != len(expected) {
+		t.Fatalf("It should write %d files, current: %v", len(expected), repo.writerBuffers)
+	}
+	for path, exp := range expected {
+		if buf := repo.writerBuffers[path]; buf == nil || buf.String() != exp {
+			t.Fatalf("It should write %s with content: %s\ncurrent: %v", path, exp, buf)
+		}
+	}
+}
+
+func TestProcessFlattenCmd_FileClosingError(t *testing.T) {
+	// Prepare
+	cases := map[string]*flattenCmdInput{
+		"json":   {raw: `{"a": {"b": 1}}`, outputPath: "a.json"},
+		"ndjson": {raw: `{"a": {"b": 1}}`, outputPath: "a.ndjson", outputFormat: outputFormatNdjson},
+	}
+
+	for name, in := range cases {
+		in.flattenOpt = jsonconv.DefaultFlattenOption
+		logger := NewMockLogger()
+		repo := NewMockRepository()
+		repo.fileClosingError = fmt.Errorf("failed to close file")
+
+		// Process
+		err := processFlattenCmd(logger, repo, in)
+
+		// Check
+		expMsg := "failed to close file"
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s for %s\ncurrent: %v", expMsg, name, err)
+		}
+		if logger.msg != "" {
+			t.Fatalf("It should not show message for %s, current: %s", name, logger.msg)
+		}
+	}
+}
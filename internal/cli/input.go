@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tuan78/jsonconv/v2/internal/cli/logger"
+	"github.com/tuan78/jsonconv/v2/internal/cli/repository"
+	"github.com/tuan78/jsonconv/v2/internal/cli/utils"
+)
+
+// expandInputPaths returns the input files of paths, which may be files,
+// directories or glob patterns. Files are listed once, in the order of paths.
+func expandInputPaths(repo repository.Repository, paths []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		matches, err := repo.ListFiles(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input files match %q", p)
+		}
+		for _, f := range matches {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	return files, nil
+}
+
+// runParallel calls fn for every index in [0, n) with at most workers goroutines.
+// It returns the error of the lowest index, if any.
+func runParallel(n int, workers int, fn func(i int) error) error {
+	workers = max(1, min(workers, n))
+	errs := make([]error, n)
+	idx := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readInputs reads the raw data, every input file or stdin with read, in that order
+// of precedence. Input files are read in parallel by at most workers goroutines,
+// and the results are in the order of inputPaths.
+func readInputs[T any](repo repository.Repository, raw string, inputPaths []string, workers int, read func(r io.Reader) (T, error)) ([]T, error) {
+	if raw != "" || len(inputPaths) == 0 {
+		r, err := openInput(repo, raw, "")
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		v, err := read(r)
+		if err != nil {
+			return nil, err
+		}
+		return []T{v}, nil
+	}
+
+	res := make([]T, len(inputPaths))
+	err := runParallel(len(inputPaths), workers, func(i int) error {
+		fi, err := repo.GetFileReader(inputPaths[i])
+		if err != nil {
+			return err
+		}
+		defer fi.Close()
+		v, err := read(fi)
+		if err != nil {
+			if len(inputPaths) > 1 {
+				return fmt.Errorf("%s: %v", inputPaths[i], err)
+			}
+			return err
+		}
+		res[i] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// eachInput calls process for every input file in parallel with at most workers goroutines,
+// passing the path of its output file in outDir, named after the input file with extension ext.
+// The text output file of a compressed input file keeps its compression extension, e.g. ".csv.gz",
+// except for bzip2, which can only be decompressed. The process should print through a syncLogger.
+func eachInput(inputPaths []string, outDir string, ext string, workers int, process func(inputPath string, outputPath string) error) error {
+	if len(inputPaths) == 0 {
+		return fmt.Errorf("need to set '--in' for '--out-dir'")
+	}
+	outputPaths := make([]string, len(inputPaths))
+	inputOf := make(map[string]string, len(inputPaths))
+	for i, p := range inputPaths {
+		out := filepath.Join(outDir, inputName(p)+ext)
+		if !binaryOutputExts[ext] {
+			out += outputCompressionExt(p)
+		}
+		if other, exist := inputOf[out]; exist {
+			return fmt.Errorf("inputs %s and %s have the same output file %s", other, p, out)
+		}
+		inputOf[out] = p
+		outputPaths[i] = out
+	}
+
+	return runParallel(len(inputPaths), workers, func(i int) error {
+		if err := process(inputPaths[i], outputPaths[i]); err != nil {
+			return fmt.Errorf("%s: %v", inputPaths[i], err)
+		}
+		return nil
+	})
+}
+
+// binaryOutputExts are the extensions of output files which are never compressed,
+// since the applications reading them cannot open compressed files.
+var binaryOutputExts = map[string]bool{".xlsx": true, ".parquet": true}
+
+// outputCompressionExt returns the compression extension of the output file of inputPath.
+func outputCompressionExt(inputPath string) string {
+	switch ext := utils.CompressionExt(inputPath); ext {
+	case ".bz2", ".bzip2":
+		return ""
+	default:
+		return ext
+	}
+}
+
+// inputName returns the file name of inputPath without its extensions, e.g. "a" for "dir/a.json.gz".
+func inputName(inputPath string) string {
+	base := filepath.Base(inputPath)
+	base = strings.TrimSuffix(base, utils.CompressionExt(base))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// A syncLogger is a logger.Logger which can be used by several goroutines.
+type syncLogger struct {
+	mu     sync.Mutex
+	logger logger.Logger
+}
+
+func (l *syncLogger) Printf(format string, i ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logger.Printf(format, i...)
+}
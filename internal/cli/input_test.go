@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExpandInputPaths(t *testing.T) {
+	// Prepare
+	repo := NewMockRepository()
+	repo.listedFiles = map[string][]string{
+		"dir":    {"dir/a.json", "dir/b.json"},
+		"*.json": {"b.json", "dir/a.json"},
+	}
+
+	// Process
+	files, err := expandInputPaths(repo, []string{"dir", "*.json", "c.json"})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to expand input paths, err: %v", err)
+	}
+	expected := []string{"dir/a.json", "dir/b.json", "b.json", "c.json"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("input files are incorrect, %v is not equal expected value %v", files, expected)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	// Prepare
+	var running, peak atomic.Int32
+	done := make([]bool, 20)
+
+	// Process
+	err := runParallel(len(done), 3, func(i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		done[i] = true
+		if i == 7 || i == 12 {
+			return fmt.Errorf("failed at %d", i)
+		}
+		return nil
+	})
+
+	// Check
+	if err == nil || err.Error() != "failed at 7" {
+		t.Fatalf("It should throw an error with message: failed at 7\ncurrent: %v", err)
+	}
+	if peak.Load() > 3 {
+		t.Fatalf("It should run at most 3 workers, current: %d", peak.Load())
+	}
+	for i, ok := range done {
+		if !ok {
+			t.Fatalf("It should process index %d", i)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/tuan78/jsonconv/v2"
+	"github.com/tuan78/jsonconv/v2/internal/cli/logger"
+	"github.com/tuan78/jsonconv/v2/internal/cli/repository"
+)
+
+func NewJsonCmd() *cobra.Command {
+	var (
+		delim string
+		noft  bool
+		fga   string
+		noemp bool
+		str   bool
+		ak    string
+		ob    bool
+		esc   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "json",
+		Short: "Convert CSV to JSON",
+		Long:  "Convert CSV to JSON",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			akStyle, err := jsonconv.ParseArrayKeyStyle(ak)
+			if err != nil {
+				return err
+			}
+			in := &jsonCmdInput{
+				inputPaths: rootFlags.InputPaths,
+				outputPath: rootFlags.OutputPath,
+				outDir:     rootFlags.OutputDir,
+				workers:    rootFlags.Workers,
+				raw:        rootFlags.RawData,
+				delim:      delim,
+				omitEmpty:  noemp,
+				inferTypes: !str,
+			}
+			if !noft {
+				in.flattenOpt = &jsonconv.FlattenOption{
+					Level:         jsonconv.DefaultFlattenLevel,
+					Gap:           fga,
+					ArrayKeyStyle: akStyle,
+					OneBasedIndex: ob,
+				}
+				if esc {
+					in.flattenOpt.KeyCollision = jsonconv.KeyCollisionEscape
+				}
+			}
+			logger := logger.NewLogger(cmd)
+			repo := repository.NewRepositoryWithCompression(rootFlags.compression)
+			return processJsonCmd(logger, repo, in)
+		},
+	}
+
+	cmd.PersistentFlags().SortFlags = false
+	cmd.PersistentFlags().StringVar(&delim, "delim", ",", "field delimiter")
+	cmd.PersistentFlags().BoolVar(&noft, "noft", false, "set it true to skip JSON unflattening of CSV headers")
+	cmd.PersistentFlags().StringVar(&fga, "fga", jsonconv.DefaultFlattenGap, "flatten gap used to separate JSON object with its nested data in CSV headers")
+	cmd.PersistentFlags().StringVar(&ak, "array-key", "bracket", "style of keys of array elements in CSV headers: bracket (a[0]) or gap (a__0, separated by '--fga')")
+	cmd.PersistentFlags().BoolVar(&ob, "one-based", false, "set it true if indices of array elements in CSV headers start from 1")
+	cmd.PersistentFlags().BoolVar(&esc, "escaped", false, "set it true if keys in CSV headers were escaped with '--key-collision escape'")
+	cmd.PersistentFlags().BoolVar(&noemp, "noempty", false, "set it true to omit empty CSV cells from JSON objects")
+	cmd.PersistentFlags().BoolVar(&str, "str", false, "set it true to keep all CSV cells as JSON strings instead of detecting numbers, booleans and null")
+	return cmd
+}
+
+type jsonCmdInput struct {
+	inputPaths []string
+	outputPath string
+	outDir     string
+	workers    int
+	raw        string
+	delim      string
+	omitEmpty  bool
+	inferTypes bool
+	flattenOpt *jsonconv.FlattenOption
+}
+
+func processJsonCmd(logger logger.Logger, repo repository.Repository, in *jsonCmdInput) error {
+	inputPaths, err := expandInputPaths(repo, in.inputPaths)
+	if err != nil {
+		return err
+	}
+	in.inputPaths = inputPaths
+	if in.outDir != "" {
+		sl := &syncLogger{logger: logger}
+		return eachInput(in.inputPaths, in.outDir, ".json", in.workers, func(inputPath, outputPath string) error {
+			each := *in
+			each.inputPaths = []string{inputPath}
+			each.outputPath = outputPath
+			each.outDir = ""
+			return processJsonCmd(sl, repo, &each)
+		})
+	}
+
+	// Read CSV data of all inputs and convert it to JSON.
+	arrs, err := readInputs(repo, in.raw, in.inputPaths, in.workers, func(r io.Reader) ([]map[string]any, error) {
+		// Create CSV reader.
+		cr := jsonconv.NewCsvReader(r)
+		if runes := []rune(in.delim); len(runes) > 0 {
+			cr.Delimiter = runes[0]
+		}
+
+		// Read and parse CSV data.
+		data, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV data, %v", err)
+		}
+		arr, err := jsonconv.FromCsv(data, &jsonconv.FromCsvOption{
+			FlattenOption: in.flattenOpt,
+			OmitEmpty:     in.omitEmpty,
+			InferTypes:    in.inferTypes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot unflatten CSV headers, %v, set '--noft' to keep them flat", err)
+		}
+		return arr, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Output the JSON content.
+	arr := []map[string]any{}
+	for _, a := range arrs {
+		arr = append(arr, a...)
+	}
+	return outputJsonContent(logger, repo, arr, in.outputPath, "", false)
+}
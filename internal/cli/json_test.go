@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tuan78/jsonconv/v2"
+)
+
+func TestProcessJsonCmd_NoInputData(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	expMsg := "need to input either raw data, input file path or data from stdin"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessJsonCmd_ReadFileError(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		inputPaths: []string{"test.csv"},
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileOpeningError = fmt.Errorf("mock open file error")
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	expMsg := repo.fileOpeningError.Error()
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessJsonCmd_CreateFileError(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		raw:        "id,user\n1,Jon Doe",
+		outputPath: "test.json",
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileCreatingError = fmt.Errorf("mock create file error")
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	expMsg := repo.fileCreatingError.Error()
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s", expMsg)
+	}
+}
+
+func TestProcessJsonCmd_InvalidCsv(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		raw: "id,user\n1,Jon Doe,extra",
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	expMsg := "invalid CSV data"
+	if err == nil || !strings.HasPrefix(err.Error(), expMsg) {
+		t.Fatalf("It should throw an error starts with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessJsonCmd_UnflattenError(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		raw:        "a,a__b\n1,2",
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	expMsg := `cannot unflatten CSV headers, row 2: conflicting flattened keys: "a__b", set '--noft' to keep them flat`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessJsonCmd_EmptyJsonData_WhenHeaderOnly(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		raw: "id,user",
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process JSON cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := "[]"
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessJsonCmd_ToConsole(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		raw: `id|is active|nested__a|nested__f[0]|nested__f[1]|score|user
+b042ab5c-ca73-4460-b739-96410ea9d3a6|false|1|4|5|-100|Jon Doe
+ce06f5b1-5721-42c0-91e1-9f72a09c250a|true||4||1.5|Tuấn`,
+		delim:      "|",
+		omitEmpty:  true,
+		inferTypes: true,
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process JSON cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `[{"id":"b042ab5c-ca73-4460-b739-96410ea9d3a6","is active":false,"nested":{"a":1,"f":[4,5]},"score":-100,"user":"Jon Doe"},{"id":"ce06f5b1-5721-42c0-91e1-9f72a09c250a","is active":true,"nested":{"f":[4]},"score":1.5,"user":"Tuấn"}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessJsonCmd_ReadFromCsvFile(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		inputPaths: []string{"test.csv"},
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.readerContent = "id,nested__a\n1,2"
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process JSON cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `[{"id":"1","nested__a":"2"}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessJsonCmd_ReadFromStdin(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.isStdinEmpty = false // fake stdin data
+	repo.readerContent = "id,nested__a\n1,2"
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process JSON cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `[{"id":"1","nested":{"a":"2"}}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessJsonCmd_ToJsonFile(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		raw:        "id,nested__a\n1,2",
+		outputPath: "test.json",
+		inferTypes: true,
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process JSON cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(repo.writerBuffer.String())
+	expMsg := `[{"id":1,"nested":{"a":2}}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessJsonCmd_MultipleInputs(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		inputPaths: []string{"a.csv", "b.csv"},
+		flattenOpt: jsonconv.DefaultFlattenOption,
+		inferTypes: true,
+		workers:    2,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.csv": "id,a__b\n1,x\n",
+		"b.csv": "id,c\n2,true\n",
+	}
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process json cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(logger.msg)
+	expMsg := `[{"a":{"b":"x"},"id":1},{"c":true,"id":2}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestProcessJsonCmd_OutDir(t *testing.T) {
+	// Prepare
+	in := &jsonCmdInput{
+		inputPaths: []string{filepath.Join("in", "a.csv"), filepath.Join("in", "b.csv")},
+		outDir:     "out",
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.readerContent = "id\n1\n"
+
+	// Process
+	err := processJsonCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process json cmd, err: %v", err)
+	}
+	for _, path := range []string{filepath.Join("out", "a.json"), filepath.Join("out", "b.json")} {
+		exp := `[{"id":"1"}]` + "\n"
+		if buf := repo.writerBuffers[path]; buf == nil || buf.String() != exp {
+			t.Fatalf("It should write %s with content: %s\ncurrent: %v", path, exp, buf)
+		}
+	}
+}
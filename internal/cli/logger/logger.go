@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 )
@@ -25,3 +26,18 @@ func NewLogger(cmd *cobra.Command) Logger {
 func (l *logger) Printf(format string, i ...interface{}) {
 	fmt.Fprintf(l.cmd.OutOrStdout(), format, i...)
 }
+
+type writer struct {
+	logger Logger
+}
+
+// NewWriter returns an io.Writer that prints everything written to it through l.
+// It is useful to stream output to the same destination as l.
+func NewWriter(l Logger) io.Writer {
+	return &writer{logger: l}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.logger.Printf("%s", p)
+	return len(p), nil
+}
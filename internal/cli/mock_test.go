@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 )
 
 // WriteNopCloser .
@@ -27,16 +28,36 @@ func NewMockLogger() *mockLogger {
 }
 
 func (l *mockLogger) Printf(format string, i ...interface{}) {
-	l.msg = fmt.Sprintf(format, i...)
+	l.msg += fmt.Sprintf(format, i...)
 }
 
 // Mock Repository.
 type mockRepository struct {
 	readerContent     string
+	fileContents      map[string]string
+	listedFiles       map[string][]string
 	writerBuffer      *bytes.Buffer
+	writerBuffers     map[string]*bytes.Buffer
 	isStdinEmpty      bool
 	fileOpeningError  error
 	fileCreatingError error
+	fileClosingError  error
+	openReaders       int
+	maxOpenReaders    int
+	mu                sync.Mutex
+}
+
+// A mockReadCloser counts the open file readers of its mockRepository.
+type mockReadCloser struct {
+	io.Reader
+	repo *mockRepository
+}
+
+func (rc *mockReadCloser) Close() error {
+	rc.repo.mu.Lock()
+	defer rc.repo.mu.Unlock()
+	rc.repo.openReaders--
+	return nil
 }
 
 //nolint:revive // test helper returns concrete type for field access
@@ -46,13 +67,26 @@ func NewMockRepository() *mockRepository {
 	}
 }
 
-func (r *mockRepository) GetFileReader(_ string) (io.ReadCloser, error) {
+func (r *mockRepository) GetFileReader(path string) (io.ReadCloser, error) {
 	if r.fileOpeningError != nil {
 		return nil, r.fileOpeningError
 	}
-	re := strings.NewReader(r.readerContent)
-	recl := io.NopCloser(re)
-	return recl, nil
+	content := r.readerContent
+	if c, exist := r.fileContents[path]; exist {
+		content = c
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.openReaders++
+	r.maxOpenReaders = max(r.maxOpenReaders, r.openReaders)
+	return &mockReadCloser{Reader: strings.NewReader(content), repo: r}, nil
+}
+
+func (r *mockRepository) ListFiles(path string) ([]string, error) {
+	if files, exist := r.listedFiles[path]; exist {
+		return files, nil
+	}
+	return []string{path}, nil
 }
 
 func (r *mockRepository) GetStdinReader() io.ReadCloser {
@@ -65,10 +99,29 @@ func (r *mockRepository) IsStdinEmpty() bool {
 	return r.isStdinEmpty
 }
 
-func (r *mockRepository) CreateFileWriter(_ string) (io.WriteCloser, error) {
+func (r *mockRepository) CreateFileWriter(path string) (io.WriteCloser, error) {
 	if r.fileCreatingError != nil {
 		return nil, r.fileCreatingError
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.writerBuffer = &bytes.Buffer{}
+	if r.writerBuffers == nil {
+		r.writerBuffers = make(map[string]*bytes.Buffer)
+	}
+	r.writerBuffers[path] = r.writerBuffer
+	if r.fileClosingError != nil {
+		return &mockWriteCloser{Writer: r.writerBuffer, err: r.fileClosingError}, nil
+	}
 	return &WriteNopCloser{Writer: r.writerBuffer}, nil
 }
+
+// A mockWriteCloser fails to close with err.
+type mockWriteCloser struct {
+	io.Writer
+	err error
+}
+
+func (wc *mockWriteCloser) Close() error {
+	return wc.err
+}
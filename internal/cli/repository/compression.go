@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/tuan78/jsonconv/v2/internal/cli/utils"
+)
+
+// A Compression is a compression format of files.
+type Compression int
+
+const (
+	// Detect the compression of created files from their extension.
+	CompressionAuto Compression = iota
+	CompressionNone
+	CompressionGzip
+	CompressionZstd
+	// Bzip2 is only supported to read files.
+	CompressionBzip2
+)
+
+// ParseCompression returns the Compression of created files named s: auto, none, gzip or zstd.
+func ParseCompression(s string) (Compression, error) {
+	switch s {
+	case "auto", "":
+		return CompressionAuto, nil
+	case "none":
+		return CompressionNone, nil
+	case "gzip":
+		return CompressionGzip, nil
+	case "zstd":
+		return CompressionZstd, nil
+	case "bzip2":
+		return CompressionAuto, errBzip2Compression
+	}
+	return CompressionAuto, fmt.Errorf("unsupported compression %q", s)
+}
+
+var errBzip2Compression = errors.New("bzip2 compression is supported for input only")
+
+// Magic bytes at the start of compressed data.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+
+	// A bzip2 stream continues with a block size from '1' to '9', then
+	// the magic of its first block, or the one of its end if it is empty.
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// compressionHeaderLen is the length of the header needed by detectCompression.
+const compressionHeaderLen = 10
+
+// detectCompression returns the compression of data starting with header.
+// Text such as "BZhash" is not taken for bzip2 data, whose header is checked
+// up to its first block.
+func detectCompression(header []byte) Compression {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return CompressionZstd
+	case isBzip2Header(header):
+		return CompressionBzip2
+	}
+	return CompressionNone
+}
+
+// isBzip2Header reports whether header is the start of a bzip2 stream.
+func isBzip2Header(header []byte) bool {
+	if len(header) < compressionHeaderLen || !bytes.HasPrefix(header, bzip2Magic) {
+		return false
+	}
+	if size := header[len(bzip2Magic)]; size < '1' || size > '9' {
+		return false
+	}
+	block := header[len(bzip2Magic)+1 : compressionHeaderLen]
+	return bytes.Equal(block, bzip2BlockMagic) || bytes.Equal(block, bzip2EndMagic)
+}
+
+// compressionOfPath returns the compression of path from its extension.
+func compressionOfPath(path string) Compression {
+	switch utils.CompressionExt(path) {
+	case ".gz", ".gzip":
+		return CompressionGzip
+	case ".zst", ".zstd":
+		return CompressionZstd
+	case ".bz2", ".bzip2":
+		return CompressionBzip2
+	}
+	return CompressionNone
+}
+
+// newDecompressor returns a reader of the data decompressed from r with c.
+func newDecompressor(r io.Reader, c Compression) (io.ReadCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// newCompressor returns a writer which compresses data to w with c.
+// Its Close does not close w.
+func newCompressor(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionBzip2:
+		return nil, errBzip2Compression
+	}
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// A codecReadCloser reads through a decompressor, closing it before its file.
+type codecReadCloser struct {
+	io.Reader
+	codec io.Closer
+	file  io.Closer
+}
+
+func (r *codecReadCloser) Close() error {
+	err := r.codec.Close()
+	if ferr := r.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// A codecWriteCloser writes through a compressor, closing it before its file
+// so that all compressed data is flushed.
+type codecWriteCloser struct {
+	io.Writer
+	codec io.Closer
+	file  io.Closer
+}
+
+func (w *codecWriteCloser) Close() error {
+	err := w.codec.Close()
+	if ferr := w.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"bufio"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/tuan78/jsonconv/v2/internal/cli/utils"
 )
@@ -13,6 +16,8 @@ type (
 	Repository interface {
 		GetFileReader(path string) (io.ReadCloser, error)
 
+		ListFiles(path string) ([]string, error)
+
 		GetStdinReader() io.ReadCloser
 
 		IsStdinEmpty() bool
@@ -20,16 +25,81 @@ type (
 		CreateFileWriter(path string) (io.WriteCloser, error)
 	}
 
-	repository struct{}
+	repository struct {
+		compression Compression
+	}
 )
 
+// NewRepository returns a Repository which decompresses files it reads and
+// compresses files it creates, detecting the compression of created files
+// from their extension (.gz, .zst).
 func NewRepository() Repository {
 	return &repository{}
 }
 
+// NewRepositoryWithCompression is like NewRepository, but compresses
+// created files with c instead of detecting it from their extension.
+func NewRepositoryWithCompression(c Compression) Repository {
+	return &repository{compression: c}
+}
+
+// GetFileReader opens the file at path. The compression of the file is detected
+// from its magic bytes, so compressed files are decompressed transparently.
 func (r *repository) GetFileReader(path string) (io.ReadCloser, error) {
 	// #nosec G304 -- CLI tool explicitly opens user-specified files
-	return os.Open(filepath.Clean(path))
+	fi, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(fi)
+	header, _ := br.Peek(compressionHeaderLen)
+	dr, err := newDecompressor(br, detectCompression(header))
+	if err != nil {
+		fi.Close()
+		return nil, fmt.Errorf("invalid compressed file %s, %v", path, err)
+	}
+	return &codecReadCloser{Reader: dr, codec: dr, file: fi}, nil
+}
+
+// ListFiles returns the files matched by path: the path itself, the files in it
+// if it is a directory (not recursively, skipping hidden ones) or the files
+// matched by it if it is a glob pattern (hidden ones only if the pattern starts with a dot).
+// A file or directory named like a glob pattern, e.g. "data[1].json", is taken as it is.
+func (r *repository) ListFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil && utils.HasGlobMeta(path) {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		hidden := strings.HasPrefix(filepath.Base(path), ".")
+		var files []string
+		for _, m := range matches {
+			if !hidden && strings.HasPrefix(filepath.Base(m), ".") {
+				continue
+			}
+			if info, err := os.Stat(m); err == nil && !info.IsDir() {
+				files = append(files, m)
+			}
+		}
+		return files, nil
+	}
+	if err != nil || !info.IsDir() {
+		// Let GetFileReader report the error of a missing file.
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+	return files, nil
 }
 
 func (r *repository) GetStdinReader() io.ReadCloser {
@@ -45,7 +115,29 @@ func (r *repository) IsStdinEmpty() bool {
 	return info.Size() == 0
 }
 
+// CreateFileWriter creates the file at path, compressing data written to it
+// with the compression of the repository.
 func (r *repository) CreateFileWriter(path string) (io.WriteCloser, error) {
+	c := r.compression
+	if c == CompressionAuto {
+		c = compressionOfPath(path)
+	}
+	if c == CompressionBzip2 {
+		return nil, errBzip2Compression
+	}
+	fi, err := r.createFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cw, err := newCompressor(fi, c)
+	if err != nil {
+		fi.Close()
+		return nil, err
+	}
+	return &codecWriteCloser{Writer: cw, codec: cw, file: fi}, nil
+}
+
+func (r *repository) createFile(path string) (*os.File, error) {
 	// Clean path to prevent traversal attacks
 	path = filepath.Clean(path)
 
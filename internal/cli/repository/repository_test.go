@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRepository_Compression(t *testing.T) {
+	// Prepare
+	dir := t.TempDir()
+	content := `{"id": 1}`
+	cases := map[string]Repository{
+		"data.json":     NewRepository(),
+		"data.json.gz":  NewRepository(),
+		"data.json.zst": NewRepository(),
+		"data.gzip":     NewRepositoryWithCompression(CompressionGzip),
+		"data.zstd":     NewRepositoryWithCompression(CompressionZstd),
+		"plain.json.gz": NewRepositoryWithCompression(CompressionNone),
+	}
+
+	for name, repo := range cases {
+		path := filepath.Join(dir, name)
+
+		// Process
+		w, err := repo.CreateFileWriter(path)
+		if err != nil {
+			t.Fatalf("failed to create %s, err: %v", name, err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatalf("failed to write %s, err: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close %s, err: %v", name, err)
+		}
+		r, err := NewRepository().GetFileReader(path)
+		if err != nil {
+			t.Fatalf("failed to open %s, err: %v", name, err)
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+
+		// Check
+		if err != nil || string(b) != content {
+			t.Fatalf("It should read %s with content: %s\ncurrent: %s, err: %v", name, content, b, err)
+		}
+		raw, _ := os.ReadFile(path)
+		compressed := !bytes.Equal(raw, []byte(content))
+		if expected := name != "data.json" && name != "plain.json.gz"; compressed != expected {
+			t.Fatalf("%s should be compressed: %v", name, expected)
+		}
+	}
+}
+
+func TestRepository_Bzip2(t *testing.T) {
+	// Prepare
+	path := filepath.Join(t.TempDir(), "data")
+	bz := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x51, 0x99, 0x3a, 0xf8, 0x00, 0x00,
+		0x03, 0xd9, 0x80, 0x00, 0x10, 0x10, 0x00, 0x20, 0x10, 0x04, 0x20, 0x00, 0x0a, 0x20, 0x00, 0x22,
+		0x03, 0x1a, 0x84, 0x30, 0x20, 0x64, 0x66, 0x43, 0xc5, 0xdc, 0x91, 0x4e, 0x14, 0x24, 0x14, 0x66,
+		0x4e, 0xbe, 0x00,
+	}
+	if err := os.WriteFile(path, bz, 0600); err != nil {
+		t.Fatalf("failed to write file, err: %v", err)
+	}
+
+	// Process
+	r, err := NewRepository().GetFileReader(path)
+	if err != nil {
+		t.Fatalf("failed to open file, err: %v", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+
+	// Check
+	if err != nil || string(b) != "{\"id\":1}\n" {
+		t.Fatalf("It should read decompressed content, current: %s, err: %v", b, err)
+	}
+	_, err = NewRepository().CreateFileWriter(path + ".bz2")
+	if err == nil || err.Error() != "bzip2 compression is supported for input only" {
+		t.Fatalf("It should throw an error for bzip2 compression, current: %v", err)
+	}
+}
+
+func TestRepository_Bzip2Text(t *testing.T) {
+	// Prepare
+	cases := []string{"BZhash,x\n1,2\n", "BZh9", "BZh91AY&SX plain text"}
+
+	for _, content := range cases {
+		path := filepath.Join(t.TempDir(), "data.csv")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write file, err: %v", err)
+		}
+
+		// Process
+		r, err := NewRepository().GetFileReader(path)
+		if err != nil {
+			t.Fatalf("failed to open file, err: %v", err)
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+
+		// Check
+		if err != nil || string(b) != content {
+			t.Fatalf("It should read %q as plain text, current: %q, err: %v", content, b, err)
+		}
+	}
+}
+
+func TestRepository_InvalidCompressedFile(t *testing.T) {
+	// Prepare
+	path := filepath.Join(t.TempDir(), "data.gz")
+	if err := os.WriteFile(path, []byte{0x1f, 0x8b, 0x00}, 0600); err != nil {
+		t.Fatalf("failed to write file, err: %v", err)
+	}
+
+	// Process
+	_, err := NewRepository().GetFileReader(path)
+
+	// Check
+	if err == nil {
+		t.Fatalf("It should throw an error for an invalid gzip file")
+	}
+}
+
+func TestRepository_ListFiles(t *testing.T) {
+	// Prepare
+	dir := t.TempDir()
+	for _, name := range []string{"data[1].json", "data1.json", "data2.json", ".hidden.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{}`), 0600); err != nil {
+			t.Fatalf("failed to write file, err: %v", err)
+		}
+	}
+	cases := map[string][]string{
+		filepath.Join(dir, "data[1].json"): {filepath.Join(dir, "data[1].json")},
+		filepath.Join(dir, "data[2].json"): {filepath.Join(dir, "data2.json")},
+		filepath.Join(dir, "data?.json"):   {filepath.Join(dir, "data1.json"), filepath.Join(dir, "data2.json")},
+		filepath.Join(dir, "missing.json"): {filepath.Join(dir, "missing.json")},
+	}
+
+	for path, expected := range cases {
+		// Process
+		files, err := NewRepository().ListFiles(path)
+
+		// Check
+		if err != nil || !reflect.DeepEqual(files, expected) {
+			t.Fatalf("It should list %v for %s, current: %v, err: %v", expected, path, files, err)
+		}
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	// Prepare
+	cases := map[string]Compression{
+		"":     CompressionAuto,
+		"auto": CompressionAuto,
+		"none": CompressionNone,
+		"gzip": CompressionGzip,
+		"zstd": CompressionZstd,
+	}
+
+	for s, expected := range cases {
+		// Process
+		c, err := ParseCompression(s)
+
+		// Check
+		if err != nil || c != expected {
+			t.Fatalf("It should parse %q as %v, current: %v, err: %v", s, expected, c, err)
+		}
+	}
+	if _, err := ParseCompression("lz4"); err == nil || err.Error() != `unsupported compression "lz4"` {
+		t.Fatalf("It should throw an error for an unsupported compression, current: %v", err)
+	}
+	if _, err := ParseCompression("bzip2"); err == nil || err.Error() != "bzip2 compression is supported for input only" {
+		t.Fatalf("It should throw an error for bzip2 compression, current: %v", err)
+	}
+}
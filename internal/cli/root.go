@@ -2,9 +2,14 @@ package cli
 
 import (
 	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/tuan78/jsonconv/v2/internal/cli/repository"
 )
 
 var (
@@ -14,33 +19,98 @@ var (
 )
 
 type RootFlags struct {
-	InputPath  string
-	OutputPath string
-	RawData    string
+	InputPaths  []string
+	InputFormat string
+	OutputPath  string
+	OutputDir   string
+	RawData     string
+	UseNumber   bool
+	Workers     int
+	Compress    string
+
+	// compression is parsed from Compress by validate.
+	compression repository.Compression
 }
 
 var rootFlags = &RootFlags{}
 
+// Output formats of JSON content.
+const (
+	outputFormatJson   = "json"
+	outputFormatNdjson = "ndjson"
+)
+
 func NewRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "jsonconv",
-		Short:   "Tool for flattening JSON and converting JSON to CSV",
-		Long:    "Tool for flattening JSON and converting JSON to CSV",
+		Short:   "Tool for flattening JSON and converting between JSON and CSV",
+		Long:    "Tool for flattening JSON and converting between JSON and CSV",
 		Version: version,
-		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
 			flag.Parse()
+			return rootFlags.validate()
 		},
 	}
 	// Add flags.
-	cmd.PersistentFlags().StringVarP(&rootFlags.RawData, "data", "d", "", "raw JSON data. If both '--data' and '--in' are not set, reads from Stdin instead")
-	cmd.PersistentFlags().StringVarP(&rootFlags.InputPath, "in", "i", "", "input file path. If both '--data' and '--in' are not set, reads from Stdin instead")
+	cmd.PersistentFlags().StringVarP(&rootFlags.RawData, "data", "d", "", "raw JSON data (or CSV data for the json command). If both '--data' and '--in' are not set, reads from Stdin instead")
+	cmd.PersistentFlags().StringSliceVarP(&rootFlags.InputPaths, "in", "i", nil, "input file paths, directories or glob patterns (e.g. 'dumps/*.json'). Several inputs are merged into one output unless '--out-dir' is set. If both '--data' and '--in' are not set, reads from Stdin instead")
+	cmd.PersistentFlags().StringVar(&rootFlags.InputFormat, "in-format", "auto", "input JSON format: auto, array, object or ndjson")
+	cmd.PersistentFlags().BoolVar(&rootFlags.UseNumber, "use-number", true, "keep JSON numbers as they are written instead of converting them to float64, so large integers keep their precision")
 	cmd.PersistentFlags().StringVarP(&rootFlags.OutputPath, "out", "o", "", "output file path. It not set, prints to Stdout instead")
+	cmd.PersistentFlags().StringVar(&rootFlags.OutputDir, "out-dir", "", "output directory to write one output file per input file, named after it (or the CSV tables of the csv command when '--normalize' is set)")
+	cmd.PersistentFlags().StringVar(&rootFlags.Compress, "compress", "auto", "compression of output files: auto (detected from their extension, e.g. '.csv.gz' or '.json.zst'), none, gzip or zstd. XLSX and Parquet outputs are never compressed. Compressed input files (gzip, zstd or bzip2) are always detected and decompressed")
+	cmd.PersistentFlags().IntVar(&rootFlags.Workers, "workers", runtime.NumCPU(), "maximum number of input files processed in parallel")
 
 	// Add commands.
 	cmd.AddCommand(NewFlattenCmd())
 	cmd.AddCommand(NewCsvCmd())
+	cmd.AddCommand(NewJsonCmd())
+	cmd.AddCommand(NewSchemaCmd())
 
 	// Parse flags.
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	return cmd
 }
+
+// validate returns an error for invalid root flags.
+func (f *RootFlags) validate() error {
+	if f.OutputPath != "" && f.OutputDir != "" {
+		return fmt.Errorf("cannot use '--out' with '--out-dir'")
+	}
+	if f.Workers < 1 {
+		return fmt.Errorf("invalid number of workers %d", f.Workers)
+	}
+	c, err := repository.ParseCompression(f.Compress)
+	if err != nil {
+		return err
+	}
+	f.compression = c
+	return nil
+}
+
+// openInput returns a reader of raw data, the file at inputPath or stdin, in that order of precedence.
+func openInput(repo repository.Repository, raw string, inputPath string) (io.ReadCloser, error) {
+	switch {
+	case raw != "":
+		return io.NopCloser(strings.NewReader(raw)), nil
+	case inputPath != "":
+		return repo.GetFileReader(inputPath)
+	case !repo.IsStdinEmpty():
+		return repo.GetStdinReader(), nil
+	}
+	return nil, fmt.Errorf("need to input either raw data, input file path or data from stdin")
+}
+
+// writeFile creates the file at filePath and writes it with write. The file is closed
+// before returning, and an error of closing it, e.g. of flushing compressed data, is returned.
+func writeFile(repo repository.Repository, filePath string, write func(w io.Writer) error) error {
+	fi, err := repo.CreateFileWriter(filePath)
+	if err != nil {
+		return err
+	}
+	if err := write(fi); err != nil {
+		fi.Close()
+		return err
+	}
+	return fi.Close()
+}
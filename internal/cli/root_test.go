@@ -2,6 +2,8 @@ package cli
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -98,3 +100,392 @@ func TestRootCmd_FlattenCmd(t *testing.T) {
 		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
 	}
 }
+
+func TestRootCmd_JsonCmd(t *testing.T) {
+	// Prepare
+	raw := `id,is active,nested__a,nested__f[0],nested__f[1],score,user
+b042ab5c-ca73-4460-b739-96410ea9d3a6,false,1,4,5,-100,Jon Doe`
+	outBuf := &bytes.Buffer{}
+	rootCmd := NewRootCmd()
+	rootCmd.SetOut(outBuf)
+	rootCmd.SetErr(outBuf)
+	rootCmd.SetArgs([]string{"json", "-d", raw})
+
+	// Process
+	err := rootCmd.Execute()
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to execute json cmd, err: %v", err)
+	}
+	msg := strings.TrimSpace(outBuf.String())
+	expMsg := `[{"id":"b042ab5c-ca73-4460-b739-96410ea9d3a6","is active":false,"nested":{"a":1,"f":[4,5]},"score":-100,"user":"Jon Doe"}]`
+	if msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+	}
+}
+
+func TestRootCmd_FlattenCmd_UnsupportedFormat(t *testing.T) {
+	// Prepare
+	outBuf := &bytes.Buffer{}
+	rootCmd := NewRootCmd()
+	rootCmd.SetOut(outBuf)
+	rootCmd.SetErr(outBuf)
+	rootCmd.SetArgs([]string{"flatten", "-d", `{"id": 1}`, "--format", "xml"})
+
+	// Process
+	err := rootCmd.Execute()
+
+	// Check
+	expMsg := `unsupported output format "xml"`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestRootCmd_CsvCmd_UnsupportedValueFormat(t *testing.T) {
+	// Prepare
+	outBuf := &bytes.Buffer{}
+	rootCmd := NewRootCmd()
+	rootCmd.SetOut(outBuf)
+	rootCmd.SetErr(outBuf)
+	rootCmd.SetArgs([]string{"csv", "-d", `{"id": 1}`, "--value-format", "xml"})
+
+	// Process
+	err := rootCmd.Execute()
+
+	// Check
+	expMsg := `unsupported value format "xml"`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestRootCmd_CsvCmd_UnsupportedHeaderOrder(t *testing.T) {
+	// Prepare
+	outBuf := &bytes.Buffer{}
+	rootCmd := NewRootCmd()
+	rootCmd.SetOut(outBuf)
+	rootCmd.SetErr(outBuf)
+	rootCmd.SetArgs([]string{"csv", "-d", `{"id": 1}`, "--hs-order", "random"})
+
+	// Process
+	err := rootCmd.Execute()
+
+	// Check
+	expMsg := `unsupported header order "random"`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestRootCmd_CsvCmd_DefaultHeaderOrder(t *testing.T) {
+	// Prepare
+	raw := `{"id": 1, "f": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10]}`
+	cases := map[string][]string{
+		"f[0],f[1],f[2],f[3],f[4],f[5],f[6],f[7],f[8],f[9],f[10],id": {"csv", "-d", raw},
+		"f[0],f[10],f[1],f[2],f[3],f[4],f[5],f[6],f[7],f[8],f[9],id": {"csv", "-d", raw, "--hs-order", "alphabetical"},
+		"f,id": {"csv", "-d", raw, "--fsa"},
+	}
+
+	for expMsg, args := range cases {
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(args)
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to execute csv cmd, err: %v", err)
+		}
+		msg, _, _ := strings.Cut(outBuf.String(), "\n")
+		if msg != expMsg {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+		}
+	}
+}
+
+func TestRootCmd_CsvCmd_UnsupportedExplodeMode(t *testing.T) {
+	// Prepare
+	outBuf := &bytes.Buffer{}
+	rootCmd := NewRootCmd()
+	rootCmd.SetOut(outBuf)
+	rootCmd.SetErr(outBuf)
+	rootCmd.SetArgs([]string{"csv", "-d", `{"id": 1}`, "--explode", "items", "--explode-mode", "product"})
+
+	// Process
+	err := rootCmd.Execute()
+
+	// Check
+	expMsg := `unsupported explode mode "product"`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestRootCmd_CsvCmd_NormalizeWithoutOutDir(t *testing.T) {
+	// Prepare
+	cases := map[string][]string{
+		"need to set '--out-dir' for '--normalize'": {"csv", "-d", `{"id": 1}`, "--normalize"},
+		"cannot use '--normalize' with '--stream'":  {"csv", "-d", `{"id": 1}`, "--normalize", "--out-dir", "out", "--stream"},
+		"cannot use '--normalize' with '--noft'":    {"csv", "-d", `{"id": 1}`, "--normalize", "--out-dir", "out", "--noft"},
+	}
+
+	for expMsg, args := range cases {
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(args)
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestRootCmd_ArrayKeyFlags(t *testing.T) {
+	// Prepare
+	cases := map[string][]string{
+		`{"f_1":4,"f_2_x":"y","id":1}`: {"flatten", "-d", `{"id": 1, "f": [4, {"x": "y"}]}`, "--ga", "_", "--array-key", "gap", "--one-based"},
+		"f_1,f_2_x,id\n4,y,1":          {"csv", "-d", `{"id": 1, "f": [4, {"x": "y"}]}`, "--fga", "_", "--array-key", "gap", "--one-based"},
+		`[{"f":[4,{"x":"y"}],"id":1}]`: {"json", "-d", "f_1,f_2_x,id\n4,y,1", "--fga", "_", "--array-key", "gap", "--one-based"},
+	}
+
+	for expMsg, args := range cases {
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(args)
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to execute %s cmd, err: %v", args[0], err)
+		}
+		msg := strings.TrimSpace(outBuf.String())
+		if msg != expMsg {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+		}
+	}
+}
+
+func TestRootCmd_UnsupportedArrayKeyStyle(t *testing.T) {
+	for _, name := range []string{"flatten", "csv", "json"} {
+		// Prepare
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs([]string{name, "-d", `{"id": 1}`, "--array-key", "dot"})
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		expMsg := `unsupported array key style "dot"`
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestRootCmd_KeyCollisionFlags(t *testing.T) {
+	// Prepare
+	raw := `{"a": {"b": 1}, "a__b": 2}`
+	cases := map[string][]string{
+		`{"a__b":2}`:               {"flatten", "-d", raw, "--key-collision", "overwrite"},
+		`{"a__b":1}`:               {"flatten", "-d", raw, "--key-collision", "keep-first"},
+		`{"a__b":1,"a__b#2":2}`:    {"flatten", "-d", raw, "--key-collision", "suffix"},
+		`{"a\\_\\_b":2,"a__b":1}`:  {"flatten", "-d", raw, "--key-collision", "escape"},
+		"a__b,a\\_\\_b\n1,2":       {"csv", "-d", raw, "--key-collision", "escape"},
+		`[{"a":{"b":1},"a__b":2}]`: {"json", "-d", "a\\_\\_b,a__b\n2,1", "--escaped"},
+	}
+
+	for expMsg, args := range cases {
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(args)
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to execute %s cmd, err: %v", args[0], err)
+		}
+		msg := strings.TrimSpace(outBuf.String())
+		if msg != expMsg {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+		}
+	}
+}
+
+func TestRootCmd_RulesFlag(t *testing.T) {
+	// Prepare
+	rulesPath := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(rulesPath, []byte(`[{"path": "meta", "level": 0}]`), 0o644); err != nil {
+		t.Fatalf("failed to write rules file, err: %v", err)
+	}
+	raw := `{"meta": {"id": 1}}`
+	cases := map[string][]string{
+		`{"meta":{"id":1}}`:        {"flatten", "-d", raw, "--rules", rulesPath},
+		"meta\n\"{\"\"id\"\":1}\"": {"csv", "-d", raw, "--rules", rulesPath},
+		"KEY   TYPES   NULL %  PRESENT %  MAX LENGTH  EXAMPLES\nmeta  object  0       100        8           {\"id\":1}": {"schema", "-d", raw, "--rules", rulesPath},
+	}
+
+	for expMsg, args := range cases {
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(args)
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to execute %s cmd, err: %v", args[0], err)
+		}
+		msg := strings.TrimSpace(outBuf.String())
+		if msg != expMsg {
+			t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, msg)
+		}
+	}
+}
+
+func TestRootCmd_KeyCollisionError(t *testing.T) {
+	// Prepare
+	raw := `{"a": {"b": 1}, "a__b": 2}`
+	exploded := `{"a": [{"b": 1}], "a__b": 2}`
+	dir := t.TempDir()
+	cases := [][]string{
+		{"flatten", "-d", raw, "--key-collision", "error"},
+		{"flatten", "-d", raw, "--key-collision", "error", "--format", "ndjson"},
+		{"csv", "-d", raw, "--key-collision", "error"},
+		{"csv", "-d", raw, "--key-collision", "error", "--hs-order", "source"},
+		{"csv", "-d", raw, "--key-collision", "error", "--stream"},
+		{"csv", "-d", exploded, "--key-collision", "error", "--explode", "a"},
+		{"csv", "-d", raw, "--key-collision", "error", "--normalize", "--out-dir", dir},
+		{"csv", "-d", raw, "--key-collision", "error", "--format", "xlsx", "-o", filepath.Join(dir, "a.xlsx")},
+		{"csv", "-d", raw, "--key-collision", "error", "--format", "parquet", "-o", filepath.Join(dir, "a.parquet")},
+		{"schema", "-d", raw, "--key-collision", "error"},
+	}
+
+	for _, args := range cases {
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(args)
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		expMsg := `colliding flattened keys: "a__b"`
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestRootCmd_CsvCmd_BinaryFormatsNotCompressed(t *testing.T) {
+	// Prepare
+	dir := t.TempDir()
+	cases := map[string][]string{
+		"a.xlsx":       {"--format", "xlsx", "--compress", "gzip"},
+		"b.xlsx.gz":    {"--format", "xlsx"},
+		"c.parquet":    {"--format", "parquet", "--compress", "zstd"},
+		"d.parquet.gz": {"--format", "parquet"},
+	}
+	magic := map[string]string{"xlsx": "PK", "parquet": "PAR1"}
+
+	for name, flags := range cases {
+		path := filepath.Join(dir, name)
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(append([]string{"csv", "-d", `{"id": 1}`, "-o", path}, flags...))
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		if err != nil {
+			t.Fatalf("It should write %s, err: %v", name, err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil || !bytes.HasPrefix(b, []byte(magic[flags[1]])) {
+			t.Fatalf("It should write %s without compression, err: %v", name, err)
+		}
+	}
+}
+
+func TestRootCmd_UnsupportedKeyCollision(t *testing.T) {
+	for _, name := range []string{"flatten", "csv", "schema"} {
+		// Prepare
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs([]string{name, "-d", `{"id": 1}`, "--key-collision", "merge"})
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		expMsg := `unsupported key collision policy "merge"`
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestRootCmd_InvalidRootFlags(t *testing.T) {
+	// Prepare
+	cases := map[string][]string{
+		"cannot use '--out' with '--out-dir'":                            {"csv", "-i", "a.json", "-o", "a.csv", "--out-dir", "out"},
+		"invalid number of workers 0":                                    {"flatten", "-i", "a.json", "--workers", "0"},
+		`unsupported compression "lz4"`:                                  {"json", "-i", "a.csv", "--compress", "lz4"},
+		"bzip2 compression is supported for input only":                  {"csv", "-i", "a.json", "--compress", "bzip2"},
+		`unsupported output format "xls"`:                                {"csv", "-i", "a.json", "--format", "xls"},
+		"need to set '--out' or '--out-dir' for '--format xlsx'":         {"csv", "-i", "a.json", "--format", "xlsx"},
+		"cannot use '--stream' or '--normalize' with '--format xlsx'":    {"csv", "-i", "a.json", "-o", "a.xlsx", "--format", "xlsx", "--stream"},
+		"need to set '--out' or '--out-dir' for '--format parquet'":      {"csv", "-i", "a.json", "--format", "parquet"},
+		"cannot use '--stream' or '--normalize' with '--format parquet'": {"csv", "-i", "a.json", "-o", "a.parquet", "--format", "parquet", "--stream"},
+		`unsupported parquet compression "snappy"`:                       {"csv", "-i", "a.json", "-o", "a.parquet", "--format", "parquet", "--parquet-compression", "snappy"},
+		`unsupported output format "yaml"`:                               {"schema", "-i", "a.json", "--format", "yaml"},
+	}
+
+	for expMsg, args := range cases {
+		outBuf := &bytes.Buffer{}
+		rootCmd := NewRootCmd()
+		rootCmd.SetOut(outBuf)
+		rootCmd.SetErr(outBuf)
+		rootCmd.SetArgs(args)
+
+		// Process
+		err := rootCmd.Execute()
+
+		// Check
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
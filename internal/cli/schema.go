@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/tuan78/jsonconv/v2"
+	"github.com/tuan78/jsonconv/v2/internal/cli/logger"
+	"github.com/tuan78/jsonconv/v2/internal/cli/repository"
+)
+
+func NewSchemaCmd() *cobra.Command {
+	var (
+		lvl  int
+		gap  string
+		sm   bool
+		sa   bool
+		ofmt string
+		ind  string
+		ak   string
+		ob   bool
+		kc   string
+		rule string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Report the schema of flattened JSON",
+		Long:  "Report the observed types, null and present percentages, example values and maximum length of every flattened key of JSON objects",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			inFormat, err := jsonconv.ParseJsonFormat(rootFlags.InputFormat)
+			if err != nil {
+				return err
+			}
+			if ofmt != outputFormatTable && ofmt != outputFormatJson {
+				return fmt.Errorf("unsupported output format %q", ofmt)
+			}
+			akStyle, err := jsonconv.ParseArrayKeyStyle(ak)
+			if err != nil {
+				return err
+			}
+			collision, err := jsonconv.ParseKeyCollision(kc)
+			if err != nil {
+				return err
+			}
+			in := &schemaCmdInput{
+				inputPaths:   rootFlags.InputPaths,
+				outputPath:   rootFlags.OutputPath,
+				outDir:       rootFlags.OutputDir,
+				workers:      rootFlags.Workers,
+				raw:          rootFlags.RawData,
+				inputFormat:  inFormat,
+				useNumber:    rootFlags.UseNumber,
+				outputFormat: ofmt,
+				indent:       parseIndent(ind),
+				rulesPath:    rule,
+				flattenOpt: &jsonconv.FlattenOption{
+					Level:         lvl,
+					Gap:           gap,
+					SkipMap:       sm,
+					SkipArray:     sa,
+					ArrayKeyStyle: akStyle,
+					OneBasedIndex: ob,
+					KeyCollision:  collision,
+				},
+			}
+			logger := logger.NewLogger(cmd)
+			repo := repository.NewRepositoryWithCompression(rootFlags.compression)
+			return processSchemaCmd(logger, repo, in)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&ofmt, "format", outputFormatTable, "output format: table or json")
+	cmd.PersistentFlags().IntVar(&lvl, "lv", jsonconv.DefaultFlattenLevel, "level for flattening a nested JSON (-1: unlimited, 0: no nested, [1...n]: n level of nested JSON)")
+	cmd.PersistentFlags().StringVar(&gap, "ga", jsonconv.DefaultFlattenGap, "gap for separating JSON object with its nested data")
+	cmd.PersistentFlags().BoolVar(&sm, "sm", false, "set it true to skip map type")
+	cmd.PersistentFlags().BoolVar(&sa, "sa", false, "set it true to skip array type")
+	cmd.PersistentFlags().StringVar(&rule, "rules", "", "path to a JSON file of flatten rules for some paths, e.g. [{\"path\": \"metadata\", \"level\": 1}, {\"path\": \"tags\", \"skipArray\": true}]")
+	cmd.PersistentFlags().StringVar(&ak, "array-key", "bracket", "style of keys of array elements: bracket (a[0]) or gap (a__0, separated by '--ga')")
+	cmd.PersistentFlags().BoolVar(&ob, "one-based", false, "set it true to start indices of array elements from 1")
+	cmd.PersistentFlags().StringVar(&kc, "key-collision", "overwrite", "what to do when two values are flattened to the same key: overwrite, error, escape (escape the gap in original keys with '\\'), suffix (append #2, #3, ...) or keep-first")
+	cmd.PersistentFlags().StringVar(&ind, "indent", "", "indent for pretty-printing JSON when '--format json' is set, either a number of spaces or a literal string such as $'\\t'")
+	return cmd
+}
+
+type schemaCmdInput struct {
+	inputPaths   []string
+	outputPath   string
+	outDir       string
+	workers      int
+	raw          string
+	inputFormat  jsonconv.JsonFormat
+	useNumber    bool
+	outputFormat string
+	indent       string
+	rulesPath    string
+	flattenOpt   *jsonconv.FlattenOption
+}
+
+// Output format of the schema command, besides JSON.
+const outputFormatTable = "table"
+
+// schemaExampleLength is the maximum number of characters of an example value in a schema table.
+const schemaExampleLength = 24
+
+func processSchemaCmd(logger logger.Logger, repo repository.Repository, in *schemaCmdInput) error {
+	if in.rulesPath != "" {
+		rules, err := loadFlattenRules(repo, in.rulesPath)
+		if err != nil {
+			return err
+		}
+		fo := *in.flattenOpt
+		fo.Rules = rules
+		in.flattenOpt = &fo
+	}
+	inputPaths, err := expandInputPaths(repo, in.inputPaths)
+	if err != nil {
+		return err
+	}
+	in.inputPaths = inputPaths
+	if in.outDir != "" {
+		ext := ".txt"
+		if in.outputFormat == outputFormatJson {
+			ext = ".json"
+		}
+		sl := &syncLogger{logger: logger}
+		return eachInput(in.inputPaths, in.outDir, ext, in.workers, func(inputPath, outputPath string) error {
+			each := *in
+			each.inputPaths = []string{inputPath}
+			each.outputPath = outputPath
+			each.outDir = ""
+			each.rulesPath = ""
+			return processSchemaCmd(sl, repo, &each)
+		})
+	}
+
+	// Read JSON objects of all inputs and infer the schema of them together.
+	arrs, err := readInputs(repo, in.raw, in.inputPaths, in.workers, func(r io.Reader) ([]map[string]any, error) {
+		return readAllJsonObjects(in.jsonReader(r))
+	})
+	if err != nil {
+		return err
+	}
+	schema, err := jsonconv.InferSchema(slices.Concat(arrs...), in.flattenOpt)
+	if err != nil {
+		return err
+	}
+
+	// Output the content.
+	if in.outputFormat == outputFormatJson {
+		return outputJsonContent(logger, repo, schema, in.outputPath, in.indent, false)
+	}
+	return outputSchemaTable(logger, repo, schema, in.outputPath)
+}
+
+// jsonReader returns a JSON reader of r with the input options of in.
+func (in *schemaCmdInput) jsonReader(r io.Reader) *jsonconv.JsonReader {
+	jr := jsonconv.NewJsonReader(r)
+	jr.Format = in.inputFormat
+	jr.UseNumber = in.useNumber
+	return jr
+}
+
+// readAllJsonObjects reads JSON objects from jr, including empty ones.
+func readAllJsonObjects(jr *jsonconv.JsonReader) ([]map[string]any, error) {
+	var arr []map[string]any
+	for {
+		obj, err := jr.Next()
+		if err == io.EOF {
+			return arr, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON data, %v", err)
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func outputSchemaTable(logger logger.Logger, repo repository.Repository, schema []jsonconv.FieldSchema, filePath string) error {
+	buf := &bytes.Buffer{}
+	if err := writeSchemaTable(buf, schema); err != nil {
+		return err
+	}
+	if filePath == "" {
+		logger.Printf("%s", buf.String())
+		return nil
+	}
+
+	err := writeFile(repo, filePath, func(w io.Writer) error {
+		_, err := buf.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Printf("The schema file is located at %s\n", filePath)
+	return nil
+}
+
+// writeSchemaTable writes schema to w as a table with a row per flattened key.
+func writeSchemaTable(w io.Writer, schema []jsonconv.FieldSchema) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tTYPES\tNULL %\tPRESENT %\tMAX LENGTH\tEXAMPLES")
+	for _, f := range schema {
+		types := strings.Join(f.Types, "|")
+		if types == "" {
+			types = "null"
+		}
+		examples := make([]string, 0, len(f.Examples))
+		for _, e := range f.Examples {
+			examples = append(examples, formatSchemaExample(e))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%g\t%g\t%d\t%s\n", f.Key, types, f.NullPercent, f.PresentPercent, f.MaxLength, strings.Join(examples, ", "))
+	}
+	return tw.Flush()
+}
+
+// formatSchemaExample formats an example value for a schema table on a single line,
+// quoting strings and cutting long values to schemaExampleLength characters.
+func formatSchemaExample(val any) string {
+	s := jsonconv.FormatCsvValue(val, "")
+	if utf8.RuneCountInString(s) > schemaExampleLength {
+		s = string([]rune(s)[:schemaExampleLength-3]) + "..."
+	}
+	if _, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(s)
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tuan78/jsonconv/v2"
+)
+
+func TestProcessSchemaCmd_NoInputData(t *testing.T) {
+	// Prepare
+	in := &schemaCmdInput{}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processSchemaCmd(logger, repo, in)
+
+	// Check
+	expMsg := "need to input either raw data, input file path or data from stdin"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
+
+func TestProcessSchemaCmd_InvalidJson(t *testing.T) {
+	// Prepare
+	in := &schemaCmdInput{
+		raw:        `[{"id": 1}, 2]`,
+		flattenOpt: jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processSchemaCmd(logger, repo, in)
+
+	// Check
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid JSON data, ") {
+		t.Fatalf("It should throw an error with message: invalid JSON data\ncurrent: %v", err)
+	}
+}
+
+func TestProcessSchemaCmd_Table(t *testing.T) {
+	// Prepare
+	in := &schemaCmdInput{
+		raw:          "{\"id\": 1, \"user\": {\"name\": \"Jon\"}}\n{\"id\": \"x\", \"note\": \"line\\nbreak\"}\n{}",
+		useNumber:    true,
+		outputFormat: outputFormatTable,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+
+	// Process
+	err := processSchemaCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process schema cmd, err: %v", err)
+	}
+	expMsg := "" +
+		"KEY         TYPES           NULL %  PRESENT %  MAX LENGTH  EXAMPLES\n" +
+		"id          integer|string  0       66.67      1           1, \"x\"\n" +
+		"note        string          0       33.33      10          \"line\\nbreak\"\n" +
+		"user__name  string          0       33.33      3           \"Jon\"\n"
+	if logger.msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, logger.msg)
+	}
+}
+
+func TestProcessSchemaCmd_Json(t *testing.T) {
+	// Prepare
+	in := &schemaCmdInput{
+		inputPaths:   []string{"a.json", "b.json"},
+		outputPath:   "schema.json",
+		useNumber:    true,
+		outputFormat: outputFormatJson,
+		flattenOpt:   &jsonconv.FlattenOption{Level: jsonconv.FlattenLevelUnlimited, Gap: "."},
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json": `[{"id": 1, "user": {"name": "Jon"}}, {"id": 2, "user": null}]`,
+		"b.json": `{"id": 3.5, "user": {"name": "Tuấn"}}`,
+	}
+
+	// Process
+	err := processSchemaCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process schema cmd, err: %v", err)
+	}
+	var schema []map[string]any
+	if err := json.Unmarshal(repo.writerBuffers["schema.json"].Bytes(), &schema); err != nil {
+		t.Fatalf("schema.json should be JSON, err: %v", err)
+	}
+	var keys []string
+	for _, f := range schema {
+		keys = append(keys, f["key"].(string))
+	}
+	if strings.Join(keys, ",") != "id,user,user.name" {
+		t.Fatalf("schema should have keys id, user and user.name, current: %v", keys)
+	}
+	if f := schema[2]; f["presentPercent"] != 66.67 || f["maxLength"] != 4.0 || f["nullable"] != true {
+		t.Fatalf("user.name is reported incorrectly, current: %v", f)
+	}
+	expMsg := "The JSON file is located at schema.json\n"
+	if logger.msg != expMsg {
+		t.Fatalf("It should show message: %s\ncurrent: %s", expMsg, logger.msg)
+	}
+}
+
+func TestProcessSchemaCmd_OutDir(t *testing.T) {
+	// Prepare
+	in := &schemaCmdInput{
+		inputPaths:   []string{"a.json", "b.json"},
+		outDir:       "out",
+		workers:      2,
+		outputFormat: outputFormatTable,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileContents = map[string]string{
+		"a.json": `{"id": 1}`,
+		"b.json": `{"name": "Jon"}`,
+	}
+
+	// Process
+	err := processSchemaCmd(logger, repo, in)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to process schema cmd, err: %v", err)
+	}
+	expected := map[string]string{"a.txt": "id", "b.txt": "name"}
+	for name, key := range expected {
+		buf, exist := repo.writerBuffers[filepath.Join("out", name)]
+		if !exist || !strings.Contains(buf.String(), "\n"+key+" ") {
+			t.Fatalf("It should write schema file %s with key %s", filepath.Join("out", name), key)
+		}
+	}
+}
+
+func TestFormatSchemaExample(t *testing.T) {
+	// Prepare
+	cases := map[string]any{
+		`"Jon"`:                                "Jon",
+		`"a\tb"`:                               "a\tb",
+		"1.5":                                  json.Number("1.5"),
+		`{"a":1}`:                              map[string]any{"a": 1},
+		`"` + strings.Repeat("x", 21) + `..."`: strings.Repeat("x", 30),
+	}
+
+	for expected, val := range cases {
+		// Process
+		s := formatSchemaExample(val)
+
+		// Check
+		if s != expected {
+			t.Fatalf("%v should be formatted as %s, current: %s", val, expected, s)
+		}
+	}
+}
+
+func TestProcessSchemaCmd_FileClosingError(t *testing.T) {
+	// Prepare
+	in := &schemaCmdInput{
+		raw:          `{"id": 1}`,
+		outputPath:   "schema.txt",
+		outputFormat: outputFormatTable,
+		flattenOpt:   jsonconv.DefaultFlattenOption,
+	}
+	logger := NewMockLogger()
+	repo := NewMockRepository()
+	repo.fileClosingError = fmt.Errorf("failed to close file")
+
+	// Process
+	err := processSchemaCmd(logger, repo, in)
+
+	// Check
+	expMsg := "failed to close file"
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+	if logger.msg != "" {
+		t.Fatalf("It should not show message, current: %s", logger.msg)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"path/filepath"
+	"strings"
 )
 
 // IsFilePath checks whether or not v is file path.
@@ -13,3 +14,20 @@ func IsFilePath(v string) bool {
 	dir := filepath.Dir(v)
 	return dir != "."
 }
+
+// HasGlobMeta checks whether or not v contains any of the special
+// characters of filepath.Match patterns.
+func HasGlobMeta(v string) bool {
+	return strings.ContainsAny(v, `*?[`)
+}
+
+// CompressionExt returns the extension of v if it is the one of a compressed
+// file (.gz, .gzip, .zst, .zstd, .bz2 or .bzip2), in lower case. Otherwise, it returns "".
+func CompressionExt(v string) string {
+	ext := strings.ToLower(filepath.Ext(v))
+	switch ext {
+	case ".gz", ".gzip", ".zst", ".zstd", ".bz2", ".bzip2":
+		return ext
+	}
+	return ""
+}
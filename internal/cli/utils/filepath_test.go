@@ -33,3 +33,42 @@ func TestIsFilePath(t *testing.T) {
 		}
 	}
 }
+
+func TestHasGlobMeta(t *testing.T) {
+	// Test path should be a glob pattern.
+	paths := []string{"*.json", filepath.Join("dir", "2024-??-*.json"), "file[0-9].csv"}
+	for _, v := range paths {
+		if !HasGlobMeta(v) {
+			t.Fatalf("%s should be a glob pattern", v)
+		}
+	}
+
+	// Test path should not be a glob pattern.
+	paths = []string{"file.json", filepath.Join("dir", "file.json"), "dir"}
+	for _, v := range paths {
+		if HasGlobMeta(v) {
+			t.Fatalf("%s should not be a glob pattern", v)
+		}
+	}
+}
+
+func TestCompressionExt(t *testing.T) {
+	// Prepare
+	cases := map[string]string{
+		"data.json.gz":              ".gz",
+		filepath.Join("d", "a.ZST"): ".zst",
+		"a.csv.bz2":                 ".bz2",
+		"a.json":                    "",
+		"gz":                        "",
+	}
+
+	for v, expected := range cases {
+		// Process
+		ext := CompressionExt(v)
+
+		// Check
+		if ext != expected {
+			t.Fatalf("%s should have compression extension %q, current: %q", v, expected, ext)
+		}
+	}
+}
@@ -1,56 +1,372 @@
 package jsonconv
 
 import (
+	"bytes"
+	"container/list"
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 )
 
+// A HeaderOrder describes how ToCsv orders dynamic CSV headers.
+type HeaderOrder int
+
+const (
+	// HeaderOrderAlphabetical sorts headers alphabetically.
+	HeaderOrderAlphabetical HeaderOrder = iota
+
+	// HeaderOrderFirstSeen orders headers by their first appearance,
+	// reading JSON objects one after another.
+	HeaderOrderFirstSeen
+
+	// HeaderOrderSource merges key orders of all JSON objects, so that a key
+	// which first appears in a later JSON object is put next to the keys it
+	// follows there, rather than at the end.
+	HeaderOrderSource
+
+	// HeaderOrderNatural sorts headers alphabetically, except that array
+	// indices of flattened keys are compared as integers, so "items[2]"
+	// comes before "items[10]". Nested keys are grouped under their parent.
+	HeaderOrderNatural
+)
+
+// ParseHeaderOrder returns the HeaderOrder named by s,
+// which is one of "alphabetical", "first-seen", "source" and "natural".
+func ParseHeaderOrder(s string) (HeaderOrder, error) {
+	switch s {
+	case "alphabetical", "":
+		return HeaderOrderAlphabetical, nil
+	case "first-seen":
+		return HeaderOrderFirstSeen, nil
+	case "source":
+		return HeaderOrderSource, nil
+	case "natural":
+		return HeaderOrderNatural, nil
+	}
+	return HeaderOrderAlphabetical, fmt.Errorf("unsupported header order %q", s)
+}
+
 // A ToCsvOption converts a JSON Array to CSV data.
 type ToCsvOption struct {
 	// Set it to apply JSON flattening
 	FlattenOption *FlattenOption
 
+	// Flatten copies of JSON objects (see Flattened),
+	// so that the given JSON array is not modified
+	KeepInput bool
+
+	// Paths of JSON arrays to explode into one CSV row per array element,
+	// repeating the other fields on each row, e.g. "items" or "items__parts".
+	// Keys of a path are joined by the Gap of FlattenOption (or DefaultFlattenGap)
+	Explode []string
+
+	// How to combine exploded JSON arrays which are not nested in one another
+	ExplodeMode ExplodeMode
+
 	// Base CSV headers used to add before dynamic headers
 	BaseHeaders []string
+
+	// CSV headers to put right after BaseHeaders, in the given order.
+	// Each one is either a header or a glob pattern where '*' matches
+	// any sequence of characters, e.g. "items[*]__price" or "meta__*".
+	// Headers matched by the same pattern are ordered by HeaderOrder.
+	Columns []string
+
+	// CSV headers or glob patterns (see Columns) to leave out of CSV data.
+	// It does not apply to BaseHeaders
+	ExcludeColumns []string
+
+	// Order of dynamic CSV headers. Keys of a map[string]any have no order,
+	// so they are taken alphabetically within each JSON object; use
+	// ToCsvOrdered to keep the key order of the source JSON
+	HeaderOrder HeaderOrder
+
+	// Emit only BaseHeaders and Columns, instead of appending
+	// the rest of dynamic headers after them
+	StrictColumns bool
+
+	// Display names of CSV headers, keyed by header (e.g. flattened key).
+	// Renaming only changes the header text, not the values of the column
+	HeaderNames map[string]string
+
+	// Function to rename CSV headers which are not found in HeaderNames
+	RenameHeader func(header string) string
+
+	// Text of CSV cells for null JSON values. Empty by default
+	NullValue string
+
+	// Function to format JSON values as CSV cells.
+	// FormatCsvValue with NullValue is used if it is nil
+	FormatValue func(val any) string
 }
 
 // ToCsv converts a JSON array to [][]string with given opt.
+// JSON objects of arr are flattened in place unless opt.KeepInput is set.
+// It cannot report errors, so KeyCollisionError of FlattenOption
+// is handled like KeyCollisionOverwrite (see ToCsvStrict).
 func ToCsv(arr []map[string]any, opt *ToCsvOption) [][]string {
+	data, _ := ToCsvStrict(arr, lenientCsvOption(opt))
+	return data
+}
+
+// ToCsvStrict is like ToCsv, but it returns ErrFlattenCollision with KeyCollisionError.
+func ToCsvStrict(arr []map[string]any, opt *ToCsvOption) ([][]string, error) {
 	if len(arr) == 0 {
-		return [][]string{}
+		return [][]string{}, nil
+	}
+	hs, rows, err := csvRecords(arr, opt)
+	if err != nil {
+		return nil, err
+	}
+	return createCsvData(hs, rows, opt), nil
+}
+
+// ToCsvStructs is like ToCsvStrict, but arr is a slice of structs, pointers to structs
+// or maps, which are converted to JSON objects like encoding/json does (see
+// FlattenStruct), keeping the order of struct fields for HeaderOrderFirstSeen
+// and HeaderOrderSource. Nil elements are skipped, and other elements are an error.
+func ToCsvStructs[T any](arr []T, opt *ToCsvOption) ([][]string, error) {
+	if opt != nil && (opt.HeaderOrder == HeaderOrderFirstSeen || opt.HeaderOrder == HeaderOrderSource) {
+		objs, err := toOrderedJsonObjects(arr)
+		if err != nil {
+			return nil, err
+		}
+		return ToCsvOrdered(objs, opt)
+	}
+	objs, err := toJsonObjects(arr)
+	if err != nil {
+		return nil, err
+	}
+	return ToCsvStrict(objs, opt)
+}
+
+// ToCsvOrdered is like ToCsvStrict, but it reads the key order of every JSON object in arr,
+// so that HeaderOrderFirstSeen and HeaderOrderSource follow the source JSON.
+func ToCsvOrdered(arr []*OrderedObject, opt *ToCsvOption) ([][]string, error) {
+	if len(arr) == 0 {
+		return [][]string{}, nil
+	}
+	hs, rows, err := csvRecordsOrdered(arr, opt)
+	if err != nil {
+		return nil, err
+	}
+	return createCsvData(hs, rows, opt), nil
+}
+
+// lenientCsvOption returns opt for callers which cannot report errors (see lenientFlattenOption).
+func lenientCsvOption(opt *ToCsvOption) *ToCsvOption {
+	if opt == nil || lenientFlattenOption(opt.FlattenOption) == opt.FlattenOption {
+		return opt
+	}
+	o := *opt
+	o.FlattenOption = lenientFlattenOption(opt.FlattenOption)
+	return &o
+}
+
+// csvRecords explodes and flattens JSON objects of arr with opt (see ToCsv),
+// and returns the CSV headers with the resulting JSON objects.
+// An error is only returned with KeyCollisionError.
+func csvRecords(arr []map[string]any, opt *ToCsvOption) ([]string, []map[string]any, error) {
+	// Explode JSON arrays.
+	if opt != nil && len(opt.Explode) > 0 {
+		var rows []map[string]any
+		for _, obj := range arr {
+			for _, row := range explode(obj, opt.Explode, csvFlattenOption(opt).Gap, opt.ExplodeMode) {
+				rows = append(rows, row.(map[string]any))
+			}
+		}
+		arr = rows
 	}
 
 	// Flatten JSON.
 	if opt != nil && opt.FlattenOption != nil {
-		for _, obj := range arr {
-			Flatten(obj, opt.FlattenOption)
+		flat := arr
+		if opt.KeepInput {
+			flat = make([]map[string]any, len(arr))
+		}
+		for i, obj := range arr {
+			var err error
+			if opt.KeepInput {
+				flat[i], err = Flattened(obj, opt.FlattenOption)
+			} else {
+				err = FlattenStrict(obj, opt.FlattenOption)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
 		}
+		arr = flat
 	}
 
-	// Create CSV rows.
-	var csvData [][]string
-	var hs []string
-	if opt != nil && len(opt.BaseHeaders) > 0 {
-		hs = CreateCsvHeader(arr, opt.BaseHeaders)
-	} else {
-		hs = CreateCsvHeader(arr, nil)
+	return createCsvHeaderWithOption(arr, opt), arr, nil
+}
+
+// csvRecordsOrdered is like csvRecords, but it selects CSV headers with the key order
+// of JSON objects of arr (see ToCsvOrdered).
+func csvRecordsOrdered(arr []*OrderedObject, opt *ToCsvOption) ([]string, []map[string]any, error) {
+	// Explode JSON arrays.
+	if opt != nil && len(opt.Explode) > 0 {
+		var rows []*OrderedObject
+		for _, obj := range arr {
+			for _, row := range explode(obj, opt.Explode, csvFlattenOption(opt).Gap, opt.ExplodeMode) {
+				rows = append(rows, row.(*OrderedObject))
+			}
+		}
+		arr = rows
 	}
-	csvData = append(csvData, hs)
-	for _, obj := range arr {
-		row := make([]string, 0)
-		for _, h := range hs {
-			if val, exist := obj[h]; exist {
-				row = append(row, fmt.Sprintf("%v", val))
-				continue
+
+	// Flatten JSON.
+	if opt != nil && opt.FlattenOption != nil {
+		flat := arr
+		if opt.KeepInput {
+			flat = make([]*OrderedObject, len(arr))
+		}
+		for i, obj := range arr {
+			var err error
+			if opt.KeepInput {
+				flat[i], err = FlattenedOrdered(obj, opt.FlattenOption)
+			} else {
+				err = FlattenOrdered(obj, opt.FlattenOption)
+			}
+			if err != nil {
+				return nil, nil, err
 			}
-			row = append(row, "")
 		}
-		csvData = append(csvData, row)
+		arr = flat
 	}
 
+	keys := make([][]string, 0, len(arr))
+	rows := make([]map[string]any, 0, len(arr))
+	for _, obj := range arr {
+		keys = append(keys, obj.keys)
+		rows = append(rows, obj.values)
+	}
+	return selectCsvHeader(keys, opt), rows, nil
+}
+
+// createCsvData creates CSV data with the renamed headers hs and a CSV record of every row.
+func createCsvData(hs []string, rows []map[string]any, opt *ToCsvOption) [][]string {
+	csvData := make([][]string, 0, len(rows)+1)
+	csvData = append(csvData, renameCsvHeader(hs, opt))
+	for _, obj := range rows {
+		csvData = append(csvData, createCsvRow(obj, hs, opt))
+	}
 	return csvData
 }
 
+// csvFlattenOption returns the options of flattened keys with opt.
+func csvFlattenOption(opt *ToCsvOption) *FlattenOption {
+	if opt != nil && opt.FlattenOption != nil {
+		return opt.FlattenOption
+	}
+	return DefaultFlattenOption
+}
+
+// createCsvRow creates a CSV record from obj with values ordered by hs and formatted by opt.
+func createCsvRow(obj map[string]any, hs []string, opt *ToCsvOption) []string {
+	format := func(val any) string {
+		return FormatCsvValue(val, "")
+	}
+	if opt != nil && opt.FormatValue != nil {
+		format = opt.FormatValue
+	} else if opt != nil {
+		format = func(val any) string {
+			return FormatCsvValue(val, opt.NullValue)
+		}
+	}
+
+	row := make([]string, 0, len(hs))
+	for _, h := range hs {
+		if val, exist := obj[h]; exist {
+			row = append(row, format(val))
+			continue
+		}
+		row = append(row, "")
+	}
+	return row
+}
+
+// FormatCsvValue formats val as a CSV cell:
+//   - nil is formatted as nullValue
+//   - numbers are formatted as exact decimals, without exponent (json.Number too,
+//     unless its exponent is beyond ±1000)
+//   - values implementing encoding.TextMarshaler (e.g. time.Time) are formatted as their text
+//   - maps, slices, arrays and structs are JSON-encoded
+//   - other values are formatted as fmt.Sprint does
+func FormatCsvValue(val any, nullValue string) string {
+	switch v := val.(type) {
+	case nil:
+		return nullValue
+	case string:
+		return v
+	case json.Number:
+		return formatJsonNumber(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case encoding.TextMarshaler:
+		if refval := reflect.ValueOf(v); refval.Kind() == reflect.Pointer && refval.IsNil() {
+			return nullValue
+		}
+		if b, err := v.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+
+	refval := reflect.ValueOf(val)
+	switch refval.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if refval.IsNil() {
+			return nullValue
+		}
+		return FormatCsvValue(refval.Elem().Interface(), nullValue)
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		buf := &bytes.Buffer{}
+		encoder := json.NewEncoder(buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(val); err == nil {
+			return strings.TrimSuffix(buf.String(), "\n")
+		}
+	}
+	return fmt.Sprint(val)
+}
+
+// maxCsvNumberExponent is the largest exponent of a json.Number which FormatCsvValue
+// writes as a decimal, so that a number like 1e999999999 cannot take gigabytes.
+const maxCsvNumberExponent = 1000
+
+// formatJsonNumber formats n as an exact decimal. A number with an exponent is
+// expanded, e.g. 1.5E3 to 1500, unless the exponent exceeds maxCsvNumberExponent.
+func formatJsonNumber(n json.Number) string {
+	s := n.String()
+	mantissa, exponent, found := strings.Cut(strings.ToLower(s), "e")
+	if !found {
+		return s
+	}
+	exp, err := strconv.Atoi(exponent)
+	if err != nil || exp > maxCsvNumberExponent || exp < -maxCsvNumberExponent {
+		return s
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return s
+	}
+	_, frac, _ := strings.Cut(mantissa, ".")
+	d := r.FloatString(max(len(frac)-exp, 0))
+	if strings.Contains(d, ".") {
+		d = strings.TrimSuffix(strings.TrimRight(d, "0"), ".")
+	}
+	return d
+}
+
 // CreateCsvHeader creates []string from arr and baseHs.
 // A baseHs is base header that we want to put at the beginning of dynamic header,
 // we can set baseHs to nil if we just want to have dynamic header only.
@@ -80,3 +396,210 @@ func CreateCsvHeader(arr []map[string]any, baseHs []string) []string {
 	}
 	return hs
 }
+
+// createCsvHeaderWithOption creates []string from arr with the headers selection of opt.
+func createCsvHeaderWithOption(arr []map[string]any, opt *ToCsvOption) []string {
+	if opt == nil {
+		return CreateCsvHeader(arr, nil)
+	}
+	if len(opt.Columns) == 0 && len(opt.ExcludeColumns) == 0 && !opt.StrictColumns &&
+		opt.HeaderOrder == HeaderOrderAlphabetical {
+		return CreateCsvHeader(arr, opt.BaseHeaders)
+	}
+
+	// Take keys of each JSON object alphabetically, so that headers are deterministic.
+	keys := make([][]string, 0, len(arr))
+	for _, obj := range arr {
+		ks := make(sort.StringSlice, 0, len(obj))
+		for k := range obj {
+			ks = append(ks, k)
+		}
+		ks.Sort()
+		keys = append(keys, ks)
+	}
+	return selectCsvHeader(keys, opt)
+}
+
+// selectCsvHeader creates []string from keys of JSON objects with the headers selection of opt.
+func selectCsvHeader(keys [][]string, opt *ToCsvOption) []string {
+	if opt == nil {
+		opt = &ToCsvOption{}
+	}
+
+	// Get detected headers which are not excluded.
+	detected := orderCsvHeader(keys, opt)
+	excluded := func(h string) bool {
+		for _, p := range opt.ExcludeColumns {
+			if matchGlob(p, h) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Put base headers first, then selected columns, then the rest.
+	hs := make([]string, 0, len(opt.BaseHeaders)+len(detected))
+	used := make(map[string]struct{})
+	add := func(h string) {
+		if _, exist := used[h]; exist {
+			return
+		}
+		used[h] = struct{}{}
+		hs = append(hs, h)
+	}
+	for _, h := range opt.BaseHeaders {
+		add(h)
+	}
+	for _, c := range opt.Columns {
+		if !isGlob(c) {
+			if !excluded(c) {
+				add(c)
+			}
+			continue
+		}
+		for _, h := range detected {
+			if matchGlob(c, h) && !excluded(h) {
+				add(h)
+			}
+		}
+	}
+	if !opt.StrictColumns {
+		for _, h := range detected {
+			if !excluded(h) {
+				add(h)
+			}
+		}
+	}
+	return hs
+}
+
+// orderCsvHeader returns the distinct keys of JSON objects ordered by HeaderOrder of opt.
+func orderCsvHeader(keys [][]string, opt *ToCsvOption) []string {
+	if opt.HeaderOrder == HeaderOrderSource {
+		// Insert every new key right after the previous key of the same JSON object.
+		hl := list.New()
+		hss := make(map[string]*list.Element)
+		for _, ks := range keys {
+			var prev *list.Element
+			for _, k := range ks {
+				e, exist := hss[k]
+				if !exist {
+					if prev == nil {
+						e = hl.PushFront(k)
+					} else {
+						e = hl.InsertAfter(k, prev)
+					}
+					hss[k] = e
+				}
+				prev = e
+			}
+		}
+		hs := make([]string, 0, hl.Len())
+		for e := hl.Front(); e != nil; e = e.Next() {
+			hs = append(hs, e.Value.(string))
+		}
+		return hs
+	}
+
+	// Get distinct keys by their first appearance.
+	hs := make([]string, 0)
+	hss := make(map[string]struct{})
+	for _, ks := range keys {
+		for _, k := range ks {
+			if _, exist := hss[k]; !exist {
+				hss[k] = struct{}{}
+				hs = append(hs, k)
+			}
+		}
+	}
+
+	switch opt.HeaderOrder {
+	case HeaderOrderAlphabetical:
+		sort.Strings(hs)
+	case HeaderOrderNatural:
+		sortNatural(hs, csvFlattenOption(opt))
+	}
+	return hs
+}
+
+// sortNatural sorts flattened keys hs segment by segment (see parseFlattenedKey),
+// comparing array indices as integers, so that "a[2]" comes before "a[10]"
+// and nested keys stay next to their siblings.
+func sortNatural(hs []string, opt *FlattenOption) {
+	segs := make(map[string][]keySegment, len(hs))
+	for _, h := range hs {
+		segs[h] = parseFlattenedKey(h, opt)
+	}
+	sort.Slice(hs, func(i, j int) bool {
+		a, b := segs[hs[i]], segs[hs[j]]
+		for n := 0; n < len(a) && n < len(b); n++ {
+			sa, sb := a[n], b[n]
+			switch {
+			case sa.isIdx && sb.isIdx:
+				if sa.idx != sb.idx {
+					return sa.idx < sb.idx
+				}
+			case sa.isIdx != sb.isIdx:
+				// An array index comes before an object key.
+				return sa.isIdx
+			case sa.key != sb.key:
+				return sa.key < sb.key
+			}
+		}
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		// Same path written differently, e.g. "a[01]" and "a[1]".
+		return hs[i] < hs[j]
+	})
+}
+
+// isGlob reports whether pattern contains the '*' wildcard.
+func isGlob(pattern string) bool {
+	return strings.Contains(pattern, "*")
+}
+
+// matchGlob reports whether s matches pattern, where '*' matches any
+// sequence of characters and every other character matches itself.
+func matchGlob(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	// The first part is a prefix and the last part is a suffix,
+	// the ones in between must appear in order.
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
+
+// renameCsvHeader returns display names of hs with HeaderNames and RenameHeader of opt.
+func renameCsvHeader(hs []string, opt *ToCsvOption) []string {
+	if opt == nil || (len(opt.HeaderNames) == 0 && opt.RenameHeader == nil) {
+		return hs
+	}
+	names := make([]string, 0, len(hs))
+	for _, h := range hs {
+		if name, exist := opt.HeaderNames[h]; exist {
+			names = append(names, name)
+			continue
+		}
+		if opt.RenameHeader != nil {
+			names = append(names, opt.RenameHeader(h))
+			continue
+		}
+		names = append(names, h)
+	}
+	return names
+}
@@ -1,6 +1,10 @@
 package jsonconv
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -18,6 +22,19 @@ func TestToCsv_EmptyArray(t *testing.T) {
 	}
 }
 
+func TestToCsv_FuncValue(t *testing.T) {
+	// Prepare
+	var convert func([]map[string]any, *ToCsvOption) [][]string = ToCsv
+
+	// Process
+	csvData := convert(nil, nil)
+
+	// Check
+	if len(csvData) != 0 {
+		t.Fatalf("It should create no CSV data, current: %v", csvData)
+	}
+}
+
 func TestToCsv_NonFlatten(t *testing.T) {
 	// Prepare
 	data := []map[string]any{
@@ -61,7 +78,7 @@ func TestToCsv_NonFlatten(t *testing.T) {
 	r4 := strings.Join(csvData[3], ",")
 	exp1 := "id,is active,nested,score,special1,special2,special3,special4,special5,special6,user"
 	exp2 := "b042ab5c-ca73-4460-b739-96410ea9d3a6,false,,-100,&,<,>,&,<,>,Jon Doe"
-	exp3 := "ce06f5b1-5721-42c0-91e1-9f72a09c250a,true,map[a:1 b:2],1.5,,,,,,,Tuấn"
+	exp3 := `ce06f5b1-5721-42c0-91e1-9f72a09c250a,true,{"a":1,"b":2},1.5,,,,,,,Tuấn`
 	exp4 := "4e01b638-44e5-4079-8043-baabbff21cc8,true,,100000000000000000,,,,,,,高橋"
 	if r1 != exp1 {
 		t.Fatalf("created headers are incorrect, %s is not equal expected %s", r1, exp1)
@@ -122,7 +139,7 @@ func TestToCsv_WithBaseHeader(t *testing.T) {
 	r4 := strings.Join(csvData[3], ",")
 	exp1 := "x,y,z,3,2,1,id,is active,nested,score,special1,special2,special3,special4,special5,special6,user"
 	exp2 := ",,,,,,b042ab5c-ca73-4460-b739-96410ea9d3a6,false,,-100,&,<,>,&,<,>,Jon Doe"
-	exp3 := ",,,,,,ce06f5b1-5721-42c0-91e1-9f72a09c250a,true,map[a:1 b:2],1.5,,,,,,,Tuấn"
+	exp3 := `,,,,,,ce06f5b1-5721-42c0-91e1-9f72a09c250a,true,{"a":1,"b":2},1.5,,,,,,,Tuấn`
 	exp4 := ",,,,,,4e01b638-44e5-4079-8043-baabbff21cc8,true,,100000000000000000,,,,,,,高橋"
 	if r1 != exp1 {
 		t.Fatalf("created headers are incorrect, %s is not equal expected %s", r1, exp1)
@@ -248,3 +265,551 @@ func TestCreateCsvHeader(t *testing.T) {
 		t.Fatalf("created headers are incorrect")
 	}
 }
+
+func sampleOrders() []map[string]any {
+	return []map[string]any{
+		{
+			"id":   "b042ab5c-ca73-4460-b739-96410ea9d3a6",
+			"user": "Jon Doe",
+			"items": []any{
+				map[string]any{"sku": "A1", "price": 1.5},
+				map[string]any{"sku": "B2", "price": 2},
+			},
+			"meta": map[string]any{"source": "web", "ip": "127.0.0.1"},
+		},
+		{
+			"id":   "ce06f5b1-5721-42c0-91e1-9f72a09c250a",
+			"user": "Tuấn",
+			"items": []any{
+				map[string]any{"sku": "C3", "price": 3},
+			},
+			"note": "gift",
+		},
+	}
+}
+
+func TestToCsv_Columns(t *testing.T) {
+	// Prepare
+	data := sampleOrders()
+
+	// Process
+	csvData := ToCsv(data, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		BaseHeaders:   []string{"id"},
+		Columns:       []string{"items[*]__price", "total", "user"},
+	})
+
+	// Check
+	r1 := strings.Join(csvData[0], ",")
+	r2 := strings.Join(csvData[1], ",")
+	r3 := strings.Join(csvData[2], ",")
+	exp1 := "id,items[0]__price,items[1]__price,total,user,items[0]__sku,items[1]__sku,meta__ip,meta__source,note"
+	exp2 := "b042ab5c-ca73-4460-b739-96410ea9d3a6,1.5,2,,Jon Doe,A1,B2,127.0.0.1,web,"
+	exp3 := "ce06f5b1-5721-42c0-91e1-9f72a09c250a,3,,,Tuấn,C3,,,,gift"
+	if r1 != exp1 {
+		t.Fatalf("created headers are incorrect, %s is not equal expected %s", r1, exp1)
+	}
+	if r2 != exp2 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r2, exp2)
+	}
+	if r3 != exp3 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r3, exp3)
+	}
+}
+
+func TestToCsvStrict_KeyCollisionError(t *testing.T) {
+	// Prepare
+	opt := &ToCsvOption{
+		FlattenOption: &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionError},
+		Explode:       []string{"a"},
+		KeepInput:     true,
+	}
+	arr := []map[string]any{{"a": []any{map[string]any{"b": 1}}, "a__b": 2}}
+
+	// Process
+	_, err := ToCsvStrict(arr, opt)
+	data := ToCsv(arr, opt)
+
+	// Check
+	if !errors.Is(err, ErrFlattenCollision) {
+		t.Fatalf("It should throw ErrFlattenCollision, current: %v", err)
+	}
+	expected := [][]string{{"a__b"}, {"2"}}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("ToCsv should overwrite colliding keys, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestToCsv_StrictColumns(t *testing.T) {
+	// Prepare
+	data := sampleOrders()
+
+	// Process
+	csvData := ToCsv(data, &ToCsvOption{
+		FlattenOption:  DefaultFlattenOption,
+		Columns:        []string{"user", "meta__*", "items[*]__sku"},
+		ExcludeColumns: []string{"meta__ip", "items[1]*"},
+		StrictColumns:  true,
+	})
+
+	// Check
+	r1 := strings.Join(csvData[0], ",")
+	r2 := strings.Join(csvData[1], ",")
+	r3 := strings.Join(csvData[2], ",")
+	exp1 := "user,meta__source,items[0]__sku"
+	exp2 := "Jon Doe,web,A1"
+	exp3 := "Tuấn,,C3"
+	if r1 != exp1 {
+		t.Fatalf("created headers are incorrect, %s is not equal expected %s", r1, exp1)
+	}
+	if r2 != exp2 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r2, exp2)
+	}
+	if r3 != exp3 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r3, exp3)
+	}
+}
+
+func TestToCsv_ExcludeColumns(t *testing.T) {
+	// Prepare
+	data := sampleOrders()
+
+	// Process
+	csvData := ToCsv(data, &ToCsvOption{
+		FlattenOption:  DefaultFlattenOption,
+		BaseHeaders:    []string{"meta__ip"},
+		ExcludeColumns: []string{"items*", "meta__*"},
+	})
+
+	// Check
+	r1 := strings.Join(csvData[0], ",")
+	exp1 := "meta__ip,id,note,user"
+	if r1 != exp1 {
+		t.Fatalf("created headers are incorrect, %s is not equal expected %s", r1, exp1)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	// Prepare
+	cases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"id", "id", true},
+		{"id", "ids", false},
+		{"*", "", true},
+		{"*", "anything", true},
+		{"meta__*", "meta__a__b", true},
+		{"meta__*", "meta", false},
+		{"*__price", "items[0]__price", true},
+		{"items[*]__price", "items[10]__price", true},
+		{"items[*]__price", "items[0]__sku", false},
+		{"a*b*c", "abc", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "acb", false},
+		{"ab*ba", "aba", false},
+		{"a**", "a", true},
+	}
+
+	for _, c := range cases {
+		// Process
+		match := matchGlob(c.pattern, c.s)
+
+		// Check
+		if match != c.match {
+			t.Fatalf("matchGlob(%q, %q) should be %v", c.pattern, c.s, c.match)
+		}
+	}
+}
+
+func TestToCsv_HeaderNames(t *testing.T) {
+	// Prepare
+	data := sampleOrders()
+
+	// Process
+	csvData := ToCsv(data, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		Columns:       []string{"id", "user", "meta__source"},
+		StrictColumns: true,
+		HeaderNames: map[string]string{
+			"user":         "Customer",
+			"meta__source": "user",
+		},
+		RenameHeader: strings.ToUpper,
+	})
+
+	// Check
+	r1 := strings.Join(csvData[0], ",")
+	r2 := strings.Join(csvData[1], ",")
+	exp1 := "ID,Customer,user"
+	exp2 := "b042ab5c-ca73-4460-b739-96410ea9d3a6,Jon Doe,web"
+	if r1 != exp1 {
+		t.Fatalf("created headers are incorrect, %s is not equal expected %s", r1, exp1)
+	}
+	if r2 != exp2 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r2, exp2)
+	}
+}
+
+func TestToCsv_NullValue(t *testing.T) {
+	// Prepare
+	data := []map[string]any{
+		{"id": 1, "score": nil, "tags": []any{"a", nil}},
+		{"id": 2},
+	}
+
+	// Process
+	csvData := ToCsv(data, &ToCsvOption{
+		NullValue: "NULL",
+	})
+
+	// Check
+	r2 := strings.Join(csvData[1], "|")
+	r3 := strings.Join(csvData[2], "|")
+	exp2 := `1|NULL|["a",null]`
+	exp3 := `2||`
+	if r2 != exp2 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r2, exp2)
+	}
+	if r3 != exp3 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r3, exp3)
+	}
+}
+
+func TestToCsv_FormatValue(t *testing.T) {
+	// Prepare
+	data := []map[string]any{
+		{"id": 1, "score": 1.5, "nested": map[string]any{"a": 1}},
+	}
+
+	// Process
+	csvData := ToCsv(data, &ToCsvOption{
+		NullValue: "ignored",
+		FormatValue: func(val any) string {
+			return fmt.Sprintf("%v", val)
+		},
+	})
+
+	// Check
+	r2 := strings.Join(csvData[1], "|")
+	exp2 := `1|map[a:1]|1.5`
+	if r2 != exp2 {
+		t.Fatalf("created row is incorrect, %s is not equal expected %s", r2, exp2)
+	}
+}
+
+func TestFormatCsvValue(t *testing.T) {
+	// Prepare
+	type point struct {
+		X int `json:"x"`
+	}
+	var nilPtr *point
+	num := 42
+	cases := []struct {
+		val      any
+		expected string
+	}{
+		{nil, "<null>"},
+		{nilPtr, "<null>"},
+		{&num, "42"},
+		{"<a & b>", "<a & b>"},
+		{true, "true"},
+		{-100, "-100"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{1e21, "1000000000000000000000"},
+		{float64(100000000000000000), "100000000000000000"},
+		{1e-7, "0.0000001"},
+		{float32(0.1), "0.1"},
+		{json.Number("12345678901234567890"), "12345678901234567890"},
+		{json.Number("1.50"), "1.50"},
+		{json.Number("1e+21"), "1000000000000000000000"},
+		{json.Number("1.5E3"), "1500"},
+		{json.Number("-1.25e-3"), "-0.00125"},
+		{json.Number("12345678901234567890e-5"), "123456789012345.6789"},
+		{json.Number("1.50e1"), "15"},
+		{json.Number("0E-2"), "0"},
+		{json.Number("1e1001"), "1e1001"},
+		{map[string]any{"b": "<&>", "a": 1}, `{"a":1,"b":"<&>"}`},
+		{[]int{4, 5, 6}, "[4,5,6]"},
+		{[2]string{"x", "y"}, `["x","y"]`},
+		{point{X: 1}, `{"x":1}`},
+	}
+
+	for _, c := range cases {
+		// Process
+		s := FormatCsvValue(c.val, "<null>")
+
+		// Check
+		if s != c.expected {
+			t.Fatalf("formatted value is incorrect, %s is not equal expected %s", s, c.expected)
+		}
+	}
+
+	// JSON-unsupported values fall back to fmt.Sprint.
+	if s := FormatCsvValue(map[string]any{"fn": nil, "ch": make(chan int)}, ""); !strings.HasPrefix(s, "map[") {
+		t.Fatalf("formatted value should fall back to fmt.Sprint, current: %s", s)
+	}
+}
+
+func sampleOrderedObjects(t *testing.T) []*OrderedObject {
+	t.Helper()
+	raw := []string{
+		`{"id": 1, "user": "Jon Doe", "total": 3.5}`,
+		`{"id": 2, "user": "Tuấn", "meta": {"source": "web"}, "total": 3}`,
+		`{"note": "gift", "id": 3}`,
+	}
+	var arr []*OrderedObject
+	for _, r := range raw {
+		obj := &OrderedObject{}
+		if err := json.Unmarshal([]byte(r), obj); err != nil {
+			t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+		}
+		arr = append(arr, obj)
+	}
+	return arr
+}
+
+func TestToCsvOrdered_HeaderOrder(t *testing.T) {
+	// Prepare
+	cases := map[HeaderOrder]string{
+		HeaderOrderAlphabetical: "id,meta__source,note,total,user",
+		HeaderOrderFirstSeen:    "id,user,total,meta__source,note",
+		HeaderOrderSource:       "note,id,user,meta__source,total",
+	}
+
+	for order, expected := range cases {
+		// Process
+		csvData, err := ToCsvOrdered(sampleOrderedObjects(t), &ToCsvOption{
+			FlattenOption: DefaultFlattenOption,
+			HeaderOrder:   order,
+		})
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to convert JSON to CSV, err: %v", err)
+		}
+		if hs := strings.Join(csvData[0], ","); hs != expected {
+			t.Fatalf("created headers are incorrect for order %v, %s is not equal expected %s", order, hs, expected)
+		}
+		if r2 := strings.Join(csvData[2], "|"); !strings.Contains(r2, "web") {
+			t.Fatalf("created row is incorrect for order %v, current: %s", order, r2)
+		}
+	}
+}
+
+func TestToCsvOrdered_ColumnsAndBaseHeaders(t *testing.T) {
+	// Prepare
+	arr := sampleOrderedObjects(t)
+
+	// Process
+	csvData, err := ToCsvOrdered(arr, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		BaseHeaders:   []string{"id"},
+		Columns:       []string{"t*", "u*"},
+		HeaderOrder:   HeaderOrderFirstSeen,
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON to CSV, err: %v", err)
+	}
+	expected := "id,total,user,meta__source,note"
+	if hs := strings.Join(csvData[0], ","); hs != expected {
+		t.Fatalf("created headers are incorrect, %s is not equal expected %s", hs, expected)
+	}
+}
+
+func TestToCsv_HeaderOrder_Deterministic(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{
+		{"c": 1, "a": 2},
+		{"b": 3, "a": 4},
+	}
+	cases := map[HeaderOrder]string{
+		HeaderOrderFirstSeen: "a,c,b",
+		HeaderOrderSource:    "a,b,c",
+	}
+
+	for order, expected := range cases {
+		for i := 0; i < 10; i++ {
+			// Process
+			csvData := ToCsv(arr, &ToCsvOption{HeaderOrder: order})
+
+			// Check
+			if hs := strings.Join(csvData[0], ","); hs != expected {
+				t.Fatalf("created headers are incorrect for order %v, %s is not equal expected %s", order, hs, expected)
+			}
+		}
+	}
+}
+
+func TestToCsvOrdered_Empty(t *testing.T) {
+	// Process
+	csvData, err := ToCsvOrdered(nil, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON to CSV, err: %v", err)
+	}
+	if len(csvData) != 0 {
+		t.Fatalf("It should create no CSV data, current: %v", csvData)
+	}
+}
+
+func TestParseHeaderOrder(t *testing.T) {
+	// Prepare
+	cases := map[string]HeaderOrder{
+		"":             HeaderOrderAlphabetical,
+		"alphabetical": HeaderOrderAlphabetical,
+		"first-seen":   HeaderOrderFirstSeen,
+		"source":       HeaderOrderSource,
+		"natural":      HeaderOrderNatural,
+	}
+
+	for s, expected := range cases {
+		// Process
+		order, err := ParseHeaderOrder(s)
+
+		// Check
+		if err != nil || order != expected {
+			t.Fatalf("parsed header order is incorrect for %q, current: %v, err: %v", s, order, err)
+		}
+	}
+	if _, err := ParseHeaderOrder("random"); err == nil {
+		t.Fatalf("It should throw an error for unsupported header order")
+	}
+}
+
+func TestToCsv_HeaderOrderNatural(t *testing.T) {
+	// Prepare
+	items := make([]any, 0)
+	for i := 0; i < 11; i++ {
+		items = append(items, map[string]any{"sku": i, "price": i})
+	}
+	arr := []map[string]any{
+		{"items": items, "items_count": 11, "id": 1, "tags": []any{"a", "b"}},
+	}
+
+	// Process
+	csvData := ToCsv(arr, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		HeaderOrder:   HeaderOrderNatural,
+	})
+
+	// Check
+	expected := []string{"id"}
+	for i := 0; i < 11; i++ {
+		expected = append(expected, fmt.Sprintf("items[%d]__price", i), fmt.Sprintf("items[%d]__sku", i))
+	}
+	expected = append(expected, "items_count", "tags[0]", "tags[1]")
+	if !reflect.DeepEqual(csvData[0], expected) {
+		t.Fatalf("created headers are incorrect, %v is not equal expected %v", csvData[0], expected)
+	}
+}
+
+func TestSortNatural(t *testing.T) {
+	// Prepare
+	hs := []string{"a[10]", "a[2]__c", "a_b", "a[2]", "a[01]", "a[1]", "a b", "a[x]", "a__b[3]", "a__b[20]"}
+
+	// Process
+	sortNatural(hs, DefaultFlattenOption)
+
+	// Check
+	expected := []string{"a[01]", "a[1]", "a[2]", "a[2]__c", "a[10]", "a__b[3]", "a__b[20]", "a b", "a[x]", "a_b"}
+	if !reflect.DeepEqual(hs, expected) {
+		t.Fatalf("sorted headers are incorrect, %v is not equal expected %v", hs, expected)
+	}
+}
+
+func TestToCsv_Explode(t *testing.T) {
+	// Prepare
+	data := sampleOrders()
+
+	// Process
+	csvData := ToCsv(data, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		Explode:       []string{"items"},
+		Columns:       []string{"id", "items__sku", "items__price"},
+		StrictColumns: true,
+	})
+
+	// Check
+	var rows []string
+	for _, r := range csvData {
+		rows = append(rows, strings.Join(r, ","))
+	}
+	expected := []string{
+		"id,items__sku,items__price",
+		"b042ab5c-ca73-4460-b739-96410ea9d3a6,A1,1.5",
+		"b042ab5c-ca73-4460-b739-96410ea9d3a6,B2,2",
+		"ce06f5b1-5721-42c0-91e1-9f72a09c250a,C3,3",
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("created CSV data is incorrect, %v is not equal expected %v", rows, expected)
+	}
+}
+
+func TestToCsvOrdered_Explode(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+	if err := json.Unmarshal([]byte(`{"id": 1, "items": [{"sku": "A1"}, {"sku": "B2"}], "note": "x"}`), obj); err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+
+	// Process
+	csvData, err := ToCsvOrdered([]*OrderedObject{obj}, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		Explode:       []string{"items"},
+		HeaderOrder:   HeaderOrderFirstSeen,
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON to CSV, err: %v", err)
+	}
+	var rows []string
+	for _, r := range csvData {
+		rows = append(rows, strings.Join(r, ","))
+	}
+	expected := []string{"id,items__sku,note", "1,A1,x", "1,B2,x"}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("created CSV data is incorrect, %v is not equal expected %v", rows, expected)
+	}
+}
+
+func TestToCsv_KeepInput(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{sampleObject()}
+	ordered := &OrderedObject{}
+	if err := json.Unmarshal([]byte(`{"id": 1, "nested": {"a": [2]}}`), ordered); err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+	opt := &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		KeepInput:     true,
+	}
+
+	// Process
+	data := ToCsv(arr, opt)
+	orderedData, err := ToCsvOrdered([]*OrderedObject{ordered}, opt)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON to CSV, err: %v", err)
+	}
+	if !reflect.DeepEqual(arr[0], sampleObject()) {
+		t.Fatalf("It should leave JSON object unmodified, current: %v", arr[0])
+	}
+	expected := ToCsv([]map[string]any{sampleObject()}, &ToCsvOption{FlattenOption: DefaultFlattenOption})
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("CSV data is incorrect, %v is not equal expected value %v", data, expected)
+	}
+	if expected := []string{"id", "nested"}; !reflect.DeepEqual(ordered.Keys(), expected) {
+		t.Fatalf("It should leave ordered JSON object unmodified, current keys: %v", ordered.Keys())
+	}
+	if expected := [][]string{{"id", "nested__a[0]"}, {"1", "2"}}; !reflect.DeepEqual(orderedData, expected) {
+		t.Fatalf("CSV data is incorrect, %v is not equal expected value %v", orderedData, expected)
+	}
+}
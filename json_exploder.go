@@ -0,0 +1,219 @@
+package jsonconv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// An ExplodeMode describes how ToCsv combines JSON arrays exploded side by side,
+// i.e. arrays whose paths are not nested in one another (see ToCsvOption.Explode).
+type ExplodeMode int
+
+const (
+	// ExplodeCartesian creates one CSV row for every combination of array elements.
+	ExplodeCartesian ExplodeMode = iota
+
+	// ExplodeZip pairs array elements with the same index, creating as many
+	// CSV rows as the longest array has elements.
+	ExplodeZip
+)
+
+// ParseExplodeMode returns the ExplodeMode named by s,
+// which is one of "cartesian" and "zip".
+func ParseExplodeMode(s string) (ExplodeMode, error) {
+	switch s {
+	case "cartesian", "":
+		return ExplodeCartesian, nil
+	case "zip":
+		return ExplodeZip, nil
+	}
+	return ExplodeCartesian, fmt.Errorf("unsupported explode mode %q", s)
+}
+
+// explode unnests JSON arrays found at paths of obj, which is a map[string]any
+// or an *OrderedObject, returning one JSON object per array element. Every path
+// is made of keys joined by gap. A path nested in another one is exploded
+// within each element of the outer array. Arrays which are empty or missing
+// leave a single JSON object without the path. The obj is not modified.
+func explode(obj any, paths []string, gap string, mode ExplodeMode) []any {
+	rows := []any{obj}
+	for _, group := range groupExplodePaths(paths, gap, mode) {
+		var exploded []any
+		for _, row := range rows {
+			exploded = append(exploded, explodeGroup(row, group)...)
+		}
+		rows = exploded
+	}
+	return rows
+}
+
+// groupExplodePaths splits paths by gap and groups them so that outer paths come before
+// nested ones. With ExplodeZip, paths nested in the same outer path share a group.
+func groupExplodePaths(paths []string, gap string, mode ExplodeMode) [][][]string {
+	segs := make([][]string, 0, len(paths))
+	for _, p := range paths {
+		if gap == "" {
+			segs = append(segs, []string{p})
+			continue
+		}
+		segs = append(segs, strings.Split(p, gap))
+	}
+	sort.SliceStable(segs, func(i, j int) bool {
+		return len(segs[i]) < len(segs[j])
+	})
+	if mode != ExplodeZip {
+		groups := make([][][]string, 0, len(segs))
+		for _, s := range segs {
+			groups = append(groups, [][]string{s})
+		}
+		return groups
+	}
+
+	// Group paths by the longest other path they are nested in.
+	var groups [][][]string
+	index := make(map[int]int)
+	for i, s := range segs {
+		parent := -1
+		for j, o := range segs[:i] {
+			if len(o) < len(s) && isPathPrefix(o, s) && (parent < 0 || len(o) > len(segs[parent])) {
+				parent = j
+			}
+		}
+		n, exist := index[parent]
+		if !exist {
+			n = len(groups)
+			index[parent] = n
+			groups = append(groups, nil)
+		}
+		groups[n] = append(groups[n], s)
+	}
+	return groups
+}
+
+// isPathPrefix reports whether path p is a prefix of path s.
+func isPathPrefix(p, s []string) bool {
+	for i := range p {
+		if p[i] != s[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// explodeGroup creates one JSON object for every index of the arrays at paths of obj.
+func explodeGroup(obj any, paths [][]string) []any {
+	arrs := make([][]any, len(paths))
+	isArr := make([]bool, len(paths))
+	n := 0
+	for i, p := range paths {
+		val, _ := getPath(obj, p)
+		if arr, ok := val.([]any); ok {
+			arrs[i], isArr[i] = arr, true
+			n = max(n, len(arr))
+		}
+	}
+
+	// Keep a single JSON object without the arrays when they are all empty or missing.
+	if n == 0 {
+		for i, p := range paths {
+			if isArr[i] {
+				obj = setPath(obj, p, nil, true)
+			}
+		}
+		return []any{obj}
+	}
+
+	rows := make([]any, 0, n)
+	for idx := 0; idx < n; idx++ {
+		row := obj
+		for i, p := range paths {
+			if !isArr[i] {
+				continue
+			}
+			if idx < len(arrs[i]) {
+				row = setPath(row, p, arrs[i][idx], false)
+			} else {
+				row = setPath(row, p, nil, true)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// getPath returns the value at path p of obj.
+func getPath(obj any, p []string) (any, bool) {
+	val := obj
+	for _, k := range p {
+		var exist bool
+		if val, exist = getChild(val, k); !exist {
+			return nil, false
+		}
+	}
+	return val, true
+}
+
+// setPath returns a copy of obj with the value at path p set to val, or removed.
+// JSON objects along p are copied so that obj is not modified.
+// If p does not exist in obj, obj is returned as is.
+func setPath(obj any, p []string, val any, remove bool) any {
+	if len(p) == 1 {
+		return withChild(obj, p[0], val, remove)
+	}
+	child, exist := getChild(obj, p[0])
+	if !exist {
+		return obj
+	}
+	return withChild(obj, p[0], setPath(child, p[1:], val, remove), false)
+}
+
+// getChild returns the value of k in JSON object obj.
+func getChild(obj any, k string) (any, bool) {
+	switch o := obj.(type) {
+	case map[string]any:
+		val, exist := o[k]
+		return val, exist
+	case *OrderedObject:
+		if o == nil {
+			return nil, false
+		}
+		return o.Get(k)
+	}
+	return nil, false
+}
+
+// withChild returns a shallow copy of JSON object obj with k set to val, or removed.
+func withChild(obj any, k string, val any, remove bool) any {
+	switch o := obj.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(o))
+		for nk, nv := range o {
+			res[nk] = nv
+		}
+		if remove {
+			delete(res, k)
+		} else {
+			res[k] = val
+		}
+		return res
+	case *OrderedObject:
+		if o == nil {
+			return obj
+		}
+		res := &OrderedObject{
+			keys:   append([]string(nil), o.keys...),
+			values: make(map[string]any, len(o.values)),
+		}
+		for nk, nv := range o.values {
+			res.values[nk] = nv
+		}
+		if remove {
+			res.Delete(k)
+		} else {
+			res.Set(k, val)
+		}
+		return res
+	}
+	return obj
+}
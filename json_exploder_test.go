@@ -0,0 +1,160 @@
+package jsonconv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func sampleOrder(t *testing.T) map[string]any {
+	t.Helper()
+	raw := `
+	{
+		"id": 1,
+		"items": [
+			{"sku": "A1", "parts": [{"no": 1}, {"no": 2}]},
+			{"sku": "B2", "parts": []}
+		],
+		"tags": ["gift", "promo", "vip"]
+	}`
+	obj := make(map[string]any)
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		t.Fatalf("failed to decode sample order, err: %v", err)
+	}
+	return obj
+}
+
+func TestExplode_Cartesian(t *testing.T) {
+	// Prepare
+	obj := sampleOrder(t)
+
+	// Process
+	rows := explode(obj, []string{"tags", "items"}, DefaultFlattenGap, ExplodeCartesian)
+
+	// Check
+	if len(rows) != 6 {
+		t.Fatalf("It should create 6 rows, current: %v", rows)
+	}
+	var pairs [][2]any
+	for _, row := range rows {
+		r := row.(map[string]any)
+		pairs = append(pairs, [2]any{r["items"].(map[string]any)["sku"], r["tags"]})
+	}
+	expected := [][2]any{
+		{"A1", "gift"}, {"B2", "gift"},
+		{"A1", "promo"}, {"B2", "promo"},
+		{"A1", "vip"}, {"B2", "vip"},
+	}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Fatalf("exploded rows are incorrect, %v is not equal expected value %v", pairs, expected)
+	}
+	if !reflect.DeepEqual(obj, sampleOrder(t)) {
+		t.Fatalf("JSON object should not be modified, current: %v", obj)
+	}
+}
+
+func TestExplode_Zip(t *testing.T) {
+	// Prepare
+	obj := sampleOrder(t)
+
+	// Process
+	rows := explode(obj, []string{"items", "tags"}, DefaultFlattenGap, ExplodeZip)
+
+	// Check
+	if len(rows) != 3 {
+		t.Fatalf("It should create 3 rows, current: %v", rows)
+	}
+	last := rows[2].(map[string]any)
+	if _, exist := last["items"]; exist || last["tags"] != "vip" || last["id"] != 1.0 {
+		t.Fatalf("last exploded row is incorrect, current: %v", last)
+	}
+}
+
+func TestExplode_Nested(t *testing.T) {
+	// Prepare
+	obj := sampleOrder(t)
+
+	for _, mode := range []ExplodeMode{ExplodeCartesian, ExplodeZip} {
+		// Process
+		rows := explode(obj, []string{"items__parts", "items"}, DefaultFlattenGap, mode)
+
+		// Check
+		var got []string
+		for _, row := range rows {
+			b, _ := json.Marshal(row.(map[string]any)["items"])
+			got = append(got, string(b))
+		}
+		expected := []string{
+			`{"parts":{"no":1},"sku":"A1"}`,
+			`{"parts":{"no":2},"sku":"A1"}`,
+			`{"sku":"B2"}`,
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("exploded rows are incorrect for mode %v, %v is not equal expected value %v", mode, got, expected)
+		}
+	}
+}
+
+func TestExplode_MissingOrNonArray(t *testing.T) {
+	// Prepare
+	obj := map[string]any{"id": 1, "items": "none", "empty": []any{}}
+
+	// Process
+	rows := explode(obj, []string{"items", "empty", "missing", "id__x"}, DefaultFlattenGap, ExplodeCartesian)
+
+	// Check
+	expected := []any{map[string]any{"id": 1, "items": "none"}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("exploded rows are incorrect, %v is not equal expected value %v", rows, expected)
+	}
+}
+
+func TestExplode_OrderedObject(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+	if err := json.Unmarshal([]byte(`{"id": 1, "items": [{"sku": "A1"}, {"sku": "B2"}], "note": "x"}`), obj); err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+
+	// Process
+	rows := explode(obj, []string{"items"}, DefaultFlattenGap, ExplodeCartesian)
+
+	// Check
+	var got []string
+	for _, row := range rows {
+		b, _ := json.Marshal(row)
+		got = append(got, string(b))
+	}
+	expected := []string{
+		`{"id":1,"items":{"sku":"A1"},"note":"x"}`,
+		`{"id":1,"items":{"sku":"B2"},"note":"x"}`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("exploded rows are incorrect, %v is not equal expected value %v", got, expected)
+	}
+	if v, _ := obj.Get("items"); len(v.([]any)) != 2 {
+		t.Fatalf("JSON object should not be modified, current: %v", v)
+	}
+}
+
+func TestParseExplodeMode(t *testing.T) {
+	// Prepare
+	cases := map[string]ExplodeMode{
+		"":          ExplodeCartesian,
+		"cartesian": ExplodeCartesian,
+		"zip":       ExplodeZip,
+	}
+
+	for s, expected := range cases {
+		// Process
+		mode, err := ParseExplodeMode(s)
+
+		// Check
+		if err != nil || mode != expected {
+			t.Fatalf("parsed explode mode is incorrect for %q, current: %v, err: %v", s, mode, err)
+		}
+	}
+	if _, err := ParseExplodeMode("product"); err == nil {
+		t.Fatalf("It should throw an error for unsupported explode mode")
+	}
+}
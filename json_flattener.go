@@ -1,8 +1,13 @@
 package jsonconv
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -23,6 +28,74 @@ const (
 	DefaultFlattenGap = "__"
 )
 
+// An ArrayKeyStyle describes how keys of flattened array elements are written.
+type ArrayKeyStyle int
+
+const (
+	// ArrayKeyBracket writes the index in brackets after the parent key, e.g. "a[0]".
+	ArrayKeyBracket ArrayKeyStyle = iota
+
+	// ArrayKeyGap writes the index after the parent key and the gap, e.g. "a__0".
+	ArrayKeyGap
+)
+
+// ParseArrayKeyStyle returns the ArrayKeyStyle named by s,
+// which is one of "bracket" and "gap".
+func ParseArrayKeyStyle(s string) (ArrayKeyStyle, error) {
+	switch s {
+	case "bracket", "":
+		return ArrayKeyBracket, nil
+	case "gap":
+		return ArrayKeyGap, nil
+	}
+	return ArrayKeyBracket, fmt.Errorf("unsupported array key style %q", s)
+}
+
+// ErrFlattenCollision is returned by Flatten with KeyCollisionError
+// when two values are flattened to the same key.
+var ErrFlattenCollision = errors.New("colliding flattened keys")
+
+// A KeyCollision describes what Flatten does when two values are flattened
+// to the same key, e.g. "a__b" next to "a": {"b": ...} with the default gap.
+type KeyCollision int
+
+const (
+	// KeyCollisionOverwrite keeps the last value.
+	KeyCollisionOverwrite KeyCollision = iota
+
+	// KeyCollisionError returns ErrFlattenCollision.
+	KeyCollisionError
+
+	// KeyCollisionEscape escapes every key with EscapeFlattenKey before
+	// flattening, so that flattened keys do not collide. Unflatten with
+	// the same option unescapes them.
+	KeyCollisionEscape
+
+	// KeyCollisionSuffix appends "#2", "#3", ... to the key of a later value.
+	KeyCollisionSuffix
+
+	// KeyCollisionKeepFirst keeps the first value.
+	KeyCollisionKeepFirst
+)
+
+// ParseKeyCollision returns the KeyCollision named by s,
+// which is one of "overwrite", "error", "escape", "suffix" and "keep-first".
+func ParseKeyCollision(s string) (KeyCollision, error) {
+	switch s {
+	case "overwrite", "":
+		return KeyCollisionOverwrite, nil
+	case "error":
+		return KeyCollisionError, nil
+	case "escape":
+		return KeyCollisionEscape, nil
+	case "suffix":
+		return KeyCollisionSuffix, nil
+	case "keep-first":
+		return KeyCollisionKeepFirst, nil
+	}
+	return KeyCollisionOverwrite, fmt.Errorf("unsupported key collision policy %q", s)
+}
+
 // A FlattenOption is for JSON object flattening.
 type FlattenOption struct {
 	// Level of flattening, it can be FlattenLevelUnlimited,
@@ -39,6 +112,100 @@ type FlattenOption struct {
 	// Skip Array type (JSON array, string array, int array, float array, etc.)
 	// from flattening process
 	SkipArray bool
+
+	// Style of keys of array elements, ArrayKeyBracket by default
+	ArrayKeyStyle ArrayKeyStyle
+
+	// Start indices of array elements from 1 instead of 0
+	OneBasedIndex bool
+
+	// Function to create the key of an array element from its parent key
+	// and its (0-based) index. It overrides ArrayKeyStyle and OneBasedIndex,
+	// and keys created by it are not recognized as array elements by Unflatten
+	FormatArrayKey func(k string, i int) string
+
+	// What to do when two values are flattened to the same key.
+	// Keys are visited in order (alphabetically for a map[string]any),
+	// and a parent key before its nested keys
+	KeyCollision KeyCollision
+
+	// Rules to flatten JSON values at some paths differently. A JSON value
+	// is flattened by the first rule matching its flattened key, or by the
+	// rule of its closest parent, or by Level, SkipMap and SkipArray.
+	// A rule is not matched again against keys nested in the one it matched
+	Rules []FlattenRule
+}
+
+// A FlattenRule sets how to flatten the JSON values at matching paths.
+type FlattenRule struct {
+	// Glob pattern matched against flattened keys, where '*' matches any
+	// sequence of characters, e.g. "address", "items[*]" or "meta*"
+	Path string
+
+	// Level of flattening below the matched key, it can be FlattenLevelUnlimited,
+	// FlattenLevelNonNested (to keep the JSON value as is) or an int value in [1..n]
+	Level int
+
+	// Skip Map type from flattening below the matched key
+	SkipMap bool
+
+	// Skip Array type from flattening below the matched key
+	SkipArray bool
+}
+
+// A flattenScope holds how to flatten a JSON value and its nested values.
+type flattenScope struct {
+	level     int // maximum level to flatten, or FlattenLevelUnlimited
+	skipMap   bool
+	skipArray bool
+	rule      int // index of the rule applied to a parent key, or -1
+}
+
+// rootScope returns the flattenScope of the JSON object given to Flatten.
+func (opt *FlattenOption) rootScope() flattenScope {
+	return flattenScope{level: opt.Level, skipMap: opt.SkipMap, skipArray: opt.SkipArray, rule: -1}
+}
+
+// scope returns the flattenScope of the JSON value at k and curLvl from the
+// first rule matching k, or sc if there is none. The rule of sc, which was
+// applied to a parent key, is not applied again.
+func (opt *FlattenOption) scope(k string, curLvl int, sc flattenScope) flattenScope {
+	for i, r := range opt.Rules {
+		if i == sc.rule || !matchGlob(r.Path, k) {
+			continue
+		}
+		lvl := r.Level
+		if lvl != FlattenLevelUnlimited {
+			lvl += curLvl
+		}
+		return flattenScope{level: lvl, skipMap: r.SkipMap, skipArray: r.SkipArray, rule: i}
+	}
+	return sc
+}
+
+// objectKey returns the key of nk nested in k, or nk itself if k is empty.
+func (opt *FlattenOption) objectKey(k string, nk string) string {
+	if opt.KeyCollision == KeyCollisionEscape {
+		nk = EscapeFlattenKey(nk, opt)
+	}
+	if k == "" {
+		return nk
+	}
+	return k + opt.Gap + nk
+}
+
+// arrayKey returns the key of the i-th element of the array at k.
+func (opt *FlattenOption) arrayKey(k string, i int) string {
+	if opt.FormatArrayKey != nil {
+		return opt.FormatArrayKey(k, i)
+	}
+	if opt.OneBasedIndex {
+		i++
+	}
+	if opt.ArrayKeyStyle == ArrayKeyGap {
+		return fmt.Sprintf("%s%s%v", k, opt.Gap, i)
+	}
+	return fmt.Sprintf("%s[%v]", k, i)
 }
 
 // DefaultFlattenOption provides default settings for flattening operations.
@@ -49,61 +216,345 @@ var DefaultFlattenOption = &FlattenOption{
 
 // Flatten flattens obj with given opt. If opt is nil,
 // it will use opt value from DefaultFlattenOption instead.
+// It cannot report errors, so KeyCollisionError is handled
+// like KeyCollisionOverwrite (see FlattenStrict).
 func Flatten(obj map[string]any, opt *FlattenOption) {
+	_ = FlattenStrict(obj, lenientFlattenOption(opt))
+}
+
+// FlattenStrict is like Flatten, but it returns ErrFlattenCollision
+// with KeyCollisionError, in which case obj is left untouched.
+func FlattenStrict(obj map[string]any, opt *FlattenOption) error {
+	if opt == nil {
+		opt = DefaultFlattenOption
+	}
+
+	// Move nested values out of obj and write their flattened keys into it.
+	// Scalar values stay in obj as they are.
+	var ks []string
+	for k, v := range obj {
+		if !isFlatScalar(v) || opt.objectKey("", k) != k {
+			ks = append(ks, k)
+		}
+	}
+	if len(ks) == 0 {
+		return nil
+	}
+	vals := make([]any, len(ks))
+	for i, k := range ks {
+		vals[i] = obj[k]
+		delete(obj, k)
+	}
+	target := &inPlaceFlatMap{obj: obj}
+	for i, k := range ks {
+		if err := extract(opt.objectKey("", k), vals[i], target, opt, opt.rootScope(), 0); err != nil || target.collided {
+			break
+		}
+	}
+	if !target.collided {
+		return nil
+	}
+
+	// How a collision is resolved depends on the order of keys, so obj is
+	// restored and flattened as a whole. Extracting the values again yields
+	// the written keys in the same order, so that they are deleted.
+	target.undo = true
+	for i, k := range ks {
+		if target.added == 0 {
+			break
+		}
+		_ = extract(opt.objectKey("", k), vals[i], target, opt, opt.rootScope(), 0)
+	}
+	for i, k := range ks {
+		obj[k] = vals[i]
+	}
+	res, err := Flattened(obj, opt)
+	if err != nil {
+		return err
+	}
+	clear(obj)
+	maps.Copy(obj, res)
+	return nil
+}
+
+// isFlatScalar reports whether val is stored by Flatten as it is: nil, a string,
+// a bool or a number.
+func isFlatScalar(val any) bool {
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Invalid, reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Flattened is like FlattenStrict, but it returns a new flattened JSON object,
+// leaving obj unmodified. Values which are not flattened (e.g. because of
+// Level or SkipArray) are shared with obj rather than copied.
+func Flattened(obj map[string]any, opt *FlattenOption) (map[string]any, error) {
 	if opt == nil {
 		opt = DefaultFlattenOption
 	}
 
-	kset := make(map[string]struct{})
-	ks := make([]string, 0)
+	ks := make([]string, 0, len(obj))
 	for k := range obj {
 		ks = append(ks, k)
 	}
+	sort.Strings(ks)
+	res := make(flatMap, len(obj))
 	for _, k := range ks {
-		curLvl := 0
-		val := reflect.ValueOf(obj[k])
-		extract(k, &val, obj, kset, opt, curLvl)
+		if err := extract(opt.objectKey("", k), obj[k], res, opt, opt.rootScope(), 0); err != nil {
+			return nil, err
+		}
 	}
-	for k := range kset {
-		delete(obj, k)
+	return res, nil
+}
+
+// FlattenOrdered flattens obj like FlattenStrict does, keeping keys in order:
+// flattened keys of a nested JSON take the position of its parent key.
+// Keys of nested map values, which have no order, are sorted.
+func FlattenOrdered(obj *OrderedObject, opt *FlattenOption) error {
+	res, err := FlattenedOrdered(obj, opt)
+	if err != nil {
+		return err
 	}
+	*obj = *res
+	return nil
 }
 
-// extract processes obj extraction with k, refval pairs and given opt.
-// When extracting map, slice and array, a new key will be stored in kset and curLvl will be increased.
-func extract(k string, refval *reflect.Value, obj map[string]any, kset map[string]struct{}, opt *FlattenOption, curLvl int) {
-	more := opt.Level == FlattenLevelUnlimited || opt.Level > curLvl
-	for refval.Kind() == reflect.Interface {
-		*refval = refval.Elem()
+// FlattenedOrdered is like FlattenOrdered, but it returns a new flattened
+// JSON object, leaving obj unmodified (see Flattened).
+func FlattenedOrdered(obj *OrderedObject, opt *FlattenOption) (*OrderedObject, error) {
+	if opt == nil {
+		opt = DefaultFlattenOption
 	}
+
+	res := NewOrderedObject()
+	for _, k := range obj.keys {
+		if err := extract(opt.objectKey("", k), obj.values[k], res, opt, opt.rootScope(), 0); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// A flatTarget stores flattened key, value pairs.
+type flatTarget interface {
+	Get(k string) (any, bool)
+	Set(k string, val any)
+}
+
+// A flatMap is a flatTarget storing key, value pairs in a map.
+type flatMap map[string]any
+
+func (m flatMap) Get(k string) (any, bool) {
+	val, exist := m[k]
+	return val, exist
+}
+
+func (m flatMap) Set(k string, val any) {
+	m[k] = val
+}
+
+// An inPlaceFlatMap is a flatTarget writing into obj until a key collides with
+// an existing one. It counts the keys it has written in added, which are deleted
+// instead of written once undo is set.
+type inPlaceFlatMap struct {
+	obj      map[string]any
+	added    int
+	collided bool
+	undo     bool
+}
+
+func (m *inPlaceFlatMap) Get(k string) (any, bool) {
+	if m.undo {
+		return nil, false
+	}
+	val, exist := m.obj[k]
+	if exist {
+		m.collided = true
+	}
+	return val, exist
+}
+
+func (m *inPlaceFlatMap) Set(k string, val any) {
+	switch {
+	case m.undo && m.added > 0:
+		delete(m.obj, k)
+		m.added--
+	case !m.undo && !m.collided:
+		m.obj[k] = val
+		m.added++
+	}
+}
+
+// extract stores val in obj with key k, extracting maps, slices and arrays
+// into nested keys with given opt until the level of sc is reached at curLvl.
+func extract(k string, val any, obj flatTarget, opt *FlattenOption, sc flattenScope, curLvl int) error {
+	if len(opt.Rules) > 0 {
+		sc = opt.scope(k, curLvl, sc)
+	}
+	return extractValue(k, val, obj, opt, sc, curLvl)
+}
+
+// extractValue is extract once the flattenScope sc of k is known.
+func extractValue(k string, val any, obj flatTarget, opt *FlattenOption, sc flattenScope, curLvl int) error {
+	more := sc.level == FlattenLevelUnlimited || sc.level > curLvl
+	if o, ok := val.(*OrderedObject); ok && o != nil {
+		if !more || sc.skipMap {
+			return store(k, val, obj, opt)
+		}
+		for _, nk := range o.keys {
+			if err := extract(opt.objectKey(k, nk), o.values[nk], obj, opt, sc, curLvl+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	refval := reflect.ValueOf(val)
 	switch refval.Kind() {
+	case reflect.Pointer, reflect.Struct:
+		// Structs are flattened like the JSON objects they are encoded to.
+		// Other pointers and structs without JSON fields are kept as is.
+		st := refval.Type()
+		if st.Kind() == reflect.Pointer {
+			st = st.Elem()
+		}
+		if st.Kind() != reflect.Struct || isMarshaler(refval.Type()) || isMarshaler(st) ||
+			len(cachedStructFields(st)) == 0 || !more || sc.skipMap {
+			return store(k, val, obj, opt)
+		}
+		if refval.Kind() == reflect.Pointer && refval.IsNil() {
+			return store(k, nil, obj, opt)
+		}
+		_, ordered := obj.(*OrderedObject)
+		return extractValue(k, jsonValue(refval, ordered), obj, opt, sc, curLvl)
 	case reflect.Map:
-		if !more || opt.SkipMap {
-			obj[k] = refval.Interface()
-			return
+		if !more || sc.skipMap {
+			return store(k, val, obj, opt)
 		}
-		kset[k] = struct{}{}
 		ks := refval.MapKeys()
+		sort.Slice(ks, func(i, j int) bool {
+			return ks[i].String() < ks[j].String()
+		})
 		for _, nk := range ks {
-			newK := fmt.Sprintf("%s%s%s", k, opt.Gap, nk.String())
-			nv := refval.MapIndex(nk)
-			extract(newK, &nv, obj, kset, opt, curLvl+1)
+			if err := extract(opt.objectKey(k, nk.String()), refval.MapIndex(nk).Interface(), obj, opt, sc, curLvl+1); err != nil {
+				return err
+			}
 		}
 	case reflect.Slice, reflect.Array:
-		if !more || opt.SkipArray {
-			obj[k] = refval.Interface()
-			return
+		if !more || sc.skipArray {
+			return store(k, val, obj, opt)
 		}
-		kset[k] = struct{}{}
-		length := refval.Len()
-		for i := 0; i < length; i++ {
-			nv := refval.Index(i)
-			newK := fmt.Sprintf("%s[%v]", k, i)
-			extract(newK, &nv, obj, kset, opt, curLvl+1)
+		for i := 0; i < refval.Len(); i++ {
+			if err := extract(opt.arrayKey(k, i), refval.Index(i).Interface(), obj, opt, sc, curLvl+1); err != nil {
+				return err
+			}
 		}
 	case reflect.Invalid:
-		obj[k] = nil
+		return store(k, nil, obj, opt)
 	default:
-		obj[k] = refval.Interface()
+		return store(k, val, obj, opt)
+	}
+	return nil
+}
+
+// store sets k to val in obj, resolving a collision with an existing key by the KeyCollision of opt.
+func store(k string, val any, obj flatTarget, opt *FlattenOption) error {
+	if _, exist := obj.Get(k); exist {
+		switch opt.KeyCollision {
+		case KeyCollisionError:
+			return fmt.Errorf("%w: %q", ErrFlattenCollision, k)
+		case KeyCollisionKeepFirst:
+			return nil
+		case KeyCollisionSuffix:
+			for n := 2; ; n++ {
+				nk := fmt.Sprintf("%s#%d", k, n)
+				if _, exist := obj.Get(nk); !exist {
+					k = nk
+					break
+				}
+			}
+		}
+	}
+	obj.Set(k, val)
+	return nil
+}
+
+// lenientFlattenOption returns opt for callers which cannot report errors,
+// with KeyCollisionError replaced by KeyCollisionOverwrite.
+func lenientFlattenOption(opt *FlattenOption) *FlattenOption {
+	if opt == nil || opt.KeyCollision != KeyCollisionError {
+		return opt
+	}
+	o := *opt
+	o.KeyCollision = KeyCollisionOverwrite
+	return &o
+}
+
+// flattenEscape is the escape character of EscapeFlattenKey.
+const flattenEscape = '\\'
+
+// EscapeFlattenKey escapes k, a key of a JSON object, so that it can be told
+// apart from flattened keys: characters of the gap of opt, '\' and, with
+// ArrayKeyBracket, '[' are prefixed with '\'. With ArrayKeyGap, a key made of
+// digits is prefixed with '\' too. If opt is nil, DefaultFlattenOption is used.
+// A flattened key is split into escaped keys at every gap which is not escaped.
+func EscapeFlattenKey(k string, opt *FlattenOption) string {
+	if opt == nil {
+		opt = DefaultFlattenOption
+	}
+	if _, ok := parseIndex(k); ok && opt.ArrayKeyStyle == ArrayKeyGap {
+		return string(flattenEscape) + k
+	}
+
+	var b strings.Builder
+	for _, r := range k {
+		if r == flattenEscape || strings.ContainsRune(opt.Gap, r) || (r == '[' && opt.ArrayKeyStyle == ArrayKeyBracket) {
+			b.WriteRune(flattenEscape)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// UnescapeFlattenKey returns the key escaped by EscapeFlattenKey as k.
+func UnescapeFlattenKey(k string) string {
+	if !strings.ContainsRune(k, flattenEscape) {
+		return k
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range k {
+		if r == flattenEscape && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// splitEscapedKey splits k by gap, except where gap is escaped by EscapeFlattenKey.
+func splitEscapedKey(k string, gap string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(k); {
+		switch {
+		case k[i] == flattenEscape && i+1 < len(k):
+			_, size := utf8.DecodeRuneInString(k[i+1:])
+			i += 1 + size
+		case gap != "" && strings.HasPrefix(k[i:], gap):
+			parts = append(parts, k[start:i])
+			i += len(gap)
+			start = i
+		default:
+			i++
+		}
 	}
+	return append(parts, k[start:])
 }
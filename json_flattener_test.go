@@ -1,6 +1,10 @@
 package jsonconv
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -523,3 +527,434 @@ func TestFlattenJsonObject_Special(t *testing.T) {
 		}
 	}
 }
+
+func TestFlattenOrderedJsonObject(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+	err := json.Unmarshal([]byte(`{"z": 1, "a": {"y": 2, "b": [3, {"d": 4, "c": 5}]}, "m": {}, "n": null}`), obj)
+	if err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+	obj.Set("x", map[string]any{"q": 6, "p": 7})
+
+	// Process
+	FlattenOrdered(obj, nil)
+
+	// Check
+	expKeys := []string{"z", "a__y", "a__b[0]", "a__b[1]__d", "a__b[1]__c", "n", "x__p", "x__q"}
+	if !reflect.DeepEqual(obj.Keys(), expKeys) {
+		t.Fatalf("flattened keys are incorrect, %v is not equal expected value %v", obj.Keys(), expKeys)
+	}
+	if val, _ := obj.Get("a__b[1]__c"); val != 5.0 {
+		t.Fatalf("flattened value is incorrect, current: %v", val)
+	}
+}
+
+func TestFlattenOrderedJsonObject_FirstLevel(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+	err := json.Unmarshal([]byte(`{"z": 1, "a": {"y": {"c": 2}, "b": [3]}}`), obj)
+	if err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+
+	// Process
+	FlattenOrdered(obj, &FlattenOption{Level: 1, Gap: "."})
+
+	// Check
+	encoded, _ := json.Marshal(obj)
+	expected := `{"z":1,"a.y":{"c":2},"a.b":[3]}`
+	if string(encoded) != expected {
+		t.Fatalf("flattened JSON object is incorrect, %s is not equal expected value %s", encoded, expected)
+	}
+}
+
+func TestFlattenJsonObject_ArrayKey(t *testing.T) {
+	// Prepare
+	cases := []struct {
+		opt      *FlattenOption
+		expected []string
+	}{
+		{
+			opt:      &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__"},
+			expected: []string{"id", "f[0]", "f[1][0]", "f[2]__x"},
+		},
+		{
+			opt:      &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", ArrayKeyStyle: ArrayKeyGap},
+			expected: []string{"id", "f__0", "f__1__0", "f__2__x"},
+		},
+		{
+			opt:      &FlattenOption{Level: FlattenLevelUnlimited, Gap: "_", ArrayKeyStyle: ArrayKeyGap, OneBasedIndex: true},
+			expected: []string{"id", "f_1", "f_2_1", "f_3_x"},
+		},
+		{
+			opt:      &FlattenOption{Level: FlattenLevelUnlimited, Gap: ".", OneBasedIndex: true},
+			expected: []string{"id", "f[1]", "f[2][1]", "f[3].x"},
+		},
+		{
+			opt: &FlattenOption{
+				Level: FlattenLevelUnlimited,
+				Gap:   ".",
+				FormatArrayKey: func(k string, i int) string {
+					return fmt.Sprintf("%s_item%d", k, i)
+				},
+			},
+			expected: []string{"id", "f_item0", "f_item1_item0", "f_item2.x"},
+		},
+	}
+
+	for _, c := range cases {
+		data := map[string]any{
+			"id": 1,
+			"f":  []any{4, []any{5}, map[string]any{"x": "y"}},
+		}
+
+		// Process
+		Flatten(data, c.opt)
+
+		// Check
+		if len(data) != len(c.expected) {
+			t.Fatalf("flattened keys are incorrect, %v is not equal expected value %v", data, c.expected)
+		}
+		for _, k := range c.expected {
+			if _, exist := data[k]; !exist {
+				t.Fatalf("flattened keys are incorrect, %v does not have key %s", data, k)
+			}
+		}
+	}
+}
+
+func TestFlattenOrderedJsonObject_ArrayKey(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+	if err := json.Unmarshal([]byte(`{"f": [4, {"x": "y"}], "id": 1}`), obj); err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+
+	// Process
+	FlattenOrdered(obj, &FlattenOption{Level: FlattenLevelUnlimited, Gap: "_", ArrayKeyStyle: ArrayKeyGap, OneBasedIndex: true})
+
+	// Check
+	expected := []string{"f_1", "f_2_x", "id"}
+	if !reflect.DeepEqual(obj.Keys(), expected) {
+		t.Fatalf("flattened keys are incorrect, %v is not equal expected value %v", obj.Keys(), expected)
+	}
+}
+
+func TestParseArrayKeyStyle(t *testing.T) {
+	// Prepare
+	cases := map[string]ArrayKeyStyle{
+		"":        ArrayKeyBracket,
+		"bracket": ArrayKeyBracket,
+		"gap":     ArrayKeyGap,
+	}
+
+	for s, expected := range cases {
+		// Process
+		style, err := ParseArrayKeyStyle(s)
+
+		// Check
+		if err != nil || style != expected {
+			t.Fatalf("parsed array key style is incorrect for %q, current: %v, err: %v", s, style, err)
+		}
+	}
+	if _, err := ParseArrayKeyStyle("dot"); err == nil {
+		t.Fatalf("It should throw an error for unsupported array key style")
+	}
+}
+
+func TestFlattenJsonObject_KeyCollision(t *testing.T) {
+	// Prepare
+	cases := []struct {
+		collision KeyCollision
+		expected  map[string]any
+	}{
+		{KeyCollisionOverwrite, map[string]any{"a__b": 1, "id": 3}},
+		{KeyCollisionKeepFirst, map[string]any{"a__b": 2, "id": 3}},
+		{KeyCollisionSuffix, map[string]any{"a__b": 2, "a__b#2": 1, "id": 3}},
+		{KeyCollisionEscape, map[string]any{"a__b": 2, `a\_\_b`: 1, "id": 3}},
+	}
+
+	for _, c := range cases {
+		data := map[string]any{
+			"a__b": 1,
+			"a":    map[string]any{"b": 2},
+			"id":   3,
+		}
+
+		// Process
+		err := FlattenStrict(data, &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: c.collision})
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to flatten JSON object, err: %v", err)
+		}
+		if !reflect.DeepEqual(data, c.expected) {
+			t.Fatalf("flattened JSON object is incorrect for %v, %v is not equal expected value %v", c.collision, data, c.expected)
+		}
+	}
+}
+
+func TestFlattenJsonObject_SameAsFlattened(t *testing.T) {
+	// Prepare
+	newData := func() map[string]any {
+		return map[string]any{
+			"a":      map[string]any{"b": 1, "c": []any{2, map[string]any{"d": nil}}},
+			"a__b#2": "x",
+			"e":      map[string]any{"f": 3, "f#2": 4},
+			"e__f":   5,
+			"g__h":   6,
+			"id":     json.Number("7"),
+			"ptr":    &struct{ X int }{X: 8},
+		}
+	}
+
+	for _, collision := range []KeyCollision{KeyCollisionOverwrite, KeyCollisionKeepFirst, KeyCollisionSuffix, KeyCollisionEscape} {
+		opt := &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: collision}
+		data := newData()
+		expected, err := Flattened(newData(), opt)
+		if err != nil {
+			t.Fatalf("failed to flatten JSON object, err: %v", err)
+		}
+
+		// Process
+		err = FlattenStrict(data, opt)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to flatten JSON object, err: %v", err)
+		}
+		if !reflect.DeepEqual(data, expected) {
+			t.Fatalf("flattened JSON object is incorrect for %v, %v is not equal expected value %v", collision, data, expected)
+		}
+	}
+}
+
+func TestFlattenJsonObject_KeyCollisionError(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"f[0]": 1,
+		"f":    []any{2},
+	}
+
+	// Process
+	err := FlattenStrict(data, &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionError})
+
+	// Check
+	if !errors.Is(err, ErrFlattenCollision) {
+		t.Fatalf("It should throw ErrFlattenCollision, current: %v", err)
+	}
+	expMsg := `colliding flattened keys: "f[0]"`
+	if err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %s", expMsg, err.Error())
+	}
+	if _, exist := data["f"]; !exist || len(data) != 2 {
+		t.Fatalf("It should leave JSON object untouched on error, current: %v", data)
+	}
+}
+
+func TestFlattenJsonObject_KeyCollisionErrorLenient(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"f[0]": 1,
+		"f":    []any{2},
+	}
+
+	// Process
+	Flatten(data, &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionError})
+
+	// Check
+	expected := map[string]any{"f[0]": 1}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("It should overwrite colliding keys, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestFlattenOrderedJsonObject_KeyCollision(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+	if err := json.Unmarshal([]byte(`{"a__b": 1, "a": {"b": 2}}`), obj); err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+
+	// Process
+	err := FlattenOrdered(obj, &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionSuffix})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to flatten JSON object, err: %v", err)
+	}
+	b, _ := json.Marshal(obj)
+	expected := `{"a__b":1,"a__b#2":2}`
+	if string(b) != expected {
+		t.Fatalf("flattened JSON object is incorrect, %s is not equal expected value %s", b, expected)
+	}
+}
+
+func TestEscapeFlattenKey(t *testing.T) {
+	// Prepare
+	cases := []struct {
+		key      string
+		opt      *FlattenOption
+		expected string
+	}{
+		{"a__b", nil, `a\_\_b`},
+		{`a\b`, nil, `a\\b`},
+		{"f[0]", nil, `f\[0]`},
+		{"f[0]", &FlattenOption{Gap: ".", ArrayKeyStyle: ArrayKeyGap}, "f[0]"},
+		{"a.b", &FlattenOption{Gap: "."}, `a\.b`},
+		{"10", &FlattenOption{Gap: ".", ArrayKeyStyle: ArrayKeyGap}, `\10`},
+		{"10", nil, "10"},
+	}
+
+	for _, c := range cases {
+		// Process
+		escaped := EscapeFlattenKey(c.key, c.opt)
+
+		// Check
+		if escaped != c.expected {
+			t.Fatalf("escaped key is incorrect for %q, %s is not equal expected value %s", c.key, escaped, c.expected)
+		}
+		if unescaped := UnescapeFlattenKey(escaped); unescaped != c.key {
+			t.Fatalf("unescaped key is incorrect, %s is not equal expected value %s", unescaped, c.key)
+		}
+	}
+}
+
+func TestParseKeyCollision(t *testing.T) {
+	// Prepare
+	cases := map[string]KeyCollision{
+		"":           KeyCollisionOverwrite,
+		"overwrite":  KeyCollisionOverwrite,
+		"error":      KeyCollisionError,
+		"escape":     KeyCollisionEscape,
+		"suffix":     KeyCollisionSuffix,
+		"keep-first": KeyCollisionKeepFirst,
+	}
+
+	for s, expected := range cases {
+		// Process
+		collision, err := ParseKeyCollision(s)
+
+		// Check
+		if err != nil || collision != expected {
+			t.Fatalf("parsed key collision policy is incorrect for %q, current: %v, err: %v", s, collision, err)
+		}
+	}
+	if _, err := ParseKeyCollision("merge"); err == nil {
+		t.Fatalf("It should throw an error for unsupported key collision policy")
+	}
+}
+
+func TestFlattened(t *testing.T) {
+	// Prepare
+	data := sampleObject()
+
+	// Process
+	flat, err := Flattened(data, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to flatten JSON object, err: %v", err)
+	}
+	if !reflect.DeepEqual(data, sampleObject()) {
+		t.Fatalf("It should leave JSON object unmodified, current: %v", data)
+	}
+	expected := sampleObject()
+	Flatten(expected, nil)
+	if !reflect.DeepEqual(flat, expected) {
+		t.Fatalf("flattened JSON object is incorrect, %v is not equal expected value %v", flat, expected)
+	}
+}
+
+func TestFlattenedOrdered(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+	if err := json.Unmarshal([]byte(`{"b": {"c": 1}, "a": [2]}`), obj); err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+
+	// Process
+	flat, err := FlattenedOrdered(obj, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to flatten JSON object, err: %v", err)
+	}
+	if expected := []string{"b", "a"}; !reflect.DeepEqual(obj.Keys(), expected) {
+		t.Fatalf("It should leave JSON object unmodified, current keys: %v", obj.Keys())
+	}
+	if expected := []string{"b__c", "a[0]"}; !reflect.DeepEqual(flat.Keys(), expected) {
+		t.Fatalf("flattened keys are incorrect, %v is not equal expected value %v", flat.Keys(), expected)
+	}
+}
+
+func TestFlattenJsonObject_Rules(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"id":       1,
+		"address":  map[string]any{"city": "HCM", "geo": map[string]any{"lat": 10.8, "lng": 106.6}},
+		"tags":     []any{"a", "b"},
+		"metadata": map[string]any{"source": "web", "extra": map[string]any{"x": 1}},
+		"items":    []any{map[string]any{"sku": "A1", "parts": []any{1, 2}}},
+	}
+	opt := &FlattenOption{
+		Level: 1,
+		Gap:   "__",
+		Rules: []FlattenRule{
+			{Path: "address", Level: FlattenLevelUnlimited},
+			{Path: "tags", Level: FlattenLevelUnlimited, SkipArray: true},
+			{Path: "items[*]", Level: 1, SkipArray: true},
+		},
+	}
+
+	// Process
+	err := FlattenStrict(data, opt)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to flatten JSON object, err: %v", err)
+	}
+	expected := map[string]any{
+		"id":                1,
+		"address__city":     "HCM",
+		"address__geo__lat": 10.8,
+		"address__geo__lng": 106.6,
+		"tags":              []any{"a", "b"},
+		"metadata__source":  "web",
+		"metadata__extra":   map[string]any{"x": 1},
+		"items[0]__sku":     "A1",
+		"items[0]__parts":   []any{1, 2},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("flattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestFlattenJsonObject_Rules_FirstMatch(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"meta": map[string]any{"a": map[string]any{"b": 1}},
+		"misc": map[string]any{"a": map[string]any{"b": 2}},
+	}
+	opt := &FlattenOption{
+		Level: FlattenLevelUnlimited,
+		Gap:   "__",
+		Rules: []FlattenRule{
+			{Path: "meta", Level: FlattenLevelNonNested},
+			{Path: "m*", Level: 1},
+		},
+	}
+
+	// Process
+	Flatten(data, opt)
+
+	// Check
+	expected := map[string]any{
+		"meta":    map[string]any{"a": map[string]any{"b": 1}},
+		"misc__a": map[string]any{"b": 2},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("flattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
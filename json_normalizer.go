@@ -0,0 +1,251 @@
+package jsonconv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNormalizeKeyColumn is returned by Normalize when a JSON object has a field with
+// the name of a generated key column. Set KeyColumn or ParentKeyColumn of NormalizeOption
+// to another name to keep the field.
+var ErrNormalizeKeyColumn = errors.New("field clashes with a key column")
+
+const (
+	// DefaultNormalizeRootTable is the default name of the root table created by Normalize.
+	DefaultNormalizeRootTable = "root"
+
+	// DefaultNormalizeKeyColumn is the default name of the generated key column.
+	DefaultNormalizeKeyColumn = "_id"
+
+	// DefaultNormalizeParentKeyColumn is the default name of the foreign key column.
+	DefaultNormalizeParentKeyColumn = "_parent_id"
+
+	// normalizeValueColumn is the column of array elements which are not JSON objects.
+	normalizeValueColumn = "value"
+)
+
+// A NormalizeOption converts JSON objects to normalized CSV tables.
+type NormalizeOption struct {
+	// Options to convert every table to CSV data. Arrays are always
+	// moved to child tables, so SkipArray of FlattenOption and its Rules
+	// is ignored, and so is Explode. Unlike ToCsv, nested JSON objects are
+	// flattened with DefaultFlattenLevel and DefaultFlattenGap if FlattenOption is nil
+	ToCsvOption
+
+	// Name of the table of the given JSON objects.
+	// DefaultNormalizeRootTable is used if it is empty. A child table
+	// with the same name gets a "#2" suffix
+	RootTable string
+
+	// Name of the column holding the generated key (1, 2, ...) of every row.
+	// DefaultNormalizeKeyColumn is used if it is empty
+	KeyColumn string
+
+	// Name of the column of child tables holding the key of the parent row.
+	// DefaultNormalizeParentKeyColumn is used if it is empty
+	ParentKeyColumn string
+}
+
+// A Table is a named CSV table created by Normalize.
+type Table struct {
+	// Name of the table. A child table is named after the path of its JSON
+	// array, e.g. "items" or "items__parts" with the default flatten gap
+	Name string
+
+	// CSV data of the table, with the headers first
+	Data [][]string
+}
+
+// Normalize converts arr to relational tables: a root table with a row per
+// JSON object and a child table per nested JSON array, with a row per array
+// element. Nested JSON objects are flattened into the row of their parent.
+// Every row gets a generated key, and rows of child tables refer to the key
+// of their parent row. Array elements which are not JSON objects are put in
+// a "value" column. Tables are returned in order of first appearance, the
+// root table first. If opt is nil, default options are used.
+//
+// Unless opt.KeepInput is set, the JSON objects of arr and of its nested arrays
+// are modified: they are flattened, get their key columns and lose the JSON arrays
+// which are moved to child tables.
+//
+// It returns ErrNormalizeKeyColumn if a row has a field named like one of
+// its key columns, which would be overwritten by the generated key, and
+// ErrFlattenCollision with KeyCollisionError.
+func Normalize(arr []map[string]any, opt *NormalizeOption) ([]Table, error) {
+	if opt == nil {
+		opt = &NormalizeOption{}
+	}
+	n := &normalizer{
+		keyCol:    opt.KeyColumn,
+		parCol:    opt.ParentKeyColumn,
+		root:      opt.RootTable,
+		keepInput: opt.KeepInput,
+		rows:      make(map[string][]map[string]any),
+	}
+	if n.keyCol == "" {
+		n.keyCol = DefaultNormalizeKeyColumn
+	}
+	if n.parCol == "" {
+		n.parCol = DefaultNormalizeParentKeyColumn
+	}
+	if n.root == "" {
+		n.root = DefaultNormalizeRootTable
+	}
+	if n.keyCol == n.parCol {
+		return nil, fmt.Errorf("key column and parent key column have the same name %q", n.keyCol)
+	}
+	n.flattenOpt = &FlattenOption{
+		Level: DefaultFlattenLevel,
+		Gap:   DefaultFlattenGap,
+	}
+	if opt.FlattenOption != nil {
+		fo := *opt.FlattenOption
+		fo.Rules = make([]FlattenRule, 0, len(opt.FlattenOption.Rules))
+		for _, r := range opt.FlattenOption.Rules {
+			r.SkipArray = true
+			fo.Rules = append(fo.Rules, r)
+		}
+		n.flattenOpt = &fo
+	}
+	n.flattenOpt.SkipArray = true
+
+	// Tables are keyed by the path of their JSON array, empty for the root table.
+	n.addTable("")
+	for _, obj := range arr {
+		if err := n.addRow("", obj, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// Convert every table to CSV data with key columns first.
+	csvOpt := opt.ToCsvOption
+	csvOpt.FlattenOption = nil
+	csvOpt.Explode = nil
+	tables := make([]Table, 0, len(n.paths))
+	names := n.tableNames()
+	for i, path := range n.paths {
+		tableOpt := csvOpt
+		tableOpt.BaseHeaders = append([]string{n.keyCol}, opt.BaseHeaders...)
+		if i > 0 {
+			tableOpt.BaseHeaders = append([]string{n.keyCol, n.parCol}, opt.BaseHeaders...)
+		}
+		data := ToCsv(n.rows[path], &tableOpt)
+		if len(data) == 0 {
+			data = [][]string{renameCsvHeader(tableOpt.BaseHeaders, &tableOpt)}
+		}
+		tables = append(tables, Table{Name: names[i], Data: data})
+	}
+	return tables, nil
+}
+
+// A normalizer collects rows of the tables created by Normalize.
+type normalizer struct {
+	flattenOpt *FlattenOption
+	keepInput  bool
+	keyCol     string
+	parCol     string
+	root       string
+	paths      []string
+	rows       map[string][]map[string]any
+}
+
+// addTable adds the table of the JSON arrays at path if it does not exist.
+func (n *normalizer) addTable(path string) {
+	if _, exist := n.rows[path]; !exist {
+		n.paths = append(n.paths, path)
+		n.rows[path] = nil
+	}
+}
+
+// tableNames returns the name of every table: the root table name for the root table
+// and the path of others. A child table named like another table gets a suffix "#2", "#3", ...
+func (n *normalizer) tableNames() []string {
+	root := n.root
+	taken := make(map[string]struct{}, len(n.paths))
+	for _, path := range n.paths {
+		taken[path] = struct{}{}
+	}
+	taken[root] = struct{}{}
+
+	names := make([]string, 0, len(n.paths))
+	for _, path := range n.paths {
+		name := path
+		if path == "" {
+			name = root
+		} else if path == root {
+			for i := 2; ; i++ {
+				name = fmt.Sprintf("%s#%d", path, i)
+				if _, exist := taken[name]; !exist {
+					break
+				}
+			}
+			taken[name] = struct{}{}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// addRow flattens obj into a row of the table at path, then moves its arrays to child tables.
+func (n *normalizer) addRow(path string, obj map[string]any, parentKey any) error {
+	var err error
+	if n.keepInput {
+		obj, err = Flattened(obj, n.flattenOpt)
+	} else {
+		err = FlattenStrict(obj, n.flattenOpt)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Generated keys must not overwrite fields of the row.
+	keyCols := []string{n.keyCol}
+	if parentKey != nil {
+		keyCols = append(keyCols, n.parCol)
+	}
+	for _, col := range keyCols {
+		if _, exist := obj[col]; exist {
+			table := path
+			if table == "" {
+				table = n.root
+			}
+			return fmt.Errorf("%w: %q of table %q", ErrNormalizeKeyColumn, col, table)
+		}
+	}
+
+	key := len(n.rows[path]) + 1
+	obj[n.keyCol] = key
+	if parentKey != nil {
+		obj[n.parCol] = parentKey
+	}
+	n.rows[path] = append(n.rows[path], obj)
+
+	// Move JSON arrays to child tables, in a deterministic order.
+	var arrKeys []string
+	for k, v := range obj {
+		if _, ok := v.([]any); ok {
+			arrKeys = append(arrKeys, k)
+		}
+	}
+	sortNatural(arrKeys, n.flattenOpt)
+	for _, k := range arrKeys {
+		arr := obj[k].([]any)
+		delete(obj, k)
+
+		childPath := k
+		if path != "" {
+			childPath = path + n.flattenOpt.Gap + k
+		}
+		n.addTable(childPath)
+		for _, elem := range arr {
+			child, ok := elem.(map[string]any)
+			if !ok {
+				child = map[string]any{normalizeValueColumn: elem}
+			}
+			if err := n.addRow(childPath, child, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
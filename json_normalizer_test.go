@@ -0,0 +1,191 @@
+package jsonconv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{
+		{
+			"id":       "o1",
+			"customer": map[string]any{"name": "Jon Doe", "phones": []any{"111", "222"}},
+			"items": []any{
+				map[string]any{"sku": "A1", "parts": []any{map[string]any{"no": 1}}},
+				map[string]any{"sku": "B2"},
+			},
+		},
+		{
+			"id":    "o2",
+			"items": []any{map[string]any{"sku": "C3", "parts": []any{map[string]any{"no": 2}, map[string]any{"no": 3}}}},
+		},
+	}
+
+	// Process
+	tables, err := Normalize(arr, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to normalize, err: %v", err)
+	}
+	got := make(map[string]string)
+	var names []string
+	for _, tb := range tables {
+		names = append(names, tb.Name)
+		var rows []string
+		for _, r := range tb.Data {
+			rows = append(rows, strings.Join(r, ","))
+		}
+		got[tb.Name] = strings.Join(rows, "\n")
+	}
+	expNames := []string{"root", "customer__phones", "items", "items__parts"}
+	if !reflect.DeepEqual(names, expNames) {
+		t.Fatalf("table names are incorrect, %v is not equal expected value %v", names, expNames)
+	}
+	expected := map[string]string{
+		"root":             "_id,customer__name,id\n1,Jon Doe,o1\n2,,o2",
+		"customer__phones": "_id,_parent_id,value\n1,1,111\n2,1,222",
+		"items":            "_id,_parent_id,sku\n1,1,A1\n2,1,B2\n3,2,C3",
+		"items__parts":     "_id,_parent_id,no\n1,1,1\n2,3,2\n3,3,3",
+	}
+	for name, exp := range expected {
+		if got[name] != exp {
+			t.Fatalf("table %s is incorrect, %s is not equal expected value %s", name, got[name], exp)
+		}
+	}
+}
+
+func TestNormalize_Option(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{
+		{"id": 1, "tags": []any{}, "meta": map[string]any{"source": "web"}},
+	}
+
+	// Process
+	tables, err := Normalize(arr, &NormalizeOption{
+		ToCsvOption: ToCsvOption{
+			FlattenOption: &FlattenOption{Level: FlattenLevelUnlimited, Gap: ".", SkipArray: false},
+			NullValue:     "NULL",
+		},
+		RootTable:       "orders",
+		KeyColumn:       "pk",
+		ParentKeyColumn: "fk",
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to normalize, err: %v", err)
+	}
+	expected := []Table{
+		{Name: "orders", Data: [][]string{{"pk", "id", "meta.source"}, {"1", "1", "web"}}},
+		{Name: "tags", Data: [][]string{{"pk", "fk"}}},
+	}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Fatalf("normalized tables are incorrect, %v is not equal expected value %v", tables, expected)
+	}
+}
+
+func TestNormalize_KeepInput(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{
+		{"id": "o1", "items": []any{map[string]any{"sku": "A1"}}},
+	}
+
+	// Process
+	tables, err := Normalize(arr, &NormalizeOption{ToCsvOption: ToCsvOption{KeepInput: true}})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to normalize, err: %v", err)
+	}
+	expected := []map[string]any{
+		{"id": "o1", "items": []any{map[string]any{"sku": "A1"}}},
+	}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Fatalf("It should leave JSON array unmodified, current: %v", arr)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("It should create 2 tables, current: %v", tables)
+	}
+}
+
+func TestNormalize_RootTableName(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{
+		{"id": 1, "root": []any{"a"}, "root#2": []any{"b"}},
+	}
+
+	// Process
+	tables, err := Normalize(arr, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to normalize, err: %v", err)
+	}
+	expected := []Table{
+		{Name: "root", Data: [][]string{{"_id", "id"}, {"1", "1"}}},
+		{Name: "root#3", Data: [][]string{{"_id", "_parent_id", "value"}, {"1", "1", "a"}}},
+		{Name: "root#2", Data: [][]string{{"_id", "_parent_id", "value"}, {"1", "1", "b"}}},
+	}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Fatalf("normalized tables are incorrect, %v is not equal expected value %v", tables, expected)
+	}
+}
+
+func TestNormalize_KeyColumnClash(t *testing.T) {
+	// Prepare
+	cases := map[string][]map[string]any{
+		`field clashes with a key column: "_id" of table "root"`:         {{"_id": "abc-123"}},
+		`field clashes with a key column: "_parent_id" of table "items"`: {{"items": []any{map[string]any{"_parent_id": 1}}}},
+		`field clashes with a key column: "_id" of table "items__parts"`: {{"items": []any{map[string]any{"parts": []any{map[string]any{"_id": 1}}}}}},
+	}
+
+	for expMsg, arr := range cases {
+		// Process
+		_, err := Normalize(arr, nil)
+
+		// Check
+		if err == nil || err.Error() != expMsg || !errors.Is(err, ErrNormalizeKeyColumn) {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+
+	// Process
+	tables, err := Normalize([]map[string]any{{"_id": "abc-123"}}, &NormalizeOption{KeyColumn: "pk"})
+
+	// Check
+	expected := []Table{{Name: "root", Data: [][]string{{"pk", "_id"}, {"1", "abc-123"}}}}
+	if err != nil || !reflect.DeepEqual(tables, expected) {
+		t.Fatalf("It should keep the field with another key column, current: %v, err: %v", tables, err)
+	}
+}
+
+func TestNormalize_KeyCollisionError(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{{"items": []any{map[string]any{"a": map[string]any{"b": 1}, "a__b": 2}}}}
+	opt := &NormalizeOption{ToCsvOption: ToCsvOption{
+		FlattenOption: &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionError},
+	}}
+
+	// Process
+	_, err := Normalize(arr, opt)
+
+	// Check
+	if !errors.Is(err, ErrFlattenCollision) {
+		t.Fatalf("It should throw ErrFlattenCollision, current: %v", err)
+	}
+}
+
+func TestNormalize_SameKeyColumns(t *testing.T) {
+	// Process
+	_, err := Normalize(nil, &NormalizeOption{KeyColumn: "id", ParentKeyColumn: "id"})
+
+	// Check
+	expMsg := `key column and parent key column have the same name "id"`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
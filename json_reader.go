@@ -1,64 +1,357 @@
 package jsonconv
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"reflect"
-	"strings"
+	"sort"
 )
 
+// A JsonFormat describes how JSON values are laid out in the input of a JsonReader.
+type JsonFormat int
+
+const (
+	// JsonFormatAuto detects the format from the first non-whitespace character:
+	// '[' for a JSON array and '{' for one or more JSON objects. Read decodes
+	// any other JSON value, such as a string or null, as is.
+	JsonFormatAuto JsonFormat = iota
+
+	// JsonFormatArray expects a single JSON array of JSON objects.
+	JsonFormatArray
+
+	// JsonFormatObject expects a single JSON object.
+	JsonFormatObject
+
+	// JsonFormatNdjson expects newline-delimited JSON objects (JSON Lines).
+	JsonFormatNdjson
+)
+
+// ParseJsonFormat returns the JsonFormat named by s,
+// which is one of "auto", "array", "object" and "ndjson".
+func ParseJsonFormat(s string) (JsonFormat, error) {
+	switch s {
+	case "auto", "":
+		return JsonFormatAuto, nil
+	case "array":
+		return JsonFormatArray, nil
+	case "object":
+		return JsonFormatObject, nil
+	case "ndjson", "jsonl":
+		return JsonFormatNdjson, nil
+	}
+	return JsonFormatAuto, fmt.Errorf("unsupported JSON format %q", s)
+}
+
+// A JsonRecordError describes an invalid JSON record read by JsonReader.
+type JsonRecordError struct {
+	// Line number (1-based) of the invalid JSON record
+	Line int
+
+	Err error
+}
+
+func (e *JsonRecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *JsonRecordError) Unwrap() error {
+	return e.Err
+}
+
 // A JsonReader reads and decodes JSON values from an input stream.
 type JsonReader struct {
-	reader io.ReadSeeker
+	// Format of the input. Set to JsonFormatAuto by default in NewJsonReader
+	Format JsonFormat
+
+	// Decode JSON numbers as json.Number instead of float64, so that
+	// large integers such as 64-bit IDs keep their precision
+	UseNumber bool
+
+	reader  io.Reader
+	lines   *lineCountingReader
+	decoder *json.Decoder
+	format  JsonFormat // resolved format, never JsonFormatAuto once started
+	base    int64      // number of whitespace bytes read before decoder
+	started bool
+	opened  bool // whether the opening bracket of a JSON array is consumed
+	done    bool
 }
 
 // NewJsonReader returns a new JsonReader that reads from r.
-func NewJsonReader(r io.ReadSeeker) *JsonReader {
+func NewJsonReader(r io.Reader) *JsonReader {
 	return &JsonReader{
 		reader: r,
+		Format: JsonFormatAuto,
 	}
 }
 
-// Read reads the next JSON-encoded value from its
-// input and stores it in the value pointed to by v.
+// Read reads the JSON-encoded input and stores it in the value pointed to by v.
+//
+// A JSON array or a JSON object is decoded into v as is. Newline-delimited
+// JSON objects are decoded into v when it points to a slice, or into []any
+// when it points to an empty interface holding more than one JSON object.
 func (r *JsonReader) Read(v any) error {
-	decoder := json.NewDecoder(r.reader)
-	err := decoder.Decode(v)
+	first, err := r.start()
 	if err != nil {
-		if !strings.Contains(err.Error(), "cannot unmarshal object into Go value of type") {
-			return err
-		}
+		return err
+	}
+	if r.done {
+		return r.recordError(io.ErrUnexpectedEOF)
+	}
 
-		// The JSON data is a valid JSON array. However, we will try to decode it line by line.
-		// Reset reader and decoder.
-		if _, seekErr := r.reader.Seek(0, io.SeekStart); seekErr != nil {
-			return seekErr
-		}
-		decoder = json.NewDecoder(r.reader)
+	refval := reflect.ValueOf(v)
+	if refval.Kind() != reflect.Pointer || refval.IsNil() {
+		return fmt.Errorf("non-pointer value %T passed to Read", v)
+	}
+	refval = refval.Elem()
 
-		// Check if v is a pointer to a slice or an array. If not, return an error.
-		refval := reflect.ValueOf(v)
-		if refval.Kind() == reflect.Pointer {
-			refval = refval.Elem()
-		}
-		if refval.Kind() != reflect.Slice && refval.Kind() != reflect.Array {
-			return err
+	// With auto format, a single JSON object is decoded as is, unless v wants a slice.
+	isSlice := refval.Kind() == reflect.Slice
+	if r.format == JsonFormatNdjson && first == '{' && r.Format == JsonFormatAuto &&
+		!isSlice && refval.Kind() != reflect.Interface {
+		r.format = JsonFormatObject
+	}
+
+	switch r.format {
+	case JsonFormatArray, JsonFormatObject:
+		if err := r.decoder.Decode(v); err != nil {
+			return r.recordError(err)
 		}
+		r.done = true
+		return nil
+	}
 
-		// Decode JSON array into v.
+	// Decode newline-delimited JSON objects.
+	if refval.Kind() == reflect.Interface {
+		var arr []any
 		for {
-			obj := reflect.New(refval.Type().Elem())
-			objInterface := obj.Interface()
-			err := decoder.Decode(objInterface)
-			if err != nil {
+			var obj any
+			if err := r.decodeNext(&obj); err != nil {
 				if err == io.EOF {
-					reflect.ValueOf(v).Elem().Set(refval)
-					return nil
+					break
 				}
 				return err
 			}
-			refval.Set(reflect.Append(refval, reflect.ValueOf(objInterface).Elem()))
+			arr = append(arr, obj)
+		}
+		if len(arr) == 1 && r.Format == JsonFormatAuto {
+			refval.Set(reflect.ValueOf(arr[0]))
+			return nil
+		}
+		refval.Set(reflect.ValueOf(arr))
+		return nil
+	}
+	if !isSlice {
+		return fmt.Errorf("cannot decode newline-delimited JSON into %T", v)
+	}
+	for {
+		elem := reflect.New(refval.Type().Elem())
+		if err := r.decodeNext(elem.Interface()); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
 		}
+		refval.Set(reflect.Append(refval, elem.Elem()))
+	}
+}
+
+// Next reads the next JSON object from the input. It returns io.EOF
+// when there are no more JSON objects. Elements of a JSON array and
+// newline-delimited JSON objects are read one at a time, so the whole
+// input never has to be held in memory.
+func (r *JsonReader) Next() (map[string]any, error) {
+	if _, err := r.start(); err != nil {
+		return nil, err
+	}
+	var v any
+	if err := r.decodeNext(&v); err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, r.recordError(fmt.Errorf("unsupported type of JSON data"))
+	}
+	return obj, nil
+}
+
+// NextOrdered is like Next, but it returns the JSON object as an OrderedObject
+// which keeps keys in the order they appear in the input, including keys of
+// nested JSON objects.
+func (r *JsonReader) NextOrdered() (*OrderedObject, error) {
+	if _, err := r.start(); err != nil {
+		return nil, err
+	}
+	var v any
+	err := r.decodeRecord(func() error {
+		var err error
+		v, err = decodeOrderedValue(r.decoder)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	obj, ok := v.(*OrderedObject)
+	if !ok {
+		return nil, r.recordError(fmt.Errorf("unsupported type of JSON data"))
+	}
+	return obj, nil
+}
+
+// start detects the input format and prepares the decoder.
+// It returns the first non-whitespace character of the input.
+func (r *JsonReader) start() (byte, error) {
+	if r.started {
+		return 0, nil
+	}
+	r.started = true
+
+	r.lines = &lineCountingReader{reader: r.reader}
+	br := bufio.NewReader(r.lines)
+	r.decoder = json.NewDecoder(br)
+	if r.UseNumber {
+		r.decoder.UseNumber()
+	}
+
+	// Peek the first non-whitespace character.
+	var first byte
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			r.done = true
+			r.format = r.Format
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			r.base++
+			continue
+		}
+		first = b
+		_ = br.UnreadByte()
+		break
+	}
+
+	r.format = r.Format
+	switch r.format {
+	case JsonFormatAuto:
+		switch first {
+		case '[':
+			r.format = JsonFormatArray
+		case '{':
+			r.format = JsonFormatNdjson
+		default:
+			// Another JSON value is read as a single value by Read,
+			// while Next and NextOrdered reject it.
+			r.format = JsonFormatObject
+		}
+	case JsonFormatArray:
+		if first != '[' {
+			return first, r.recordError(fmt.Errorf("expected JSON array"))
+		}
+	case JsonFormatObject, JsonFormatNdjson:
+		if first != '{' {
+			return first, r.recordError(fmt.Errorf("expected JSON object"))
+		}
+	}
+	return first, nil
+}
+
+// decodeNext decodes the next JSON record into v, or returns io.EOF.
+func (r *JsonReader) decodeNext(v any) error {
+	return r.decodeRecord(func() error {
+		return r.decoder.Decode(v)
+	})
+}
+
+// decodeRecord calls decode to decode the next JSON record, or returns io.EOF.
+func (r *JsonReader) decodeRecord(decode func() error) error {
+	if r.done {
+		return io.EOF
+	}
+
+	switch r.format {
+	case JsonFormatArray:
+		if !r.opened {
+			// Consume the opening bracket.
+			if _, err := r.decoder.Token(); err != nil {
+				return r.recordError(err)
+			}
+			r.opened = true
+		}
+		if !r.decoder.More() {
+			// Consume the closing bracket.
+			if _, err := r.decoder.Token(); err != nil {
+				return r.recordError(err)
+			}
+			r.done = true
+			return io.EOF
+		}
+	case JsonFormatObject:
+		r.done = true
+	}
+
+	err := decode()
+	if err == io.EOF && r.format == JsonFormatNdjson {
+		r.done = true
+		return io.EOF
+	}
+	if err != nil {
+		return r.recordError(err)
+	}
+	r.lines.discard(r.base + r.decoder.InputOffset())
 	return nil
 }
+
+// recordError wraps err with the line number where it happened.
+func (r *JsonReader) recordError(err error) error {
+	off := r.base + r.decoder.InputOffset()
+	var se *json.SyntaxError
+	switch {
+	case errors.As(err, &se):
+		off = r.base + se.Offset
+	case err == io.ErrUnexpectedEOF:
+		// The whole input has been read, the record ends at the last line.
+		off = r.lines.read
+	}
+	return &JsonRecordError{Line: r.lines.lineAt(off), Err: err}
+}
+
+// A lineCountingReader records offsets of newline characters read from reader,
+// so that an input offset can be mapped to a line number. Offsets before
+// the last discarded one are only kept as a count, to bound memory usage.
+type lineCountingReader struct {
+	reader   io.Reader
+	read     int64
+	newlines []int64
+	skipped  int
+}
+
+func (r *lineCountingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			r.newlines = append(r.newlines, r.read+int64(i))
+		}
+	}
+	r.read += int64(n)
+	return n, err
+}
+
+// lineAt returns the 1-based line number of offset off.
+func (r *lineCountingReader) lineAt(off int64) int {
+	n := sort.Search(len(r.newlines), func(i int) bool { return r.newlines[i] >= off })
+	return r.skipped + n + 1
+}
+
+// discard forgets newline offsets before off.
+func (r *lineCountingReader) discard(off int64) {
+	n := sort.Search(len(r.newlines), func(i int) bool { return r.newlines[i] >= off })
+	r.skipped += n
+	r.newlines = r.newlines[n:]
+}
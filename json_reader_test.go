@@ -1,6 +1,10 @@
 package jsonconv
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -141,3 +145,327 @@ func TestJsonReader_JsonArray_NewlineDelimited(t *testing.T) {
 		t.Fatalf("failed to read json array")
 	}
 }
+
+// nonSeekableReader hides the Seek method of the wrapped reader.
+type nonSeekableReader struct {
+	io.Reader
+}
+
+func TestJsonReader_JsonArray_NewlineDelimited_NonSeekable(t *testing.T) {
+	// Prepare
+	raw := `{"id": 1, "user": "Tuấn"}
+{"id": 2, "user": "Jon Doe"}`
+	var v any
+	re := NewJsonReader(nonSeekableReader{strings.NewReader(raw)})
+
+	// Process
+	err := re.Read(&v)
+	if err != nil {
+		t.Fatalf("failed to read json, err: %v", err)
+	}
+
+	// Check
+	arr, ok := v.([]any)
+	if !ok || len(arr) != 2 {
+		t.Fatalf("failed to read newline-delimited json, current: %v", v)
+	}
+	if arr[1].(map[string]any)["user"] != "Jon Doe" {
+		t.Fatalf("failed to read newline-delimited json, current: %v", v)
+	}
+}
+
+func TestJsonReader_Format_Mismatch(t *testing.T) {
+	// Prepare
+	cases := map[JsonFormat]string{
+		JsonFormatArray:  `{"id": 1}`,
+		JsonFormatObject: `[{"id": 1}]`,
+		JsonFormatNdjson: `[{"id": 1}]`,
+	}
+
+	for format, raw := range cases {
+		var v any
+		re := NewJsonReader(strings.NewReader(raw))
+		re.Format = format
+
+		// Process
+		err := re.Read(&v)
+
+		// Check
+		if err == nil {
+			t.Fatalf("Should throw an error for format %v and JSON %s", format, raw)
+		}
+	}
+}
+
+func TestJsonReader_Scalar(t *testing.T) {
+	// Prepare
+	cases := map[string]any{
+		` "str"`: "str",
+		"42":     42.0,
+		"null":   nil,
+		"true":   true,
+	}
+
+	for raw, expected := range cases {
+		var v any = "unset"
+		re := NewJsonReader(strings.NewReader(raw))
+
+		// Process
+		err := re.Read(&v)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to read json %s, err: %v", raw, err)
+		}
+		if v != expected {
+			t.Fatalf("json %s should be read as %#v, current: %#v", raw, expected, v)
+		}
+
+		// Process
+		_, err = NewJsonReader(strings.NewReader(raw)).Next()
+		_, orderedErr := NewJsonReader(strings.NewReader(raw)).NextOrdered()
+
+		// Check
+		expMsg := "line 1: unsupported type of JSON data"
+		for _, err := range []error{err, orderedErr} {
+			if err == nil || err.Error() != expMsg {
+				t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+			}
+		}
+	}
+}
+
+func TestJsonReader_Format_Ndjson_SingleObject(t *testing.T) {
+	// Prepare
+	raw := `{"id": 1}`
+	var v any
+	re := NewJsonReader(strings.NewReader(raw))
+	re.Format = JsonFormatNdjson
+
+	// Process
+	err := re.Read(&v)
+	if err != nil {
+		t.Fatalf("failed to read json, err: %v", err)
+	}
+
+	// Check
+	if arr, ok := v.([]any); !ok || len(arr) != 1 {
+		t.Fatalf("newline-delimited json should always be read as array, current: %v", v)
+	}
+}
+
+func TestJsonReader_Next(t *testing.T) {
+	// Prepare
+	cases := map[JsonFormat]string{
+		JsonFormatAuto: `
+			[
+				{"id": 1},
+				{"id": 2}
+			]`,
+		JsonFormatArray: `[{"id": 1}, {"id": 2}]`,
+		JsonFormatNdjson: `
+			{"id": 1}
+
+			{"id": 2}
+		`,
+	}
+
+	for format, raw := range cases {
+		re := NewJsonReader(nonSeekableReader{strings.NewReader(raw)})
+		re.Format = format
+
+		// Process
+		var ids []any
+		for {
+			obj, err := re.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("failed to read next json object, err: %v", err)
+			}
+			ids = append(ids, obj["id"])
+		}
+
+		// Check
+		if len(ids) != 2 || ids[0] != 1.0 || ids[1] != 2.0 {
+			t.Fatalf("failed to read json objects for format %v, current: %v", format, ids)
+		}
+	}
+}
+
+func TestJsonReader_Next_JsonObject(t *testing.T) {
+	// Prepare
+	raw := `{"id": 1}`
+	re := NewJsonReader(strings.NewReader(raw))
+	re.Format = JsonFormatObject
+
+	// Process
+	obj, err := re.Next()
+	if err != nil {
+		t.Fatalf("failed to read next json object, err: %v", err)
+	}
+	_, eofErr := re.Next()
+
+	// Check
+	if obj["id"] != 1.0 {
+		t.Fatalf("failed to read json object, current: %v", obj)
+	}
+	if eofErr != io.EOF {
+		t.Fatalf("It should return io.EOF after the json object, current: %v", eofErr)
+	}
+}
+
+func TestJsonReader_Next_LineNumber(t *testing.T) {
+	// Prepare
+	cases := map[string]int{
+		"{\"id\": 1}\n{\"id\": 2}\n{\"id\": ,}\n{\"id\": 4}": 3,
+		"\n\n{\"id\": 1}\n[1]":                               4,
+		"[\n\t{\"id\": 1},\n\t\"id\"\n]":                     3,
+		"[\n\t{\"id\": 1},\n\t{\"id\" 2}\n]":                 3,
+		"{\"id\": 1}\n{\"id\": 2":                            2,
+	}
+
+	for raw, line := range cases {
+		re := NewJsonReader(strings.NewReader(raw))
+
+		// Process
+		var err error
+		for err == nil {
+			_, err = re.Next()
+		}
+
+		// Check
+		var recErr *JsonRecordError
+		if !errors.As(err, &recErr) {
+			t.Fatalf("It should throw a JSON record error for %q, current: %v", raw, err)
+		}
+		if recErr.Line != line {
+			t.Fatalf("It should report line %d for %q, current: %v", line, raw, err)
+		}
+	}
+}
+
+func TestParseJsonFormat(t *testing.T) {
+	// Prepare
+	cases := map[string]JsonFormat{
+		"":       JsonFormatAuto,
+		"auto":   JsonFormatAuto,
+		"array":  JsonFormatArray,
+		"object": JsonFormatObject,
+		"ndjson": JsonFormatNdjson,
+		"jsonl":  JsonFormatNdjson,
+	}
+
+	for s, expected := range cases {
+		// Process
+		format, err := ParseJsonFormat(s)
+
+		// Check
+		if err != nil || format != expected {
+			t.Fatalf("parsed format is incorrect for %q, current: %v, err: %v", s, format, err)
+		}
+	}
+	if _, err := ParseJsonFormat("xml"); err == nil {
+		t.Fatalf("Should throw an error for unsupported format")
+	}
+}
+
+func TestJsonReader_UseNumber(t *testing.T) {
+	// Prepare
+	raw := `[{"id": 1234567890123456789, "nested": {"score": 0.1, "ids": [9007199254740993]}}]`
+	re := NewJsonReader(strings.NewReader(raw))
+	re.UseNumber = true
+
+	// Process
+	obj, err := re.Next()
+	if err != nil {
+		t.Fatalf("failed to read next json object, err: %v", err)
+	}
+	Flatten(obj, nil)
+	csv := ToCsv([]map[string]any{obj}, nil)
+	buf := &strings.Builder{}
+	wr := NewJsonWriter(buf)
+	wr.SortKeys = true
+	err = wr.Write(obj)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write json object, err: %v", err)
+	}
+	if obj["id"] != json.Number("1234567890123456789") {
+		t.Fatalf("It should keep number as json.Number, current: %#v", obj["id"])
+	}
+	expRow := []string{"1234567890123456789", "9007199254740993", "0.1"}
+	if !reflect.DeepEqual(csv[1], expRow) {
+		t.Fatalf("It should keep number precision in CSV row: %v\ncurrent: %v", expRow, csv[1])
+	}
+	expJson := `{"id":1234567890123456789,"nested__ids[0]":9007199254740993,"nested__score":0.1}`
+	if s := strings.TrimSpace(buf.String()); s != expJson {
+		t.Fatalf("It should keep number precision in JSON: %s\ncurrent: %s", expJson, s)
+	}
+}
+
+func TestJsonReader_NextOrdered(t *testing.T) {
+	// Prepare
+	raw := `[{"z": 1, "a": {"y": 2, "b": 3}}, {"m": 4, "c": 5}]`
+	re := NewJsonReader(strings.NewReader(raw))
+	re.UseNumber = true
+
+	// Process
+	var objs []*OrderedObject
+	for {
+		obj, err := re.NextOrdered()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read next json object, err: %v", err)
+		}
+		objs = append(objs, obj)
+	}
+
+	// Check
+	if len(objs) != 2 {
+		t.Fatalf("failed to read json objects, current: %v", objs)
+	}
+	if ks := objs[0].Keys(); !reflect.DeepEqual(ks, []string{"z", "a"}) {
+		t.Fatalf("It should keep key order, current: %v", ks)
+	}
+	nested, _ := objs[0].Get("a")
+	if ks := nested.(*OrderedObject).Keys(); !reflect.DeepEqual(ks, []string{"y", "b"}) {
+		t.Fatalf("It should keep key order of nested JSON object, current: %v", ks)
+	}
+	if val, _ := objs[1].Get("c"); val != json.Number("5") {
+		t.Fatalf("It should decode number as json.Number, current: %#v", val)
+	}
+}
+
+func TestJsonReader_NextOrdered_InvalidJson(t *testing.T) {
+	// Prepare
+	cases := map[string]int{
+		"{\"id\": 1}\n[1]":                   2,
+		"[\n\t{\"id\": 1},\n\t\"id\"\n]":     3,
+		"{\"id\": 1}\n{\"id\": {\"a\": 2}":   2,
+		"[\n\t{\"id\": 1},\n\t{\"id\" 2}\n]": 3,
+	}
+
+	for raw, line := range cases {
+		re := NewJsonReader(strings.NewReader(raw))
+
+		// Process
+		var err error
+		for err == nil {
+			_, err = re.NextOrdered()
+		}
+
+		// Check
+		var recErr *JsonRecordError
+		if !errors.As(err, &recErr) {
+			t.Fatalf("It should throw a JSON record error for %q, current: %v", raw, err)
+		}
+		if recErr.Line != line {
+			t.Fatalf("It should report line %d for %q, current: %v", line, raw, err)
+		}
+	}
+}
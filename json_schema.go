@@ -0,0 +1,166 @@
+package jsonconv
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A FieldSchema describes the values of a flattened key of JSON objects (see InferSchema).
+type FieldSchema struct {
+	// Flattened key
+	Key string `json:"key"`
+
+	// JSON types of non-null values, most frequent first:
+	// string, integer, number, boolean, object or array
+	Types []string `json:"types"`
+
+	// True if the key is null or missing in some JSON objects
+	Nullable bool `json:"nullable"`
+
+	// Percentages of JSON objects where the value of the key is null,
+	// and where the key is present (null or not), rounded to two decimals
+	NullPercent    float64 `json:"nullPercent"`
+	PresentPercent float64 `json:"presentPercent"`
+
+	// Distinct non-null values, up to SchemaExampleCount, in order of appearance
+	Examples []any `json:"examples"`
+
+	// Maximum number of characters of non-null values formatted as CSV cells (see FormatCsvValue)
+	MaxLength int `json:"maxLength"`
+}
+
+// SchemaExampleCount is the maximum number of example values of a FieldSchema.
+const SchemaExampleCount = 3
+
+// JSON types of FieldSchema.
+const (
+	SchemaTypeString  = "string"
+	SchemaTypeInteger = "integer"
+	SchemaTypeNumber  = "number"
+	SchemaTypeBoolean = "boolean"
+	SchemaTypeObject  = "object"
+	SchemaTypeArray   = "array"
+)
+
+// InferSchema flattens copies of the JSON objects of arr with opt (DefaultFlattenOption if it is nil)
+// and describes the values of every flattened key. Keys are ordered like HeaderOrderNatural.
+// An error is only returned with KeyCollisionError.
+func InferSchema(arr []map[string]any, opt *FlattenOption) ([]FieldSchema, error) {
+	if opt == nil {
+		opt = DefaultFlattenOption
+	}
+
+	type field struct {
+		schema   FieldSchema
+		present  int
+		nulls    int
+		types    map[string]int
+		examples map[string]struct{}
+	}
+	fields := make(map[string]*field)
+	for _, obj := range arr {
+		flat, err := Flattened(obj, opt)
+		if err != nil {
+			return nil, err
+		}
+		for k, val := range flat {
+			f, exist := fields[k]
+			if !exist {
+				f = &field{
+					schema:   FieldSchema{Key: k, Types: []string{}, Examples: []any{}},
+					types:    make(map[string]int),
+					examples: make(map[string]struct{}),
+				}
+				fields[k] = f
+			}
+			f.present++
+			typ := schemaTypeOf(val)
+			if typ == "" {
+				f.nulls++
+				continue
+			}
+			f.types[typ]++
+			text := FormatCsvValue(val, "")
+			f.schema.MaxLength = max(f.schema.MaxLength, utf8.RuneCountInString(text))
+			if _, exist := f.examples[typ+":"+text]; !exist && len(f.schema.Examples) < SchemaExampleCount {
+				f.examples[typ+":"+text] = struct{}{}
+				f.schema.Examples = append(f.schema.Examples, val)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sortNatural(keys, opt)
+	schema := make([]FieldSchema, 0, len(keys))
+	for _, k := range keys {
+		f := fields[k]
+		for typ := range f.types {
+			f.schema.Types = append(f.schema.Types, typ)
+		}
+		sort.Slice(f.schema.Types, func(i, j int) bool {
+			a, b := f.schema.Types[i], f.schema.Types[j]
+			if f.types[a] != f.types[b] {
+				return f.types[a] > f.types[b]
+			}
+			return a < b
+		})
+		f.schema.Nullable = f.nulls > 0 || f.present < len(arr)
+		f.schema.NullPercent = schemaPercent(f.nulls, len(arr))
+		f.schema.PresentPercent = schemaPercent(f.present, len(arr))
+		schema = append(schema, f.schema)
+	}
+	return schema, nil
+}
+
+// schemaPercent returns the percentage of n in total, rounded to two decimals.
+func schemaPercent(n, total int) float64 {
+	return math.Round(float64(n)*10000/float64(total)) / 100
+}
+
+// schemaTypeOf returns the JSON type of val, or an empty string if it is null.
+// Whole numbers are integers, like in InferParquetSchema.
+func schemaTypeOf(val any) string {
+	val = indirectValue(val)
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string, encoding.TextMarshaler:
+		return SchemaTypeString
+	case bool:
+		return SchemaTypeBoolean
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return SchemaTypeNumber
+		}
+		return SchemaTypeInteger
+	case float32:
+		if _, ok := parquetWholeFloat(float64(v)); ok {
+			return SchemaTypeInteger
+		}
+		return SchemaTypeNumber
+	case float64:
+		if _, ok := parquetWholeFloat(v); ok {
+			return SchemaTypeInteger
+		}
+		return SchemaTypeNumber
+	}
+
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return SchemaTypeInteger
+	case reflect.Map, reflect.Struct:
+		return SchemaTypeObject
+	case reflect.Slice, reflect.Array:
+		return SchemaTypeArray
+	}
+	return SchemaTypeString
+}
@@ -0,0 +1,131 @@
+package jsonconv
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferSchema(t *testing.T) {
+	// Prepare
+	raw := `[
+		{"id": 1, "user": {"name": "Jon"}, "score": 1.5, "tags": ["a"], "note": null},
+		{"id": 2, "user": {"name": "Nguyễn Tuấn"}, "score": 2, "tags": ["b", "c"]},
+		{"id": "3", "user": {"name": "Jon"}, "score": null, "tags": []},
+		{"id": 4, "user": null, "active": true}
+	]`
+	var arr []map[string]any
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&arr); err != nil {
+		t.Fatalf("failed to decode JSON, err: %v", err)
+	}
+
+	// Process
+	schema, err := InferSchema(arr, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to infer schema, err: %v", err)
+	}
+	expected := []FieldSchema{
+		{Key: "active", Types: []string{"boolean"}, Nullable: true, PresentPercent: 25, Examples: []any{true}, MaxLength: 4},
+		{Key: "id", Types: []string{"integer", "string"}, PresentPercent: 100, Examples: []any{json.Number("1"), json.Number("2"), "3"}, MaxLength: 1},
+		{Key: "note", Types: []string{}, Nullable: true, NullPercent: 25, PresentPercent: 25, Examples: []any{}},
+		{Key: "score", Types: []string{"integer", "number"}, Nullable: true, NullPercent: 25, PresentPercent: 75, Examples: []any{json.Number("1.5"), json.Number("2")}, MaxLength: 3},
+		{Key: "tags[0]", Types: []string{"string"}, Nullable: true, PresentPercent: 50, Examples: []any{"a", "b"}, MaxLength: 1},
+		{Key: "tags[1]", Types: []string{"string"}, Nullable: true, PresentPercent: 25, Examples: []any{"c"}, MaxLength: 1},
+		{Key: "user", Types: []string{}, Nullable: true, NullPercent: 25, PresentPercent: 25, Examples: []any{}},
+		{Key: "user__name", Types: []string{"string"}, Nullable: true, PresentPercent: 75, Examples: []any{"Jon", "Nguyễn Tuấn"}, MaxLength: 11},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Fatalf("schema is incorrect, %+v is not equal expected value %+v", schema, expected)
+	}
+	if _, flattened := arr[0]["user__name"]; flattened {
+		t.Fatalf("InferSchema should not modify JSON objects")
+	}
+}
+
+func TestInferSchema_FlattenOption(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{
+		{"a": map[string]any{"b": map[string]any{"c": 1.0}}, "items": []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+	}
+	opt := &FlattenOption{Level: 1, Gap: ".", SkipArray: false}
+
+	// Process
+	schema, err := InferSchema(arr, opt)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to infer schema, err: %v", err)
+	}
+	var keys []string
+	for _, f := range schema {
+		keys = append(keys, f.Key)
+	}
+	expectedKeys := []string{"a.b", "items[0]", "items[1]", "items[2]", "items[3]", "items[4]", "items[5]", "items[6]", "items[7]", "items[8]", "items[9]", "items[10]"}
+	if !reflect.DeepEqual(keys, expectedKeys) {
+		t.Fatalf("keys are incorrect, %v is not equal expected value %v", keys, expectedKeys)
+	}
+	if schema[0].Types[0] != SchemaTypeObject || schema[0].Examples[0] == nil || schema[0].MaxLength != len(`{"c":1}`) {
+		t.Fatalf("a.b should be an object, current: %+v", schema[0])
+	}
+}
+
+func TestInferSchema_Empty(t *testing.T) {
+	// Process
+	schema, err := InferSchema(nil, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to infer schema, err: %v", err)
+	}
+	if len(schema) != 0 {
+		t.Fatalf("schema should be empty, current: %+v", schema)
+	}
+}
+
+func TestInferSchema_KeyCollisionError(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{{"a": map[string]any{"b": 1}, "a__b": 2}}
+
+	// Process
+	_, err := InferSchema(arr, &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionError})
+
+	// Check
+	if !errors.Is(err, ErrFlattenCollision) {
+		t.Fatalf("It should throw ErrFlattenCollision, current: %v", err)
+	}
+}
+
+func TestSchemaTypeOf(t *testing.T) {
+	// Prepare
+	n := 1
+	var nilPtr *int
+	cases := map[string]any{
+		"":        nilPtr,
+		"string":  time.Now(),
+		"integer": &n,
+		"number":  float32(0.5),
+		"boolean": false,
+		"object":  struct{}{},
+		"array":   [2]int{},
+	}
+
+	for expected, val := range cases {
+		// Process
+		typ := schemaTypeOf(val)
+
+		// Check
+		if typ != expected {
+			t.Fatalf("type of %#v should be %q, current: %q", val, expected, typ)
+		}
+	}
+	if typ := schemaTypeOf(uint64(1 << 63)); typ != SchemaTypeInteger {
+		t.Fatalf("type of uint64 should be integer, current: %q", typ)
+	}
+}
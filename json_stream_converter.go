@@ -0,0 +1,148 @@
+package jsonconv
+
+import (
+	"io"
+)
+
+// DefaultCsvStreamSampleSize is the default number of JSON objects
+// buffered by ToCsvStream to detect CSV headers.
+const DefaultCsvStreamSampleSize = 1000
+
+// A ToCsvStreamOption converts a stream of JSON objects to CSV data.
+type ToCsvStreamOption struct {
+	ToCsvOption
+
+	// Fixed CSV headers. If set, headers are not detected from
+	// the JSON stream and BaseHeaders is ignored
+	Headers []string
+
+	// Number of JSON objects buffered to detect CSV headers when Headers is not set.
+	// Keys that first appear after these objects are not written.
+	// DefaultCsvStreamSampleSize is used if it is not positive
+	SampleSize int
+}
+
+// ToCsvStream reads JSON objects from r and writes them to w as CSV records,
+// one JSON object at a time (see JsonReader.Next), so the whole input never
+// has to be held in memory. Empty JSON objects are skipped. Unlike ToCsv,
+// it returns ErrFlattenCollision with KeyCollisionError.
+func ToCsvStream(r *JsonReader, w *CsvWriter, opt *ToCsvStreamOption) error {
+	if opt == nil {
+		opt = &ToCsvStreamOption{}
+	}
+	sampleSize := opt.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = DefaultCsvStreamSampleSize
+	}
+
+	// Read JSON objects with their key order if headers are ordered by it.
+	ordered := (opt.HeaderOrder == HeaderOrderFirstSeen || opt.HeaderOrder == HeaderOrderSource) &&
+		len(opt.Headers) == 0
+	read := func() (any, error) {
+		for {
+			if ordered {
+				obj, err := r.NextOrdered()
+				if err != nil || obj.Len() != 0 {
+					return obj, err
+				}
+				continue
+			}
+			obj, err := r.Next()
+			if err != nil || len(obj) != 0 {
+				return obj, err
+			}
+		}
+	}
+
+	// Explode and flatten JSON objects one at a time.
+	var pending []any
+	next := func() (map[string]any, []string, error) {
+		if len(pending) == 0 {
+			obj, err := read()
+			if err != nil {
+				return nil, nil, err
+			}
+			pending = []any{obj}
+			if len(opt.Explode) > 0 {
+				pending = explode(obj, opt.Explode, csvFlattenOption(&opt.ToCsvOption).Gap, opt.ExplodeMode)
+			}
+		}
+		row := pending[0]
+		pending = pending[1:]
+
+		if obj, ok := row.(*OrderedObject); ok {
+			if opt.FlattenOption != nil {
+				if err := FlattenOrdered(obj, opt.FlattenOption); err != nil {
+					return nil, nil, err
+				}
+			}
+			return obj.values, obj.keys, nil
+		}
+		obj := row.(map[string]any)
+		if opt.FlattenOption != nil {
+			if err := FlattenStrict(obj, opt.FlattenOption); err != nil {
+				return nil, nil, err
+			}
+		}
+		return obj, nil, nil
+	}
+
+	// Buffer the first objects to detect CSV headers.
+	hs := opt.Headers
+	var (
+		sample []map[string]any
+		keys   [][]string
+	)
+	if len(hs) == 0 {
+		for len(sample) < sampleSize {
+			obj, ks, err := next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			sample = append(sample, obj)
+			keys = append(keys, ks)
+		}
+		if len(sample) == 0 {
+			return nil
+		}
+		if ordered {
+			hs = selectCsvHeader(keys, &opt.ToCsvOption)
+		} else {
+			hs = createCsvHeaderWithOption(sample, &opt.ToCsvOption)
+		}
+	}
+
+	// Write CSV header and buffered records, then stream the rest.
+	wroteHeader := false
+	write := func(obj map[string]any) error {
+		if !wroteHeader {
+			if err := w.WriteRecord(renameCsvHeader(hs, &opt.ToCsvOption)); err != nil {
+				return err
+			}
+			wroteHeader = true
+		}
+		return w.WriteRecord(createCsvRow(obj, hs, &opt.ToCsvOption))
+	}
+	for _, obj := range sample {
+		if err := write(obj); err != nil {
+			return err
+		}
+	}
+	sample, keys = nil, nil
+	for {
+		obj, _, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := write(obj); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
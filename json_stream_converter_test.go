@@ -0,0 +1,263 @@
+package jsonconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestToCsvStream_EmptyInput(t *testing.T) {
+	// Prepare
+	inputs := []string{"", "  \n", "[]", "[{}, {}]"}
+
+	for _, raw := range inputs {
+		buf := &bytes.Buffer{}
+
+		// Process
+		err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(buf), nil)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to convert JSON stream %q, err: %v", raw, err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("It should write nothing for %q, current: %s", raw, buf.String())
+		}
+	}
+}
+
+func TestToCsvStream_InvalidJson(t *testing.T) {
+	// Prepare
+	inputs := []string{
+		`"id"`,
+		`[1, 2]`,
+		`[{"id": 1}, 2]`,
+		`{"id": 1} [1]`,
+		`[{"id": 1}`,
+		`{"id": `,
+	}
+
+	for _, raw := range inputs {
+		// Process
+		err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(&bytes.Buffer{}), nil)
+
+		// Check
+		if err == nil {
+			t.Fatalf("Should throw an error for invalid JSON %q", raw)
+		}
+	}
+}
+
+func TestToCsvStream_JsonArray(t *testing.T) {
+	// Prepare
+	raw := `
+	[
+		{"id": "b042ab5c-ca73-4460-b739-96410ea9d3a6", "user": "Jon Doe", "score": -100, "nested": {"a": 1, "b": [2, 3]}},
+		{},
+		{"id": "ce06f5b1-5721-42c0-91e1-9f72a09c250a", "user": "Tuấn", "score": 1.5, "is active": true}
+	]`
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(buf), &ToCsvStreamOption{
+		ToCsvOption: ToCsvOption{
+			FlattenOption: DefaultFlattenOption,
+			BaseHeaders:   []string{"user"},
+		},
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON stream, err: %v", err)
+	}
+	s := buf.String()
+	expected := `user,id,is active,nested__a,nested__b[0],nested__b[1],score
+Jon Doe,b042ab5c-ca73-4460-b739-96410ea9d3a6,,1,2,3,-100
+Tuấn,ce06f5b1-5721-42c0-91e1-9f72a09c250a,true,,,,1.5
+`
+	if s != expected {
+		t.Fatalf("csv output is incorrect, %s is not equal expected %s", s, expected)
+	}
+}
+
+func TestToCsvStream_JsonObjectSequence(t *testing.T) {
+	// Prepare
+	raw := `{"id": 1, "user": "Jon Doe"}
+{"id": 2, "user": "Tuấn", "score": 1.5}
+{"id": 3, "user": "高橋", "extra": true}`
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(buf), &ToCsvStreamOption{
+		SampleSize: 2,
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON stream, err: %v", err)
+	}
+	s := buf.String()
+	expected := `id,score,user
+1,,Jon Doe
+2,1.5,Tuấn
+3,,高橋
+`
+	if s != expected {
+		t.Fatalf("csv output is incorrect, %s is not equal expected %s", s, expected)
+	}
+}
+
+func TestToCsvStream_FixedHeaders(t *testing.T) {
+	// Prepare
+	raw := `[{"id": 1, "user": "Jon Doe", "nested": {"a": 1}}, {"id": 2, "score": 1.5}]`
+	buf := &bytes.Buffer{}
+	wr := NewCsvWriter(buf)
+	wr.Delimiter = '|'
+
+	// Process
+	err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), wr, &ToCsvStreamOption{
+		ToCsvOption: ToCsvOption{
+			FlattenOption: DefaultFlattenOption,
+			BaseHeaders:   []string{"ignored"},
+		},
+		Headers: []string{"nested__a", "id", "missing"},
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON stream, err: %v", err)
+	}
+	s := buf.String()
+	expected := `nested__a|id|missing
+1|1|
+|2|
+`
+	if s != expected {
+		t.Fatalf("csv output is incorrect, %s is not equal expected %s", s, expected)
+	}
+}
+
+func TestToCsvStream_InvalidDelimiter(t *testing.T) {
+	// Prepare
+	raw := `[{"id": 1}]`
+	wr := NewCsvWriter(&bytes.Buffer{})
+	wr.Delimiter = '\n'
+
+	// Process
+	err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), wr, nil)
+
+	// Check
+	if err == nil {
+		t.Fatalf("Should throw an error for invalid delimiter")
+	}
+}
+
+func TestToCsvStream_HeaderNames(t *testing.T) {
+	// Prepare
+	raw := `[{"id": 1, "customer": {"address": {"zip": "70000"}}}]`
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(buf), &ToCsvStreamOption{
+		ToCsvOption: ToCsvOption{
+			FlattenOption: DefaultFlattenOption,
+			HeaderNames:   map[string]string{"customer__address__zip": "ZIP"},
+		},
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert JSON stream, err: %v", err)
+	}
+	s := buf.String()
+	expected := "ZIP,id\n70000,1\n"
+	if s != expected {
+		t.Fatalf("csv output is incorrect, %s is not equal expected %s", s, expected)
+	}
+}
+
+func TestToCsvStream_HeaderOrder(t *testing.T) {
+	// Prepare
+	raw := `
+	{"id": 2, "user": "Tuấn", "meta": {"source": "web"}}
+	{"note": "gift", "id": 3}`
+	cases := map[HeaderOrder]string{
+		HeaderOrderAlphabetical: "id,meta__source,note,user",
+		HeaderOrderFirstSeen:    "id,user,meta__source,note",
+		HeaderOrderSource:       "note,id,user,meta__source",
+	}
+
+	for order, expected := range cases {
+		buf := &bytes.Buffer{}
+
+		// Process
+		err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(buf), &ToCsvStreamOption{
+			ToCsvOption: ToCsvOption{
+				FlattenOption: DefaultFlattenOption,
+				HeaderOrder:   order,
+			},
+		})
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to convert JSON stream, err: %v", err)
+		}
+		hs, _, _ := strings.Cut(buf.String(), "\n")
+		if hs != expected {
+			t.Fatalf("created headers are incorrect for order %v, %s is not equal expected %s", order, hs, expected)
+		}
+	}
+}
+
+func TestToCsvStream_Explode(t *testing.T) {
+	// Prepare
+	raw := `
+	{"id": 1, "items": [{"sku": "A1", "qty": 2}, {"sku": "B2"}], "tags": ["x", "y"]}
+	{"id": 2, "items": []}`
+
+	for _, order := range []HeaderOrder{HeaderOrderAlphabetical, HeaderOrderFirstSeen} {
+		buf := &bytes.Buffer{}
+
+		// Process
+		err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(buf), &ToCsvStreamOption{
+			ToCsvOption: ToCsvOption{
+				FlattenOption: DefaultFlattenOption,
+				Explode:       []string{"items", "tags"},
+				ExplodeMode:   ExplodeZip,
+				HeaderOrder:   order,
+			},
+			SampleSize: 1,
+		})
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to convert JSON stream, err: %v", err)
+		}
+		expected := map[HeaderOrder]string{
+			HeaderOrderAlphabetical: "id,items__qty,items__sku,tags\n1,2,A1,x\n1,,B2,y\n2,,,\n",
+			HeaderOrderFirstSeen:    "id,items__sku,items__qty,tags\n1,A1,2,x\n1,B2,,y\n2,,,\n",
+		}[order]
+		if buf.String() != expected {
+			t.Fatalf("It should write CSV data: %s\ncurrent: %s", expected, buf.String())
+		}
+	}
+}
+
+func TestToCsvStream_KeyCollisionError(t *testing.T) {
+	// Prepare
+	raw := `{"a": {"b": 1}, "a__b": 2}`
+	opt := &ToCsvStreamOption{
+		ToCsvOption: ToCsvOption{
+			FlattenOption: &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionError},
+		},
+	}
+
+	// Process
+	err := ToCsvStream(NewJsonReader(strings.NewReader(raw)), NewCsvWriter(&bytes.Buffer{}), opt)
+
+	// Check
+	expMsg := `colliding flattened keys: "a__b"`
+	if err == nil || err.Error() != expMsg {
+		t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+	}
+}
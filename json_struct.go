@@ -0,0 +1,326 @@
+package jsonconv
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// FlattenStruct flattens v, a struct or a pointer to a struct, like Flattened
+// does with a JSON object. Fields are named and omitted as encoding/json does,
+// following `json` struct tags ("-", "omitempty") and embedded structs.
+// If opt is nil, it will use opt value from DefaultFlattenOption instead.
+func FlattenStruct(v any, opt *FlattenOption) (map[string]any, error) {
+	refval := reflect.ValueOf(v)
+	for refval.Kind() == reflect.Pointer && !refval.IsNil() {
+		refval = refval.Elem()
+	}
+	if refval.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported type %T, need a struct or a pointer to a struct", v)
+	}
+	return Flattened(jsonValue(refval, false).(map[string]any), opt)
+}
+
+// toJsonObjects converts elements of arr to JSON objects (see jsonValue).
+// Nil elements are skipped, and elements which are not structs or maps are an error.
+func toJsonObjects[T any](arr []T) ([]map[string]any, error) {
+	objs := make([]map[string]any, 0, len(arr))
+	for i, e := range arr {
+		switch obj := jsonValue(reflect.ValueOf(e), false).(type) {
+		case nil:
+		case map[string]any:
+			objs = append(objs, obj)
+		case *OrderedObject:
+			objs = append(objs, obj.Map())
+		default:
+			return nil, fmt.Errorf("element %d: unsupported type %T, need a struct, a map or a pointer to one", i, e)
+		}
+	}
+	return objs, nil
+}
+
+// toOrderedJsonObjects is like toJsonObjects, keeping the order of struct fields.
+func toOrderedJsonObjects[T any](arr []T) ([]*OrderedObject, error) {
+	objs := make([]*OrderedObject, 0, len(arr))
+	for i, e := range arr {
+		switch obj := jsonValue(reflect.ValueOf(e), true).(type) {
+		case nil:
+		case *OrderedObject:
+			objs = append(objs, obj)
+		default:
+			return nil, fmt.Errorf("element %d: unsupported type %T, need a struct, a map or a pointer to one", i, e)
+		}
+	}
+	return objs, nil
+}
+
+// jsonValue converts v to the value decoded from its JSON encoding: structs and maps
+// become map[string]any, or *OrderedObject if ordered, slices and arrays become []any,
+// and pointers are dereferenced. Values implementing json.Marshaler or
+// encoding.TextMarshaler, []byte and other values are returned as is.
+func jsonValue(v reflect.Value, ordered bool) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if isMarshaler(v.Type()) {
+		return interfaceOf(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return jsonValue(v.Elem(), ordered)
+	case reflect.Struct:
+		fields := cachedStructFields(v.Type())
+		if ordered {
+			obj := NewOrderedObject()
+			eachStructField(v, fields, func(name string, fv reflect.Value) {
+				obj.Set(name, jsonValue(fv, ordered))
+			})
+			return obj
+		}
+		obj := make(map[string]any, len(fields))
+		eachStructField(v, fields, func(name string, fv reflect.Value) {
+			obj[name] = jsonValue(fv, ordered)
+		})
+		return obj
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		ks := make([]string, 0, v.Len())
+		vals := make(map[string]reflect.Value, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, ok := mapKeyString(iter.Key())
+			if !ok {
+				return interfaceOf(v)
+			}
+			ks = append(ks, k)
+			vals[k] = iter.Value()
+		}
+		if ordered {
+			sort.Strings(ks)
+			obj := NewOrderedObject()
+			for _, k := range ks {
+				obj.Set(k, jsonValue(vals[k], ordered))
+			}
+			return obj
+		}
+		obj := make(map[string]any, len(ks))
+		for _, k := range ks {
+			obj[k] = jsonValue(vals[k], ordered)
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && (v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8) {
+			if v.IsNil() {
+				return nil
+			}
+			return interfaceOf(v)
+		}
+		arr := make([]any, v.Len())
+		for i := range arr {
+			arr[i] = jsonValue(v.Index(i), ordered)
+		}
+		return arr
+	}
+	return interfaceOf(v)
+}
+
+// interfaceOf returns the value of v, which may be reached through an unexported
+// embedded struct. Such a value is converted to its basic type, or nil if it has none.
+func interfaceOf(v reflect.Value) any {
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	}
+	return nil
+}
+
+// isMarshaler reports whether t or a pointer to t implements json.Marshaler or encoding.TextMarshaler.
+func isMarshaler(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// mapKeyString returns k as a JSON object key, like encoding/json does.
+func mapKeyString(k reflect.Value) (string, bool) {
+	if k.Kind() == reflect.String {
+		return k.String(), true
+	}
+	if tm, ok := interfaceOf(k).(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err == nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	}
+	return "", false
+}
+
+// A structField is a field of a struct which is encoded to JSON.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	tagged    bool
+	depth     int
+}
+
+var structFieldsCache sync.Map // map[reflect.Type][]structField
+
+// cachedStructFields returns the fields of struct type t which are encoded to JSON, in order.
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	var fields []structField
+	collectStructFields(t, nil, 0, map[reflect.Type]bool{t: true}, &fields)
+	fields = dominantStructFields(fields)
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+// collectStructFields appends fields of struct type t at index and depth to fields,
+// including the fields of embedded structs. The visited types are not embedded again.
+func collectStructFields(t reflect.Type, index []int, depth int, visited map[reflect.Type]bool, fields *[]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		ft := sf.Type
+		if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if !sf.IsExported() && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(index[:len(index):len(index)], i)
+
+		// Fields of embedded structs without a name are promoted.
+		if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+			if !visited[ft] {
+				visited[ft] = true
+				collectStructFields(ft, idx, depth+1, visited, fields)
+				delete(visited, ft)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		field := structField{name: name, index: idx, tagged: name != "", depth: depth}
+		if name == "" {
+			field.name = sf.Name
+		}
+		for _, o := range strings.Split(opts, ",") {
+			if o == "omitempty" {
+				field.omitEmpty = true
+			}
+		}
+		*fields = append(*fields, field)
+	}
+}
+
+// dominantStructFields resolves fields with the same name like encoding/json does:
+// the shallowest one wins, then the tagged one. Others are dropped.
+func dominantStructFields(fields []structField) []structField {
+	byName := make(map[string][]structField)
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	res := make([]structField, 0, len(byName))
+	for _, fs := range byName {
+		sort.SliceStable(fs, func(i, j int) bool {
+			if fs[i].depth != fs[j].depth {
+				return fs[i].depth < fs[j].depth
+			}
+			return fs[i].tagged && !fs[j].tagged
+		})
+		if len(fs) > 1 && fs[0].depth == fs[1].depth && fs[0].tagged == fs[1].tagged {
+			continue
+		}
+		res = append(res, fs[0])
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i].index, res[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return res
+}
+
+// eachStructField calls fn with the name and value of every field of struct v
+// which is encoded to JSON. Fields of nil embedded pointers are skipped.
+func eachStructField(v reflect.Value, fields []structField, fn func(name string, fv reflect.Value)) {
+	for _, f := range fields {
+		fv := v
+		for i, x := range f.index {
+			if i > 0 && fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv = reflect.Value{}
+					break
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(x)
+		}
+		if !fv.IsValid() || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		fn(f.name, fv)
+	}
+}
+
+// isEmptyValue reports whether v is empty for the "omitempty" option of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
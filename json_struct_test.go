@@ -0,0 +1,261 @@
+package jsonconv
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type testBase struct {
+	ID      int       `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type testUser struct {
+	testBase
+	Name     string         `json:"name"`
+	Email    string         `json:"email,omitempty"`
+	Password string         `json:"-"`
+	Dash     string         `json:"-,"`
+	Address  *testAddress   `json:"address"`
+	Tags     []string       `json:"tags"`
+	Meta     map[string]int `json:"meta,omitempty"`
+	Labels   map[int]string `json:"labels,omitempty"`
+	Raw      []byte         `json:"raw,omitempty"`
+	Untagged bool
+	internal string
+}
+
+func sampleUser() testUser {
+	return testUser{
+		testBase: testBase{ID: 1, Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Name:     "Jon Doe",
+		Password: "secret",
+		Dash:     "-",
+		Address:  &testAddress{City: "HCM"},
+		Tags:     []string{"a", "b"},
+		Labels:   map[int]string{1: "x"},
+		internal: "hidden",
+	}
+}
+
+func TestFlattenStruct(t *testing.T) {
+	// Prepare
+	user := sampleUser()
+
+	// Process
+	flat, err := FlattenStruct(&user, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to flatten struct, err: %v", err)
+	}
+	expected := map[string]any{
+		"id":            1,
+		"created":       user.Created,
+		"name":          "Jon Doe",
+		"-":             "-",
+		"address__city": "HCM",
+		"tags[0]":       "a",
+		"tags[1]":       "b",
+		"labels__1":     "x",
+		"Untagged":      false,
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Fatalf("flattened struct is incorrect, %v is not equal expected value %v", flat, expected)
+	}
+
+	// Keys should match the ones of the JSON encoding.
+	b, _ := json.Marshal(user)
+	obj := make(map[string]any)
+	if err := json.Unmarshal(b, &obj); err != nil {
+		t.Fatalf("failed to unmarshal JSON, err: %v", err)
+	}
+	Flatten(obj, nil)
+	for k := range obj {
+		if _, exist := flat[k]; !exist {
+			t.Fatalf("flattened struct does not have key %s of its JSON encoding", k)
+		}
+	}
+}
+
+func TestFlattenStruct_UnsupportedType(t *testing.T) {
+	// Prepare
+	var nilUser *testUser
+	inputs := []any{nil, 1, "user", map[string]any{"id": 1}, nilUser}
+
+	for _, v := range inputs {
+		// Process
+		_, err := FlattenStruct(v, nil)
+
+		// Check
+		if err == nil || !strings.HasPrefix(err.Error(), "unsupported type") {
+			t.Fatalf("It should throw an error for %T, current: %v", v, err)
+		}
+	}
+}
+
+func TestFlattenStruct_EmbeddedConflicts(t *testing.T) {
+	// Prepare
+	type A struct {
+		X int
+		Y int `json:"y"`
+	}
+	type B struct {
+		X int
+		Y int
+	}
+	type C struct {
+		A
+		*B
+		Z int `json:"z"`
+	}
+	type D struct {
+		C
+		Z string `json:"z"`
+	}
+	v := D{C: C{A: A{X: 1, Y: 2}, Z: 3}, Z: "top"} // X is ambiguous, B is nil
+
+	// Process
+	flat, err := FlattenStruct(v, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to flatten struct, err: %v", err)
+	}
+	expected := map[string]any{"y": 2, "z": "top"}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Fatalf("flattened struct is incorrect, %v is not equal expected value %v", flat, expected)
+	}
+}
+
+func TestFlattenJsonObject_NestedStruct(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"user":  sampleUser(),
+		"addr":  &testAddress{City: "HN", Zip: "10000"},
+		"empty": (*testAddress)(nil),
+	}
+
+	// Process
+	Flatten(data, &FlattenOption{Level: FlattenLevelUnlimited, Gap: "."})
+
+	// Check
+	expected := map[string]any{
+		"user.id":           1,
+		"user.created":      sampleUser().Created,
+		"user.name":         "Jon Doe",
+		"user.-":            "-",
+		"user.address.city": "HCM",
+		"user.tags[0]":      "a",
+		"user.tags[1]":      "b",
+		"user.labels.1":     "x",
+		"user.Untagged":     false,
+		"addr.city":         "HN",
+		"addr.zip":          "10000",
+		"empty":             nil,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("flattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestToCsvStructs(t *testing.T) {
+	// Prepare
+	users := []testUser{sampleUser(), {Name: "Tuấn", Tags: []string{"c"}}}
+
+	// Process
+	data, err := ToCsvStructs(users, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		HeaderOrder:   HeaderOrderFirstSeen,
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert structs to CSV, err: %v", err)
+	}
+	expected := [][]string{
+		{"id", "created", "name", "-", "address__city", "tags[0]", "tags[1]", "labels__1", "Untagged", "address"},
+		{"1", "2024-01-02T03:04:05Z", "Jon Doe", "-", "HCM", "a", "b", "x", "false", ""},
+		{"0", "0001-01-01T00:00:00Z", "Tuấn", "", "", "c", "", "", "false", ""},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("CSV data is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestToCsvStructs_Pointers(t *testing.T) {
+	// Prepare
+	addrs := []*testAddress{{City: "HCM", Zip: "70000"}, nil, {City: "HN"}}
+
+	// Process
+	data, err := ToCsvStructs(addrs, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert structs to CSV, err: %v", err)
+	}
+	expected := [][]string{
+		{"city", "zip"},
+		{"HCM", "70000"},
+		{"HN", ""},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("CSV data is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestToCsvStructs_Explode(t *testing.T) {
+	// Prepare
+	type item struct {
+		Sku string `json:"sku"`
+	}
+	type order struct {
+		ID    string `json:"id"`
+		Items []item `json:"items"`
+	}
+	orders := []order{{ID: "o1", Items: []item{{Sku: "A1"}, {Sku: "B2"}}}}
+
+	// Process
+	data, err := ToCsvStructs(orders, &ToCsvOption{
+		FlattenOption: DefaultFlattenOption,
+		Explode:       []string{"items"},
+	})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to convert structs to CSV, err: %v", err)
+	}
+	expected := [][]string{
+		{"id", "items__sku"},
+		{"o1", "A1"},
+		{"o1", "B2"},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("CSV data is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestToCsvStructs_UnsupportedElement(t *testing.T) {
+	// Prepare
+	arr := []any{map[string]any{"id": 1}, nil, "x"}
+	cases := []*ToCsvOption{nil, {HeaderOrder: HeaderOrderSource}}
+
+	for _, opt := range cases {
+		// Process
+		_, err := ToCsvStructs(arr, opt)
+
+		// Check
+		expMsg := "element 2: unsupported type string, need a struct, a map or a pointer to one"
+		if err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
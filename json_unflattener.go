@@ -0,0 +1,261 @@
+package jsonconv
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnflattenConflict is returned by Unflatten when two flattened keys
+	// require different shapes (value, object or array) at the same path.
+	ErrUnflattenConflict = errors.New("conflicting flattened keys")
+
+	// ErrUnflattenSparseArray is returned by Unflatten when the array indices
+	// found under a path are not contiguous from 0.
+	ErrUnflattenSparseArray = errors.New("sparse array indices")
+)
+
+// unflatObj and unflatArr are intermediate containers used while rebuilding
+// nested data. They are distinct types so that map values which were never
+// flattened (e.g. because of FlattenOption.Level) are not mistaken for them.
+type (
+	unflatObj map[string]any
+	unflatArr map[int]any
+)
+
+// A keySegment is one step of a flattened key, either an object key or an array index.
+type keySegment struct {
+	key   string
+	idx   int
+	isIdx bool
+}
+
+// Unflatten rebuilds nested JSON objects and arrays from obj which was
+// flattened with given opt. If opt is nil, it will use opt value from
+// DefaultFlattenOption instead. The obj will be modified as result,
+// unless an error is returned, in which case obj is left untouched.
+func Unflatten(obj map[string]any, opt *FlattenOption) error {
+	if opt == nil {
+		opt = DefaultFlattenOption
+	}
+
+	// Sort keys so that reported errors are deterministic.
+	ks := make([]string, 0, len(obj))
+	for k := range obj {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	root := make(unflatObj)
+	for _, k := range ks {
+		if err := insertFlattened(root, parseFlattenedKey(k, opt), obj[k]); err != nil {
+			return fmt.Errorf("%w: %q", err, k)
+		}
+	}
+
+	res, err := buildUnflattened(root, "", opt.Gap)
+	if err != nil {
+		return err
+	}
+	clear(obj)
+	for k, v := range res.(map[string]any) {
+		obj[k] = v
+	}
+	return nil
+}
+
+// parseFlattenedKey splits k into segments by the gap of opt and the index syntax
+// produced by extract with the ArrayKeyStyle of opt, i.e. "[i]" or a gap followed by i.
+// With KeyCollisionEscape, escaped gaps and indices are kept in keys, which are unescaped.
+func parseFlattenedKey(k string, opt *FlattenOption) []keySegment {
+	escaped := opt.KeyCollision == KeyCollisionEscape
+	parts := []string{k}
+	if escaped {
+		parts = splitEscapedKey(k, opt.Gap)
+	} else if opt.Gap != "" {
+		parts = strings.Split(k, opt.Gap)
+	}
+	unescape := func(p string) string {
+		if escaped {
+			return UnescapeFlattenKey(p)
+		}
+		return p
+	}
+	base := 0
+	if opt.OneBasedIndex {
+		base = 1
+	}
+
+	segs := make([]keySegment, 0, len(parts))
+	for i, p := range parts {
+		if opt.ArrayKeyStyle == ArrayKeyGap {
+			if idx, ok := parseIndex(p); ok && i > 0 && idx >= base {
+				segs = append(segs, keySegment{idx: idx - base, isIdx: true})
+				continue
+			}
+			segs = append(segs, keySegment{key: unescape(p)})
+			continue
+		}
+
+		var idxs []int
+		for {
+			name, idx, ok := cutIndexSuffix(p)
+			if !ok || idx < base || (escaped && endsWithEscape(name)) {
+				break
+			}
+			p = name
+			idxs = append(idxs, idx-base)
+		}
+		segs = append(segs, keySegment{key: unescape(p)})
+		for i := len(idxs) - 1; i >= 0; i-- {
+			segs = append(segs, keySegment{idx: idxs[i], isIdx: true})
+		}
+	}
+	return segs
+}
+
+// endsWithEscape reports whether s ends with an escape character
+// which is not escaped itself, i.e. an odd number of them.
+func endsWithEscape(s string) bool {
+	n := len(s) - len(strings.TrimRight(s, string(flattenEscape)))
+	return n%2 == 1
+}
+
+// cutIndexSuffix cuts a trailing "[i]" from s, where i is a non-negative decimal integer.
+func cutIndexSuffix(s string) (string, int, bool) {
+	if !strings.HasSuffix(s, "]") {
+		return s, 0, false
+	}
+	open := strings.LastIndexByte(s, '[')
+	if open < 0 {
+		return s, 0, false
+	}
+	idx, ok := parseIndex(s[open+1 : len(s)-1])
+	if !ok {
+		return s, 0, false
+	}
+	return s[:open], idx, true
+}
+
+// parseIndex parses s as a non-negative decimal integer.
+func parseIndex(s string) (int, bool) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	return idx, true
+}
+
+// insertFlattened stores val at the path described by segs, creating intermediate containers as needed.
+func insertFlattened(root unflatObj, segs []keySegment, val any) error {
+	var cur any = root
+	for i, seg := range segs {
+		last := i == len(segs)-1
+
+		child, exist := getUnflatChild(cur, seg)
+		if last {
+			if exist {
+				return ErrUnflattenConflict
+			}
+			setUnflatChild(cur, seg, val)
+			return nil
+		}
+
+		next := segs[i+1]
+		if !exist {
+			if next.isIdx {
+				child = make(unflatArr)
+			} else {
+				child = make(unflatObj)
+			}
+			setUnflatChild(cur, seg, child)
+		}
+		switch child.(type) {
+		case unflatArr:
+			if !next.isIdx {
+				return ErrUnflattenConflict
+			}
+		case unflatObj:
+			if next.isIdx {
+				return ErrUnflattenConflict
+			}
+		default:
+			return ErrUnflattenConflict
+		}
+		cur = child
+	}
+	return nil
+}
+
+func getUnflatChild(container any, seg keySegment) (any, bool) {
+	var (
+		v     any
+		exist bool
+	)
+	switch c := container.(type) {
+	case unflatObj:
+		v, exist = c[seg.key]
+	case unflatArr:
+		v, exist = c[seg.idx]
+	}
+	return v, exist
+}
+
+func setUnflatChild(container any, seg keySegment, v any) {
+	switch c := container.(type) {
+	case unflatObj:
+		c[seg.key] = v
+	case unflatArr:
+		c[seg.idx] = v
+	}
+}
+
+// buildUnflattened converts intermediate containers into map[string]any and []any.
+// The path and gap are only used for error reporting.
+func buildUnflattened(v any, path string, gap string) (any, error) {
+	switch c := v.(type) {
+	case unflatObj:
+		// Keys are built in sorted order, so that the same error is reported every time.
+		ks := make([]string, 0, len(c))
+		for k := range c {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		obj := make(map[string]any, len(c))
+		for _, k := range ks {
+			nv := c[k]
+			np := k
+			if path != "" {
+				np = path + gap + k
+			}
+			res, err := buildUnflattened(nv, np, gap)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = res
+		}
+		return obj, nil
+	case unflatArr:
+		arr := make([]any, len(c))
+		for i := range arr {
+			nv, exist := c[i]
+			if !exist {
+				return nil, fmt.Errorf("%w: %q is missing index %d", ErrUnflattenSparseArray, path, i)
+			}
+			res, err := buildUnflattened(nv, fmt.Sprintf("%s[%d]", path, i), gap)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = res
+		}
+		return arr, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,273 @@
+package jsonconv
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func sampleDecodedObject(t *testing.T) map[string]any {
+	t.Helper()
+	raw := `
+	{
+		"id": "b042ab5c-ca73-4460-b739-96410ea9d3a6",
+		"user": "Jon Doe",
+		"score": -100,
+		"is active": false,
+		"nested": {
+			"a": 1,
+			"b": 2,
+			"c": {
+				"d": {
+					"e": 3
+				}
+			},
+			"f": [4, 5, [6, 7], {"x": "y"}],
+			"g": {
+				"h": "A",
+				"i": true,
+				"j": 1,
+				"k": 1.5,
+				"l": null
+			}
+		}
+	}`
+	obj := make(map[string]any)
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		t.Fatalf("failed to decode sample object, err: %v", err)
+	}
+	return obj
+}
+
+func TestUnflattenJsonObject_NilFlattenOption(t *testing.T) {
+	// Prepare
+	data := sampleDecodedObject(t)
+	Flatten(data, nil)
+
+	// Process
+	err := Unflatten(data, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unflatten JSON object, err: %v", err)
+	}
+	expected := sampleDecodedObject(t)
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("unflattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestUnflattenJsonObject_Gap(t *testing.T) {
+	// Prepare
+	opt := &FlattenOption{
+		Level: FlattenLevelUnlimited,
+		Gap:   "|",
+	}
+	data := sampleDecodedObject(t)
+	Flatten(data, opt)
+
+	// Process
+	err := Unflatten(data, opt)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unflatten JSON object, err: %v", err)
+	}
+	expected := sampleDecodedObject(t)
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("unflattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestUnflattenJsonObject_FirstLevel(t *testing.T) {
+	// Prepare
+	opt := &FlattenOption{
+		Level: 1,
+		Gap:   "|",
+	}
+	data := sampleDecodedObject(t)
+	Flatten(data, opt)
+
+	// Process
+	err := Unflatten(data, opt)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unflatten JSON object, err: %v", err)
+	}
+	expected := sampleDecodedObject(t)
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("unflattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestUnflattenJsonObject_SparseArray(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"id":      1,
+		"f[0]":    4,
+		"f[2]":    6,
+		"g__h[1]": "A",
+	}
+
+	// Process
+	err := Unflatten(data, nil)
+
+	// Check
+	if !errors.Is(err, ErrUnflattenSparseArray) {
+		t.Fatalf("It should throw a sparse array error, current: %v", err)
+	}
+	if len(data) != 4 || data["f[0]"] != 4 {
+		t.Fatalf("JSON object should be left untouched on error")
+	}
+	expMsg := `sparse array indices: "f" is missing index 1`
+	for range 20 {
+		if err := Unflatten(data, nil); err == nil || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+}
+
+func TestUnflattenJsonObject_Conflict(t *testing.T) {
+	// Prepare
+	cases := []map[string]any{
+		{"a": 1, "a__b": 2},
+		{"a[0]": 1, "a__b": 2},
+		{"a__b": 1, "a[0]": 2},
+		{"a[0]": 1, "a[0]__b": 2},
+	}
+
+	for _, data := range cases {
+		// Process
+		err := Unflatten(data, nil)
+
+		// Check
+		if !errors.Is(err, ErrUnflattenConflict) {
+			t.Fatalf("It should throw a conflict error for %v, current: %v", data, err)
+		}
+	}
+}
+
+func TestUnflattenJsonObject_NonIndexBrackets(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"a[x]":    1,
+		"b[]":     2,
+		"c[-1]":   3,
+		"d[0][1]": 4,
+		"d[0][0]": 5,
+	}
+
+	// Process
+	err := Unflatten(data, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unflatten JSON object, err: %v", err)
+	}
+	expected := map[string]any{
+		"a[x]":  1,
+		"b[]":   2,
+		"c[-1]": 3,
+		"d":     []any{[]any{5, 4}},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("unflattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestUnflattenJsonObject_ArrayKey(t *testing.T) {
+	// Prepare
+	opts := []*FlattenOption{
+		{Level: FlattenLevelUnlimited, Gap: "_", ArrayKeyStyle: ArrayKeyGap},
+		{Level: FlattenLevelUnlimited, Gap: "__", ArrayKeyStyle: ArrayKeyGap, OneBasedIndex: true},
+		{Level: FlattenLevelUnlimited, Gap: "|", OneBasedIndex: true},
+	}
+
+	for _, opt := range opts {
+		data := sampleDecodedObject(t)
+		Flatten(data, opt)
+
+		// Process
+		err := Unflatten(data, opt)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to unflatten JSON object, err: %v", err)
+		}
+		expected := sampleDecodedObject(t)
+		if !reflect.DeepEqual(data, expected) {
+			t.Fatalf("unflattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+		}
+	}
+}
+
+func TestUnflattenJsonObject_ArrayKey_NonIndex(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"0":      1,
+		"a__0":   2,
+		"b[0]":   3,
+		"c__x":   4,
+		"d__1__": 6,
+	}
+
+	// Process
+	err := Unflatten(data, &FlattenOption{Gap: "__", ArrayKeyStyle: ArrayKeyGap, OneBasedIndex: true})
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to unflatten JSON object, err: %v", err)
+	}
+	expected := map[string]any{
+		"0":    1,
+		"a":    map[string]any{"0": 2},
+		"b[0]": 3,
+		"c":    map[string]any{"x": 4},
+		"d":    []any{map[string]any{"": 6}},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("unflattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+	}
+}
+
+func TestUnflattenJsonObject_Escaped(t *testing.T) {
+	// Prepare
+	opts := []*FlattenOption{
+		{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionEscape},
+		{Level: FlattenLevelUnlimited, Gap: "_", ArrayKeyStyle: ArrayKeyGap, KeyCollision: KeyCollisionEscape},
+	}
+
+	for _, opt := range opts {
+		data := map[string]any{
+			"a__b": json.Number("1"),
+			"a":    map[string]any{"b": json.Number("2"), "0": json.Number("3")},
+			"f[0]": json.Number("4"),
+			"f":    []any{json.Number("5"), map[string]any{`x\`: "y"}},
+			"gap_": "z",
+			"é__ü": true,
+		}
+		expected := map[string]any{}
+		for k, v := range data {
+			expected[k] = v
+		}
+
+		// Process
+		if err := FlattenStrict(data, opt); err != nil {
+			t.Fatalf("failed to flatten JSON object, err: %v", err)
+		}
+		if len(data) != 8 {
+			t.Fatalf("flattened keys should not collide, current: %v", data)
+		}
+		err := Unflatten(data, opt)
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to unflatten JSON object, err: %v", err)
+		}
+		if !reflect.DeepEqual(data, expected) {
+			t.Fatalf("unflattened JSON object is incorrect, %v is not equal expected value %v", data, expected)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package jsonconv
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"io"
 )
@@ -13,7 +15,26 @@ type JsonWriter struct {
 	// to avoid certain safety problems that can arise when embedding JSON in HTML.
 	EscapeHTML bool
 
+	// Prefix and Indent make Write pretty-print JSON values. Each JSON element
+	// begins on a new line beginning with Prefix followed by one or more
+	// copies of Indent according to the indentation nesting.
+	// Both are ignored by WriteRecord, which always writes a single line.
+	Prefix string
+	Indent string
+
+	// SortKeys sorts keys of JSON objects alphabetically, including keys
+	// that come from struct fields or from types implementing json.Marshaler.
+	SortKeys bool
+
+	// Compact writes JSON values without insignificant whitespace,
+	// ignoring Prefix and Indent.
+	Compact bool
+
 	writer io.Writer
+
+	// buffer and encoder are created on the first WriteRecord call and reused until Flush.
+	buffer  *bufio.Writer
+	encoder *json.Encoder
 }
 
 // NewJsonWriter returns a new JsonWriter that writes to w.
@@ -27,7 +48,58 @@ func NewJsonWriter(w io.Writer) *JsonWriter {
 // Write writes the JSON encoding of v to the stream,
 // followed by a newline character.
 func (r *JsonWriter) Write(v any) error {
+	v, err := r.prepare(v)
+	if err != nil {
+		return err
+	}
 	encoder := json.NewEncoder(r.writer)
 	encoder.SetEscapeHTML(r.EscapeHTML)
+	if !r.Compact {
+		encoder.SetIndent(r.Prefix, r.Indent)
+	}
 	return encoder.Encode(v)
 }
+
+// WriteRecord writes the JSON encoding of v as a single line, followed by
+// a newline character, so that consecutive records form newline-delimited
+// JSON (JSON Lines). Records are buffered, so Flush must be called to
+// ensure they are written to the underlying writer.
+func (r *JsonWriter) WriteRecord(v any) error {
+	if r.encoder == nil {
+		r.buffer = bufio.NewWriter(r.writer)
+		r.encoder = json.NewEncoder(r.buffer)
+		r.encoder.SetEscapeHTML(r.EscapeHTML)
+	}
+	v, err := r.prepare(v)
+	if err != nil {
+		return err
+	}
+	return r.encoder.Encode(v)
+}
+
+// Flush writes any buffered records written by WriteRecord to the underlying writer.
+func (r *JsonWriter) Flush() error {
+	if r.buffer == nil {
+		return nil
+	}
+	return r.buffer.Flush()
+}
+
+// prepare returns v, or its re-decoded generic form when SortKeys is set,
+// since encoding/json sorts keys of maps but not of other JSON objects.
+func (r *JsonWriter) prepare(v any) (any, error) {
+	if !r.SortKeys {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var sorted any
+	if err := decoder.Decode(&sorted); err != nil {
+		return nil, err
+	}
+	return sorted, nil
+}
@@ -88,3 +88,169 @@ func TestJsonWriter_JsonArray(t *testing.T) {
 		t.Fatalf("json output is not correct")
 	}
 }
+
+func TestJsonWriter_WriteRecord(t *testing.T) {
+	// Prepare
+	data := []map[string]any{
+		{
+			"id":       "b042ab5c-ca73-4460-b739-96410ea9d3a6",
+			"user":     "Jon Doe",
+			"special1": "&",
+			"nested": map[string]any{
+				"a": 1,
+			},
+		},
+		{
+			"id":   "ce06f5b1-5721-42c0-91e1-9f72a09c250a",
+			"user": "Tuấn",
+		},
+	}
+	buf := &bytes.Buffer{}
+	wr := NewJsonWriter(buf)
+	wr.EscapeHTML = false
+
+	// Process
+	for _, obj := range data {
+		if err := wr.WriteRecord(obj); err != nil {
+			t.Fatalf("failed to write json record, err: %v", err)
+		}
+	}
+	err := wr.Flush()
+	if err != nil {
+		t.Fatalf("failed to flush json, err: %v", err)
+	}
+
+	// Check
+	s := buf.String()
+	expected := `{"id":"b042ab5c-ca73-4460-b739-96410ea9d3a6","nested":{"a":1},"special1":"&","user":"Jon Doe"}
+{"id":"ce06f5b1-5721-42c0-91e1-9f72a09c250a","user":"Tuấn"}
+`
+	if s != expected {
+		t.Fatalf("json output is not correct, current: %s", s)
+	}
+}
+
+func TestJsonWriter_WriteRecord_InvalidJson(t *testing.T) {
+	// Prepare
+	wr := NewJsonWriter(&bytes.Buffer{})
+
+	// Process
+	err := wr.WriteRecord(map[string]any{"fn": func() {}})
+
+	// Check
+	if err == nil {
+		t.Fatalf("Should throw an error for invalid JSON value")
+	}
+}
+
+func TestJsonWriter_Indent(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"id":     1,
+		"nested": map[string]any{"a": []int{1, 2}},
+	}
+	buf := &bytes.Buffer{}
+	wr := NewJsonWriter(buf)
+	wr.Prefix = "#"
+	wr.Indent = "  "
+
+	// Process
+	err := wr.Write(data)
+	if err != nil {
+		t.Fatalf("failed to write json, err: %v", err)
+	}
+
+	// Check
+	s := buf.String()
+	expected := `{
+#  "id": 1,
+#  "nested": {
+#    "a": [
+#      1,
+#      2
+#    ]
+#  }
+#}
+`
+	if s != expected {
+		t.Fatalf("json output is not correct, current: %s", s)
+	}
+}
+
+func TestJsonWriter_Compact(t *testing.T) {
+	// Prepare
+	data := map[string]any{
+		"id":     1,
+		"nested": map[string]any{"a": []int{1, 2}},
+	}
+	buf := &bytes.Buffer{}
+	wr := NewJsonWriter(buf)
+	wr.Indent = "\t"
+	wr.Compact = true
+
+	// Process
+	err := wr.Write(data)
+	if err != nil {
+		t.Fatalf("failed to write json, err: %v", err)
+	}
+
+	// Check
+	s := buf.String()
+	expected := `{"id":1,"nested":{"a":[1,2]}}
+`
+	if s != expected {
+		t.Fatalf("json output is not correct, current: %s", s)
+	}
+}
+
+func TestJsonWriter_SortKeys(t *testing.T) {
+	// Prepare
+	type user struct {
+		Name  string  `json:"name"`
+		ID    int     `json:"id"`
+		Score float64 `json:"score"`
+		Tag   string  `json:"<tag>"`
+	}
+	data := user{Name: "Jon Doe", ID: 12345678901234567, Score: 1.5, Tag: "&"}
+	buf := &bytes.Buffer{}
+	wr := NewJsonWriter(buf)
+	wr.EscapeHTML = false
+	wr.SortKeys = true
+
+	// Process
+	err := wr.Write(data)
+	if err != nil {
+		t.Fatalf("failed to write json, err: %v", err)
+	}
+	err = wr.WriteRecord(data)
+	if err != nil {
+		t.Fatalf("failed to write json record, err: %v", err)
+	}
+	err = wr.Flush()
+	if err != nil {
+		t.Fatalf("failed to flush json, err: %v", err)
+	}
+
+	// Check
+	s := buf.String()
+	expected := `{"<tag>":"&","id":12345678901234567,"name":"Jon Doe","score":1.5}
+{"<tag>":"&","id":12345678901234567,"name":"Jon Doe","score":1.5}
+`
+	if s != expected {
+		t.Fatalf("json output is not correct, current: %s", s)
+	}
+}
+
+func TestJsonWriter_SortKeys_InvalidJson(t *testing.T) {
+	// Prepare
+	wr := NewJsonWriter(&bytes.Buffer{})
+	wr.SortKeys = true
+
+	// Process
+	err := wr.Write(map[string]any{"fn": func() {}})
+
+	// Check
+	if err == nil {
+		t.Fatalf("Should throw an error for invalid JSON value")
+	}
+}
@@ -0,0 +1,200 @@
+package jsonconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// An OrderedObject is a JSON object which keeps its keys in insertion order,
+// e.g. the order in which they appear in the source JSON (see JsonReader.NextOrdered).
+// Nested JSON objects decoded along with it are *OrderedObject as well.
+// The zero value is an empty object ready to use.
+type OrderedObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewOrderedObject returns a new empty OrderedObject.
+func NewOrderedObject() *OrderedObject {
+	return &OrderedObject{values: make(map[string]any)}
+}
+
+// Len returns the number of keys in o.
+func (o *OrderedObject) Len() int {
+	return len(o.keys)
+}
+
+// Keys returns the keys of o in order.
+func (o *OrderedObject) Keys() []string {
+	return append([]string(nil), o.keys...)
+}
+
+// Get returns the value of k and whether it exists.
+func (o *OrderedObject) Get(k string) (any, bool) {
+	val, exist := o.values[k]
+	return val, exist
+}
+
+// Set sets the value of k. A new key is appended after the existing ones,
+// an existing key keeps its position.
+func (o *OrderedObject) Set(k string, val any) {
+	if o.values == nil {
+		o.values = make(map[string]any)
+	}
+	if _, exist := o.values[k]; !exist {
+		o.keys = append(o.keys, k)
+	}
+	o.values[k] = val
+}
+
+// Delete removes k from o.
+func (o *OrderedObject) Delete(k string) {
+	if _, exist := o.values[k]; !exist {
+		return
+	}
+	delete(o.values, k)
+	for i, key := range o.keys {
+		if key == k {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Map converts o to map[string]any, along with nested OrderedObject values.
+func (o *OrderedObject) Map() map[string]any {
+	obj := make(map[string]any, len(o.keys))
+	for _, k := range o.keys {
+		obj[k] = unorderValue(o.values[k])
+	}
+	return obj
+}
+
+// unorderValue converts OrderedObject values nested in val to map[string]any.
+func unorderValue(val any) any {
+	switch v := val.(type) {
+	case *OrderedObject:
+		if v == nil {
+			return nil
+		}
+		return v.Map()
+	case []any:
+		arr := make([]any, len(v))
+		for i, nv := range v {
+			arr[i] = unorderValue(nv)
+		}
+		return arr
+	}
+	return val
+}
+
+// MarshalJSON encodes o as a JSON object with keys in order.
+func (o OrderedObject) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	// HTML escaping is left to the encoder which calls MarshalJSON.
+	encoder.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encoder.Encode(k); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := encoder.Encode(o.values[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object into o, keeping the order of its keys.
+func (o *OrderedObject) UnmarshalJSON(data []byte) error {
+	val, err := decodeOrderedValue(json.NewDecoder(bytes.NewReader(data)))
+	if err != nil {
+		return err
+	}
+	obj, ok := val.(*OrderedObject)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal %T into OrderedObject", val)
+	}
+	*o = *obj
+	return nil
+}
+
+// decodeOrderedValue decodes the next JSON value from decoder token by token,
+// so that keys of JSON objects are kept in order. It returns io.EOF
+// only if there is no JSON value left.
+func decodeOrderedValue(decoder *json.Decoder) (any, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	return decodeOrderedToken(decoder, tok)
+}
+
+// decodeOrderedToken decodes the JSON value starting with tok.
+func decodeOrderedToken(decoder *json.Decoder, tok json.Token) (any, error) {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	// Inside a JSON object or array, the end of input is unexpected.
+	next := func() (json.Token, error) {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return tok, err
+	}
+
+	switch delim {
+	case '{':
+		obj := NewOrderedObject()
+		for decoder.More() {
+			tok, err := next()
+			if err != nil {
+				return nil, err
+			}
+			k, _ := tok.(string)
+			if tok, err = next(); err != nil {
+				return nil, err
+			}
+			val, err := decodeOrderedToken(decoder, tok)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(k, val)
+		}
+		// Consume the closing brace.
+		if _, err := next(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	default:
+		arr := make([]any, 0)
+		for decoder.More() {
+			tok, err := next()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeOrderedToken(decoder, tok)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		// Consume the closing bracket.
+		if _, err := next(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+}
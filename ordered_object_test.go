@@ -0,0 +1,77 @@
+package jsonconv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOrderedObject_SetAndDelete(t *testing.T) {
+	// Prepare
+	obj := &OrderedObject{}
+
+	// Process
+	obj.Set("b", 1)
+	obj.Set("a", 2)
+	obj.Set("c", 3)
+	obj.Set("b", 4)
+	obj.Delete("a")
+	obj.Delete("x")
+
+	// Check
+	expected := []string{"b", "c"}
+	if !reflect.DeepEqual(obj.Keys(), expected) {
+		t.Fatalf("keys are incorrect, %v is not equal expected value %v", obj.Keys(), expected)
+	}
+	if val, exist := obj.Get("b"); !exist || val != 4 {
+		t.Fatalf("value of key b is incorrect, current: %v", val)
+	}
+	if obj.Len() != 2 {
+		t.Fatalf("length is incorrect, current: %v", obj.Len())
+	}
+}
+
+func TestOrderedObject_JsonRoundTrip(t *testing.T) {
+	// Prepare
+	raw := `{"z":1,"a":{"y":"<b>","b":[{"d":null,"c":true}]},"m":[]}`
+	obj := &OrderedObject{}
+
+	// Process
+	err := json.Unmarshal([]byte(raw), obj)
+	if err != nil {
+		t.Fatalf("failed to unmarshal ordered object, err: %v", err)
+	}
+	encoded, err := json.Marshal(obj)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to marshal ordered object, err: %v", err)
+	}
+	expected := `{"z":1,"a":{"y":"\u003cb\u003e","b":[{"d":null,"c":true}]},"m":[]}`
+	if string(encoded) != expected {
+		t.Fatalf("It should keep key order: %s\ncurrent: %s", expected, encoded)
+	}
+	expMap := map[string]any{
+		"z": 1.0,
+		"a": map[string]any{"y": "<b>", "b": []any{map[string]any{"d": nil, "c": true}}},
+		"m": []any{},
+	}
+	if !reflect.DeepEqual(obj.Map(), expMap) {
+		t.Fatalf("converted map is incorrect, %v is not equal expected value %v", obj.Map(), expMap)
+	}
+}
+
+func TestOrderedObject_UnmarshalJson_Invalid(t *testing.T) {
+	// Prepare
+	cases := []string{`[1, 2]`, `{"a": 1`, `{"a" 1}`}
+
+	for _, raw := range cases {
+		// Process
+		err := json.Unmarshal([]byte(raw), &OrderedObject{})
+
+		// Check
+		if err == nil {
+			t.Fatalf("It should throw an error for %s", raw)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package jsonconv
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// A thriftStruct is a Thrift struct encoded with the compact protocol, which Parquet
+// uses for its metadata. Its fields must be in ascending order of id.
+type thriftStruct []thriftField
+
+// A thriftField is a field of a thriftStruct. Its value is an int32, an int64, a string,
+// a thriftStruct, or a list of one of them ([]int32, []string or []thriftStruct).
+type thriftField struct {
+	id    int16
+	value any
+}
+
+// Types of the Thrift compact protocol.
+const (
+	thriftTypeI32    = 5
+	thriftTypeI64    = 6
+	thriftTypeBinary = 8
+	thriftTypeList   = 9
+	thriftTypeStruct = 12
+)
+
+// encode appends s to b.
+func (s thriftStruct) encode(b []byte) []byte {
+	var last int16
+	for _, f := range s {
+		typ, ok := thriftTypeOf(f.value)
+		if !ok {
+			panic(fmt.Sprintf("unsupported thrift value %T", f.value))
+		}
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			b = append(b, byte(delta)<<4|typ)
+		} else {
+			b = append(b, typ)
+			b = binary.AppendVarint(b, int64(f.id))
+		}
+		last = f.id
+		b = thriftEncodeValue(b, f.value)
+	}
+	return append(b, 0)
+}
+
+// thriftTypeOf returns the Thrift type of v.
+func thriftTypeOf(v any) (byte, bool) {
+	switch v.(type) {
+	case int32:
+		return thriftTypeI32, true
+	case int64:
+		return thriftTypeI64, true
+	case string:
+		return thriftTypeBinary, true
+	case thriftStruct:
+		return thriftTypeStruct, true
+	case []int32, []string, []thriftStruct:
+		return thriftTypeList, true
+	}
+	return 0, false
+}
+
+// thriftEncodeValue appends v, without a field header, to b.
+func thriftEncodeValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case int32:
+		return binary.AppendVarint(b, int64(v))
+	case int64:
+		return binary.AppendVarint(b, v)
+	case string:
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		return append(b, v...)
+	case thriftStruct:
+		return v.encode(b)
+	case []int32:
+		b = thriftListHeader(b, thriftTypeI32, len(v))
+		for _, e := range v {
+			b = thriftEncodeValue(b, e)
+		}
+	case []string:
+		b = thriftListHeader(b, thriftTypeBinary, len(v))
+		for _, e := range v {
+			b = thriftEncodeValue(b, e)
+		}
+	case []thriftStruct:
+		b = thriftListHeader(b, thriftTypeStruct, len(v))
+		for _, e := range v {
+			b = e.encode(b)
+		}
+	}
+	return b
+}
+
+// thriftListHeader appends the header of a list of n elements of type typ to b.
+func thriftListHeader(b []byte, typ byte, n int) []byte {
+	if n < 15 {
+		return append(b, byte(n)<<4|typ)
+	}
+	b = append(b, 0xf0|typ)
+	return binary.AppendUvarint(b, uint64(n))
+}
@@ -0,0 +1,130 @@
+package jsonconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// decodeThriftStruct decodes a Thrift struct of the compact protocol from b, returning its
+// fields by id and the number of bytes read. Integers are int64, binaries are strings,
+// lists are []any and structs are map[int16]any.
+func decodeThriftStruct(b []byte) (map[int16]any, int, error) {
+	fields := make(map[int16]any)
+	var last int16
+	i := 0
+	for {
+		if i >= len(b) {
+			return nil, 0, fmt.Errorf("unexpected end of struct")
+		}
+		header := b[i]
+		i++
+		if header == 0 {
+			return fields, i, nil
+		}
+		typ := header & 0x0f
+		if delta := int16(header >> 4); delta > 0 {
+			last += delta
+		} else {
+			id, n := binary.Varint(b[i:])
+			if n <= 0 {
+				return nil, 0, fmt.Errorf("invalid field id")
+			}
+			i += n
+			last = int16(id)
+		}
+		val, n, err := decodeThriftValue(b[i:], typ)
+		if err != nil {
+			return nil, 0, fmt.Errorf("field %d: %v", last, err)
+		}
+		i += n
+		fields[last] = val
+	}
+}
+
+// decodeThriftValue decodes a value of type typ from b, returning the number of bytes read.
+func decodeThriftValue(b []byte, typ byte) (any, int, error) {
+	switch typ {
+	case thriftTypeI32, thriftTypeI64:
+		v, n := binary.Varint(b)
+		if n <= 0 {
+			return nil, 0, fmt.Errorf("invalid integer")
+		}
+		return v, n, nil
+	case thriftTypeBinary:
+		l, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < l {
+			return nil, 0, fmt.Errorf("invalid binary")
+		}
+		return string(b[n : n+int(l)]), n + int(l), nil
+	case thriftTypeList:
+		if len(b) == 0 {
+			return nil, 0, fmt.Errorf("invalid list")
+		}
+		size, elemType, i := int(b[0]>>4), b[0]&0x0f, 1
+		if size == 15 {
+			l, n := binary.Uvarint(b[1:])
+			if n <= 0 {
+				return nil, 0, fmt.Errorf("invalid list size")
+			}
+			size, i = int(l), 1+n
+		}
+		list := make([]any, 0, size)
+		for range size {
+			val, n, err := decodeThriftValue(b[i:], elemType)
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, val)
+			i += n
+		}
+		return list, i, nil
+	case thriftTypeStruct:
+		return decodeThriftStruct(b)
+	}
+	return nil, 0, fmt.Errorf("unsupported type %d", typ)
+}
+
+func TestThriftStruct(t *testing.T) {
+	// Prepare
+	s := thriftStruct{
+		{1, int32(1)},
+		{3, "ab"},
+		{20, int64(-1)},
+		{21, []int32{1, 2}},
+		{22, thriftStruct{}},
+	}
+
+	// Process
+	b := s.encode(nil)
+
+	// Check
+	expected := []byte{0x15, 0x02, 0x28, 0x02, 'a', 'b', 0x06, 0x28, 0x01, 0x19, 0x25, 0x02, 0x04, 0x1c, 0x00, 0x00}
+	if !bytes.Equal(b, expected) {
+		t.Fatalf("thrift struct is encoded incorrectly, % x is not equal expected value % x", b, expected)
+	}
+}
+
+func TestThriftStruct_LongList(t *testing.T) {
+	// Prepare
+	names := make([]string, 20)
+	for i := range names {
+		names[i] = fmt.Sprint(i)
+	}
+
+	// Process
+	b := thriftStruct{{1, names}}.encode(nil)
+	fields, n, err := decodeThriftStruct(b)
+
+	// Check
+	if err != nil || n != len(b) {
+		t.Fatalf("failed to decode thrift struct, err: %v", err)
+	}
+	if b[1] != 0xf8 || b[2] != 20 {
+		t.Fatalf("long list should have a size after its header, current: % x", b[1:3])
+	}
+	if list := fields[1].([]any); len(list) != 20 || list[19] != "19" {
+		t.Fatalf("list is decoded incorrectly, current: %v", list)
+	}
+}
@@ -0,0 +1,633 @@
+package jsonconv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A ParquetType is the type of the values of a Parquet column.
+type ParquetType int
+
+const (
+	// UTF-8 strings (BYTE_ARRAY)
+	ParquetString ParquetType = iota
+
+	// 64-bit signed integers (INT64)
+	ParquetInt64
+
+	// 64-bit floating point numbers (DOUBLE)
+	ParquetDouble
+
+	// Booleans (BOOLEAN)
+	ParquetBool
+)
+
+// String returns the name of t, e.g. "int64".
+func (t ParquetType) String() string {
+	switch t {
+	case ParquetString:
+		return "string"
+	case ParquetInt64:
+		return "int64"
+	case ParquetDouble:
+		return "double"
+	case ParquetBool:
+		return "bool"
+	}
+	return fmt.Sprintf("ParquetType(%d)", int(t))
+}
+
+// A ParquetColumn is a column of a Parquet file. Every column is optional (nullable).
+type ParquetColumn struct {
+	Name string
+	Type ParquetType
+}
+
+// A ParquetCompression is the compression codec of the column data of a Parquet file.
+type ParquetCompression int
+
+const (
+	ParquetUncompressed ParquetCompression = iota
+	ParquetGzip
+)
+
+// ParseParquetCompression parses a Parquet compression codec: none (or uncompressed) or gzip.
+func ParseParquetCompression(s string) (ParquetCompression, error) {
+	switch s {
+	case "", "none", "uncompressed":
+		return ParquetUncompressed, nil
+	case "gzip":
+		return ParquetGzip, nil
+	}
+	return ParquetUncompressed, fmt.Errorf("unsupported parquet compression %q", s)
+}
+
+// A ParquetWriter writes rows of flattened JSON objects to w as a Parquet file.
+// Close must be called after the last rows to complete the file.
+type ParquetWriter struct {
+	writer    io.Writer
+	offset    int64
+	numRows   int64
+	rowGroups []thriftStruct
+	closed    bool
+
+	// Columns of the Parquet file. If it is nil, it is inferred
+	// from the rows of the first Write (see InferParquetSchema)
+	Schema []ParquetColumn
+
+	// Compression codec of column data
+	Compression ParquetCompression
+
+	// Maximum number of rows of a row group. If it is not positive,
+	// the rows of every Write are written as one row group
+	RowGroupSize int
+}
+
+// Physical types, repetition types, converted types, encodings and codecs of
+// the Parquet format (see parquet.thrift).
+const (
+	parquetTypeBoolean   = 0
+	parquetTypeInt64     = 2
+	parquetTypeDouble    = 5
+	parquetTypeByteArray = 6
+
+	parquetOptional = 1
+	parquetUtf8     = 0
+
+	parquetEncodingPlain = 0
+	parquetEncodingRle   = 3
+
+	parquetCodecUncompressed = 0
+	parquetCodecGzip         = 2
+)
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// NewParquetWriter returns a new ParquetWriter that writes to w with schema,
+// which is inferred from the first rows if it is nil.
+func NewParquetWriter(w io.Writer, schema []ParquetColumn) *ParquetWriter {
+	return &ParquetWriter{writer: w, Schema: schema}
+}
+
+// Write writes rows of flattened JSON objects (see Flatten), keyed by the column names
+// of Schema. A missing key or a nil value is null, and keys which are not columns
+// are ignored. Every value must fit the type of its column; any value can be
+// written to a string column, formatted by FormatCsvValue.
+func (w *ParquetWriter) Write(rows []map[string]any) error {
+	if w.closed {
+		return errors.New("parquet writer is closed")
+	}
+	if w.Schema == nil && len(rows) > 0 {
+		w.Schema = InferParquetSchema(rows)
+	}
+	keys := make([]string, len(w.Schema))
+	for i, col := range w.Schema {
+		keys[i] = col.Name
+	}
+	return w.writeRows(rows, keys)
+}
+
+// writeRows writes rows as row groups, reading the values of the i-th column from keys[i].
+func (w *ParquetWriter) writeRows(rows []map[string]any, keys []string) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	size := w.RowGroupSize
+	if size <= 0 {
+		size = max(len(rows), 1)
+	}
+	for i := 0; i < len(rows); i += size {
+		if err := w.writeRowGroup(rows[i:min(i+size, len(rows))], keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start checks Schema and writes the magic number at the beginning of the file.
+func (w *ParquetWriter) start() error {
+	if w.offset > 0 {
+		return nil
+	}
+	names := make(map[string]struct{}, len(w.Schema))
+	for _, col := range w.Schema {
+		if _, exist := names[col.Name]; exist {
+			return fmt.Errorf("duplicate parquet column %q", col.Name)
+		}
+		if col.Type < ParquetString || col.Type > ParquetBool {
+			return fmt.Errorf("unsupported type %v of parquet column %q", col.Type, col.Name)
+		}
+		names[col.Name] = struct{}{}
+	}
+	return w.write([]byte(parquetMagic))
+}
+
+// write writes b to the underlying writer and advances the offset.
+func (w *ParquetWriter) write(b []byte) error {
+	n, err := w.writer.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+// writeRowGroup writes rows as a row group with a column chunk of one data page per column.
+func (w *ParquetWriter) writeRowGroup(rows []map[string]any, keys []string) error {
+	// Encode every column before writing, so that an invalid value writes nothing.
+	pages := make([][]byte, len(w.Schema))
+	for i, col := range w.Schema {
+		data, err := encodeParquetPage(col, rows, keys[i])
+		if err != nil {
+			return err
+		}
+		pages[i] = data
+	}
+
+	codec := int32(parquetCodecUncompressed)
+	if w.Compression == ParquetGzip {
+		codec = parquetCodecGzip
+	}
+	var totalSize int64
+	chunks := make([]thriftStruct, 0, len(w.Schema))
+	for i, col := range w.Schema {
+		compressed, err := compressParquetPage(pages[i], w.Compression)
+		if err != nil {
+			return err
+		}
+		if len(pages[i]) > math.MaxInt32 || len(compressed) > math.MaxInt32 {
+			return fmt.Errorf("parquet column %q is too large for a page, reduce the row group size", col.Name)
+		}
+		header := thriftStruct{
+			{1, int32(0)}, // DATA_PAGE
+			{2, int32(len(pages[i]))},
+			{3, int32(len(compressed))},
+			{5, thriftStruct{
+				{1, int32(len(rows))},
+				{2, int32(parquetEncodingPlain)},
+				{3, int32(parquetEncodingRle)},
+				{4, int32(parquetEncodingRle)},
+			}},
+		}.encode(nil)
+
+		offset := w.offset
+		if err := w.write(header); err != nil {
+			return err
+		}
+		if err := w.write(compressed); err != nil {
+			return err
+		}
+		uncompressedSize := int64(len(header) + len(pages[i]))
+		totalSize += uncompressedSize
+		chunks = append(chunks, thriftStruct{
+			{2, offset},
+			{3, thriftStruct{
+				{1, parquetPhysicalType(col.Type)},
+				{2, []int32{parquetEncodingPlain, parquetEncodingRle}},
+				{3, []string{col.Name}},
+				{4, codec},
+				{5, int64(len(rows))},
+				{6, uncompressedSize},
+				{7, int64(len(header) + len(compressed))},
+				{9, offset},
+			}},
+		})
+	}
+
+	w.rowGroups = append(w.rowGroups, thriftStruct{
+		{1, chunks},
+		{2, totalSize},
+		{3, int64(len(rows))},
+	})
+	w.numRows += int64(len(rows))
+	return nil
+}
+
+// Close writes the metadata of the file, which lists its schema and row groups.
+// It does not close the underlying writer.
+func (w *ParquetWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	schema := make([]thriftStruct, 0, len(w.Schema)+1)
+	schema = append(schema, thriftStruct{
+		{4, "schema"},
+		{5, int32(len(w.Schema))},
+	})
+	for _, col := range w.Schema {
+		elem := thriftStruct{
+			{1, parquetPhysicalType(col.Type)},
+			{3, int32(parquetOptional)},
+			{4, col.Name},
+		}
+		if col.Type == ParquetString {
+			elem = append(elem,
+				thriftField{6, int32(parquetUtf8)},
+				thriftField{10, thriftStruct{{1, thriftStruct{}}}}, // LogicalType STRING
+			)
+		}
+		schema = append(schema, elem)
+	}
+	footer := thriftStruct{
+		{1, int32(1)},
+		{2, schema},
+		{3, w.numRows},
+		{4, w.rowGroups},
+		{6, "jsonconv"},
+	}.encode(nil)
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	return w.write(append(footer, parquetMagic...))
+}
+
+// parquetPhysicalType returns the physical type of the values of type t.
+func parquetPhysicalType(t ParquetType) int32 {
+	switch t {
+	case ParquetInt64:
+		return parquetTypeInt64
+	case ParquetDouble:
+		return parquetTypeDouble
+	case ParquetBool:
+		return parquetTypeBoolean
+	}
+	return parquetTypeByteArray
+}
+
+// encodeParquetPage encodes the values of key in rows as the uncompressed data of
+// a data page of col: the definition levels of values, then the non-null values.
+func encodeParquetPage(col ParquetColumn, rows []map[string]any, key string) ([]byte, error) {
+	present := make([]bool, len(rows))
+	var values []byte
+	var bits []bool
+	for i, obj := range rows {
+		val := indirectValue(obj[key])
+		if val == nil {
+			continue
+		}
+		present[i] = true
+
+		ok := true
+		switch col.Type {
+		case ParquetString:
+			s, isStr := val.(string)
+			if !isStr {
+				s = FormatCsvValue(val, "")
+			}
+			values = binary.LittleEndian.AppendUint32(values, uint32(len(s)))
+			values = append(values, s...)
+		case ParquetInt64:
+			var n int64
+			if n, ok = parquetInt64(val); ok {
+				values = binary.LittleEndian.AppendUint64(values, uint64(n))
+			}
+		case ParquetDouble:
+			var f float64
+			if f, ok = parquetDouble(val); ok {
+				values = binary.LittleEndian.AppendUint64(values, math.Float64bits(f))
+			}
+		case ParquetBool:
+			var b bool
+			if b, ok = val.(bool); ok {
+				bits = append(bits, b)
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot write %s to %v parquet column %q", FormatCsvValue(val, ""), col.Type, col.Name)
+		}
+	}
+
+	// Booleans are bit-packed, starting from the least significant bit.
+	if col.Type == ParquetBool {
+		values = make([]byte, (len(bits)+7)/8)
+		for i, b := range bits {
+			if b {
+				values[i/8] |= 1 << (i % 8)
+			}
+		}
+	}
+
+	levels := encodeParquetLevels(present)
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	data = append(data, levels...)
+	return append(data, values...), nil
+}
+
+// encodeParquetLevels encodes definition levels with the RLE hybrid encoding of
+// bit width 1, where a level is 1 for a present value and 0 for null.
+func encodeParquetLevels(present []bool) []byte {
+	var b []byte
+	for i := 0; i < len(present); {
+		j := i + 1
+		for j < len(present) && present[j] == present[i] {
+			j++
+		}
+		b = binary.AppendUvarint(b, uint64(j-i)<<1)
+		if present[i] {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		i = j
+	}
+	return b
+}
+
+// compressParquetPage compresses the data of a page with c.
+func compressParquetPage(data []byte, c ParquetCompression) ([]byte, error) {
+	if c != ParquetGzip {
+		return data, nil
+	}
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	if _, err := gw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// A ToParquetOption converts a JSON array to a Parquet file.
+type ToParquetOption struct {
+	// Options to flatten JSON objects and to select, order and rename their
+	// columns like CSV headers. NullValue and FormatValue are ignored
+	ToCsvOption
+
+	// Compression codec of column data
+	Compression ParquetCompression
+
+	// Maximum number of rows of a row group (see ParquetWriter)
+	RowGroupSize int
+}
+
+// ToParquet writes a JSON array to w as a Parquet file with given opt. JSON objects are
+// exploded and flattened like ToCsv does, and the file has a column of every CSV header,
+// typed by the values of the column (see InferParquetSchema). Like ToCsvStrict,
+// it returns ErrFlattenCollision with KeyCollisionError.
+func ToParquet(w io.Writer, arr []map[string]any, opt *ToParquetOption) error {
+	var csvOpt *ToCsvOption
+	if opt != nil {
+		csvOpt = &opt.ToCsvOption
+	}
+	hs, rows, err := csvRecords(arr, csvOpt)
+	if err != nil {
+		return err
+	}
+	return writeParquet(w, hs, rows, opt)
+}
+
+// ToParquetStructs is like ToParquet, but arr is a slice of structs, pointers to structs
+// or maps (see ToCsvStructs).
+func ToParquetStructs[T any](w io.Writer, arr []T, opt *ToParquetOption) error {
+	if opt != nil && (opt.HeaderOrder == HeaderOrderFirstSeen || opt.HeaderOrder == HeaderOrderSource) {
+		objs, err := toOrderedJsonObjects(arr)
+		if err != nil {
+			return err
+		}
+		return ToParquetOrdered(w, objs, opt)
+	}
+	objs, err := toJsonObjects(arr)
+	if err != nil {
+		return err
+	}
+	return ToParquet(w, objs, opt)
+}
+
+// ToParquetOrdered is like ToParquet, but it reads the key order of every JSON object in arr
+// (see ToCsvOrdered).
+func ToParquetOrdered(w io.Writer, arr []*OrderedObject, opt *ToParquetOption) error {
+	var csvOpt *ToCsvOption
+	if opt != nil {
+		csvOpt = &opt.ToCsvOption
+	}
+	hs, rows, err := csvRecordsOrdered(arr, csvOpt)
+	if err != nil {
+		return err
+	}
+	return writeParquet(w, hs, rows, opt)
+}
+
+// writeParquet writes rows to w as a Parquet file with a column of every header of hs.
+func writeParquet(w io.Writer, hs []string, rows []map[string]any, opt *ToParquetOption) error {
+	var csvOpt *ToCsvOption
+	if opt != nil {
+		csvOpt = &opt.ToCsvOption
+	}
+	pw := NewParquetWriter(w, inferParquetColumns(hs, renameCsvHeader(hs, csvOpt), rows))
+	if opt != nil {
+		pw.Compression = opt.Compression
+		pw.RowGroupSize = opt.RowGroupSize
+	}
+	if err := pw.writeRows(rows, hs); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// InferParquetSchema infers a Parquet column of every key of the flattened JSON objects
+// of arr (see Flatten), in alphabetical order. The type of a column is the type of its
+// non-null values:
+//   - booleans are bool
+//   - integers are int64, and so are float64 whole numbers (numbers of encoding/json
+//     without UseNumber), unless they are beyond the exact integers of a float64
+//   - other numbers are double, and a column of int64 and double values is double,
+//     unless a double cannot hold one of its integers exactly (beyond 2^53)
+//   - other values, integers which overflow int64 and columns of mixed types are string
+//
+// A column with only null values is string. An empty arr has no columns, so ToParquet
+// writes a file with no columns and no rows for it.
+func InferParquetSchema(arr []map[string]any) []ParquetColumn {
+	return inferParquetColumns(CreateCsvHeader(arr, nil), nil, arr)
+}
+
+// inferParquetColumns infers a Parquet column for every key of keys in arr,
+// named by names if it is not nil.
+func inferParquetColumns(keys, names []string, arr []map[string]any) []ParquetColumn {
+	cols := make([]ParquetColumn, len(keys))
+	for i, key := range keys {
+		cols[i].Name = key
+		if names != nil {
+			cols[i].Name = names[i]
+		}
+		inferred, inexact := false, false
+		for _, obj := range arr {
+			val := obj[key]
+			t, ok := parquetTypeOf(val)
+			if !ok {
+				continue
+			}
+			if t == ParquetInt64 {
+				_, isDouble := parquetDouble(indirectValue(val))
+				inexact = inexact || !isDouble
+			}
+			if inferred {
+				t = mergeParquetTypes(cols[i].Type, t)
+			}
+			cols[i].Type = t
+			inferred = true
+		}
+		if cols[i].Type == ParquetDouble && inexact {
+			cols[i].Type = ParquetString
+		}
+	}
+	return cols
+}
+
+// parquetTypeOf returns the Parquet type of val, or false if it is null.
+func parquetTypeOf(val any) (ParquetType, bool) {
+	val = indirectValue(val)
+	if val == nil {
+		return ParquetString, false
+	}
+	if _, ok := val.(bool); ok {
+		return ParquetBool, true
+	}
+	if _, ok := parquetInt64(val); ok {
+		return ParquetInt64, true
+	}
+	if _, ok := parquetDouble(val); ok {
+		return ParquetDouble, true
+	}
+	return ParquetString, true
+}
+
+// mergeParquetTypes returns the type of a column with values of types a and b.
+func mergeParquetTypes(a, b ParquetType) ParquetType {
+	switch {
+	case a == b:
+		return a
+	case (a == ParquetInt64 || a == ParquetDouble) && (b == ParquetInt64 || b == ParquetDouble):
+		return ParquetDouble
+	}
+	return ParquetString
+}
+
+// indirectValue returns the value which val points to, or nil if it is a nil pointer.
+func indirectValue(val any) any {
+	refval := reflect.ValueOf(val)
+	for refval.Kind() == reflect.Pointer || refval.Kind() == reflect.Interface {
+		if refval.IsNil() {
+			return nil
+		}
+		refval = refval.Elem()
+	}
+	if !refval.IsValid() {
+		return nil
+	}
+	return refval.Interface()
+}
+
+// parquetInt64 returns val as an int64 if it is an integer which fits an int64.
+func parquetInt64(val any) (int64, bool) {
+	switch v := val.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), uint64(v) <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float32:
+		return parquetWholeFloat(float64(v))
+	case float64:
+		return parquetWholeFloat(v)
+	case json.Number:
+		n, err := strconv.ParseInt(v.String(), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// parquetWholeFloat returns f as an int64 if it is a whole number which a float64 holds exactly.
+func parquetWholeFloat(f float64) (int64, bool) {
+	if f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// parquetDouble returns val as a float64 if it is a number. Integers which a float64
+// cannot hold exactly are not, since it would lose their digits.
+func parquetDouble(val any) (float64, bool) {
+	switch v := val.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			f, err := v.Float64()
+			return f, err == nil
+		}
+	}
+	if n, ok := parquetInt64(val); ok {
+		f := float64(n)
+		return f, f != math.MaxInt64+1 && int64(f) == n
+	}
+	return 0, false
+}
@@ -0,0 +1,519 @@
+package jsonconv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parquetFile is a Parquet file read by readParquet.
+type parquetFile struct {
+	schema    []ParquetColumn
+	rows      []map[string]any
+	rowGroups int
+}
+
+// readParquet reads a Parquet file written by ParquetWriter, with the values of
+// every row keyed by column name: nil for null, string, int64, float64 or bool.
+func readParquet(t *testing.T, data []byte) parquetFile {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("parquet file should start and end with %s", parquetMagic)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLen : len(data)-8]
+	meta, n, err := decodeThriftStruct(footer)
+	if err != nil || n != footerLen {
+		t.Fatalf("failed to decode file metadata, err: %v", err)
+	}
+
+	// Read schema.
+	var file parquetFile
+	elems := meta[2].([]any)
+	if root := elems[0].(map[int16]any); root[4] != "schema" || root[5] != int64(len(elems)-1) {
+		t.Fatalf("schema should start with a root element of %d children, current: %v", len(elems)-1, root)
+	}
+	types := map[int64]ParquetType{
+		parquetTypeByteArray: ParquetString,
+		parquetTypeInt64:     ParquetInt64,
+		parquetTypeDouble:    ParquetDouble,
+		parquetTypeBoolean:   ParquetBool,
+	}
+	for _, e := range elems[1:] {
+		elem := e.(map[int16]any)
+		col := ParquetColumn{Name: elem[4].(string), Type: types[elem[1].(int64)]}
+		if elem[3] != int64(parquetOptional) {
+			t.Fatalf("column %q should be optional", col.Name)
+		}
+		if _, utf8 := elem[6]; utf8 != (col.Type == ParquetString) {
+			t.Fatalf("only string column should be UTF8, column %q", col.Name)
+		}
+		file.schema = append(file.schema, col)
+	}
+
+	// Read row groups.
+	for _, g := range meta[4].([]any) {
+		group := g.(map[int16]any)
+		numRows := int(group[3].(int64))
+		rows := make([]map[string]any, numRows)
+		for i := range rows {
+			rows[i] = make(map[string]any)
+		}
+		for i, c := range group[1].([]any) {
+			col := file.schema[i]
+			chunk := c.(map[int16]any)[3].(map[int16]any)
+			if chunk[3].([]any)[0] != col.Name || chunk[5] != int64(numRows) {
+				t.Fatalf("column chunk %d should be of column %q, current: %v", i, col.Name, chunk)
+			}
+			values := readParquetPage(t, data[chunk[9].(int64):], col, numRows, chunk[4].(int64))
+			for j, val := range values {
+				rows[j][col.Name] = val
+			}
+		}
+		file.rows = append(file.rows, rows...)
+		file.rowGroups++
+	}
+	if meta[3] != int64(len(file.rows)) {
+		t.Fatalf("file should have %d rows, current: %v", len(file.rows), meta[3])
+	}
+	return file
+}
+
+// readParquetPage reads the values of a data page of col at the beginning of b.
+func readParquetPage(t *testing.T, b []byte, col ParquetColumn, numRows int, codec int64) []any {
+	t.Helper()
+	header, n, err := decodeThriftStruct(b)
+	if err != nil {
+		t.Fatalf("failed to decode page header, err: %v", err)
+	}
+	page := b[n : n+int(header[3].(int64))]
+	if codec == parquetCodecGzip {
+		gr, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			t.Fatalf("failed to decompress page, err: %v", err)
+		}
+		page, _ = io.ReadAll(gr)
+	}
+	if len(page) != int(header[2].(int64)) {
+		t.Fatalf("page of column %q should have %v bytes, current: %d", col.Name, header[2], len(page))
+	}
+
+	// Read definition levels.
+	levelsLen := int(binary.LittleEndian.Uint32(page))
+	levels, values := page[4:4+levelsLen], page[4+levelsLen:]
+	var present []bool
+	for len(levels) > 0 {
+		run, n := binary.Uvarint(levels)
+		if run&1 != 0 {
+			t.Fatalf("definition levels should be RLE runs")
+		}
+		for range run >> 1 {
+			present = append(present, levels[n] == 1)
+		}
+		levels = levels[n+1:]
+	}
+	if len(present) != numRows {
+		t.Fatalf("column %q should have %d definition levels, current: %d", col.Name, numRows, len(present))
+	}
+
+	// Read values.
+	result := make([]any, numRows)
+	bit := 0
+	for i := range result {
+		if !present[i] {
+			continue
+		}
+		switch col.Type {
+		case ParquetString:
+			l := int(binary.LittleEndian.Uint32(values))
+			result[i] = string(values[4 : 4+l])
+			values = values[4+l:]
+		case ParquetInt64:
+			result[i] = int64(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case ParquetDouble:
+			result[i] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case ParquetBool:
+			result[i] = values[bit/8]&(1<<(bit%8)) != 0
+			bit++
+		}
+	}
+	return result
+}
+
+func TestParquetWriter(t *testing.T) {
+	// Prepare
+	schema := []ParquetColumn{
+		{Name: "id", Type: ParquetInt64},
+		{Name: "name", Type: ParquetString},
+		{Name: "score", Type: ParquetDouble},
+		{Name: "active", Type: ParquetBool},
+	}
+	rows := []map[string]any{
+		{"id": json.Number("1"), "name": "Tuấn", "score": json.Number("1.5"), "active": true},
+		{"id": 2, "name": json.Number("007"), "score": 2, "active": false, "other": "ignored"},
+		{"id": nil},
+		{"id": int64(math.MaxInt64), "name": "", "score": -0.25, "active": true},
+		{"id": float64(5), "name": map[string]any{"a": 1}, "active": true},
+	}
+	expected := []map[string]any{
+		{"id": int64(1), "name": "Tuấn", "score": 1.5, "active": true},
+		{"id": int64(2), "name": "007", "score": 2.0, "active": false},
+		{"id": nil, "name": nil, "score": nil, "active": nil},
+		{"id": int64(math.MaxInt64), "name": "", "score": -0.25, "active": true},
+		{"id": int64(5), "name": `{"a":1}`, "score": nil, "active": true},
+	}
+
+	for _, compression := range []ParquetCompression{ParquetUncompressed, ParquetGzip} {
+		buf := &bytes.Buffer{}
+		wr := NewParquetWriter(buf, schema)
+		wr.Compression = compression
+		wr.RowGroupSize = 2
+
+		// Process
+		err := wr.Write(rows[:1])
+		if err == nil {
+			err = wr.Write(rows[1:])
+		}
+		if err == nil {
+			err = wr.Close()
+		}
+
+		// Check
+		if err != nil {
+			t.Fatalf("failed to write parquet, err: %v", err)
+		}
+		file := readParquet(t, buf.Bytes())
+		if !reflect.DeepEqual(file.schema, schema) {
+			t.Fatalf("schema is incorrect, %v is not equal expected value %v", file.schema, schema)
+		}
+		if !reflect.DeepEqual(file.rows, expected) {
+			t.Fatalf("rows are incorrect, %v is not equal expected value %v", file.rows, expected)
+		}
+		if file.rowGroups != 3 {
+			t.Fatalf("file should have 3 row groups, current: %d", file.rowGroups)
+		}
+	}
+}
+
+func TestParquetWriter_InferSchema(t *testing.T) {
+	// Prepare
+	rows := []map[string]any{{"a": 1.0, "b": "x"}, {"a": 2.5, "c": true}}
+	buf := &bytes.Buffer{}
+	wr := NewParquetWriter(buf, nil)
+
+	// Process
+	err := wr.Write(nil)
+	if err == nil {
+		err = wr.Write(rows)
+	}
+	if err == nil {
+		err = wr.Close()
+	}
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	file := readParquet(t, buf.Bytes())
+	expected := []ParquetColumn{{"a", ParquetDouble}, {"b", ParquetString}, {"c", ParquetBool}}
+	if !reflect.DeepEqual(file.schema, expected) {
+		t.Fatalf("schema is incorrect, %v is not equal expected value %v", file.schema, expected)
+	}
+	if file.rows[0]["a"] != 1.0 || file.rows[1]["c"] != true || file.rows[1]["b"] != nil {
+		t.Fatalf("rows are incorrect, current: %v", file.rows)
+	}
+}
+
+func TestParquetWriter_Golden(t *testing.T) {
+	// Prepare
+	// The expected file is encoded by hand from the Parquet format (parquet.thrift and
+	// Encodings.md), independently of the Thrift encoder of the writer and of readParquet.
+	schema := []ParquetColumn{{Name: "a", Type: ParquetInt64}, {Name: "b", Type: ParquetString}}
+	rows := []map[string]any{{"a": 1}, {"b": "xy"}}
+	var expected []byte
+	for _, part := range [][]byte{
+		[]byte("PAR1"),
+
+		// Column chunk of "a" at offset 4: PageHeader, then the page of 16 bytes.
+		{0x15, 0x00, 0x15, 0x20, 0x15, 0x20},                               // type DATA_PAGE, uncompressed and compressed size 16
+		{0x2c, 0x15, 0x04, 0x15, 0x00, 0x15, 0x06, 0x15, 0x06, 0x00, 0x00}, // DataPageHeader: 2 values, PLAIN, RLE levels
+		{0x04, 0x00, 0x00, 0x00, 0x02, 0x01, 0x02, 0x00},                   // 4 bytes of levels: run of one 1, run of one 0
+		{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},                   // int64 1
+
+		// Column chunk of "b" at offset 37: PageHeader, then the page of 14 bytes.
+		{0x15, 0x00, 0x15, 0x1c, 0x15, 0x1c},
+		{0x2c, 0x15, 0x04, 0x15, 0x00, 0x15, 0x06, 0x15, 0x06, 0x00, 0x00},
+		{0x04, 0x00, 0x00, 0x00, 0x02, 0x00, 0x02, 0x01}, // run of one 0, run of one 1
+		{0x02, 0x00, 0x00, 0x00, 'x', 'y'},               // byte array "xy"
+
+		// FileMetaData: version 1, then a list of 3 SchemaElements.
+		{0x15, 0x02, 0x19, 0x3c},
+		{0x48, 0x06, 's', 'c', 'h', 'e', 'm', 'a', 0x15, 0x04, 0x00},                        // root "schema" of 2 children
+		{0x15, 0x04, 0x25, 0x02, 0x18, 0x01, 'a', 0x00},                                     // INT64, OPTIONAL, "a"
+		{0x15, 0x0c, 0x25, 0x02, 0x18, 0x01, 'b', 0x25, 0x00, 0x4c, 0x1c, 0x00, 0x00, 0x00}, // BYTE_ARRAY, OPTIONAL, "b", UTF8, STRING
+
+		// num_rows 2, then a list of 1 RowGroup of 2 ColumnChunks.
+		{0x16, 0x04, 0x19, 0x1c, 0x19, 0x2c},
+		{0x26, 0x08, 0x1c, 0x15, 0x04, 0x19, 0x25, 0x00, 0x06, 0x19, 0x18, 0x01, 'a'}, // offset 4, INT64, [PLAIN, RLE], ["a"]
+		{0x15, 0x00, 0x16, 0x04, 0x16, 0x42, 0x16, 0x42, 0x26, 0x08, 0x00, 0x00},      // UNCOMPRESSED, 2 values, 33 bytes, page at 4
+		{0x26, 0x4a, 0x1c, 0x15, 0x0c, 0x19, 0x25, 0x00, 0x06, 0x19, 0x18, 0x01, 'b'}, // offset 37, BYTE_ARRAY, [PLAIN, RLE], ["b"]
+		{0x15, 0x00, 0x16, 0x04, 0x16, 0x3e, 0x16, 0x3e, 0x26, 0x4a, 0x00, 0x00},      // UNCOMPRESSED, 2 values, 31 bytes, page at 37
+		{0x16, 0x80, 0x01, 0x16, 0x04, 0x00},                                          // total_byte_size 64, num_rows 2
+
+		// created_by "jsonconv", then the length of FileMetaData, 110 bytes.
+		{0x28, 0x08, 'j', 's', 'o', 'n', 'c', 'o', 'n', 'v', 0x00},
+		{0x6e, 0x00, 0x00, 0x00},
+		[]byte("PAR1"),
+	} {
+		expected = append(expected, part...)
+	}
+	buf := &bytes.Buffer{}
+	wr := NewParquetWriter(buf, schema)
+
+	// Process
+	err := wr.Write(rows)
+	if err == nil {
+		err = wr.Close()
+	}
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("parquet file is incorrect,\n% x\nis not equal expected value\n% x", buf.Bytes(), expected)
+	}
+}
+
+func TestParquetWriter_Empty(t *testing.T) {
+	// Prepare
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := NewParquetWriter(buf, []ParquetColumn{{Name: "id", Type: ParquetInt64}}).Close()
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	file := readParquet(t, buf.Bytes())
+	if len(file.schema) != 1 || len(file.rows) != 0 || file.rowGroups != 0 {
+		t.Fatalf("file should have a column and no rows, current: %v", file)
+	}
+}
+
+func TestParquetWriter_Errors(t *testing.T) {
+	// Prepare
+	cases := []struct {
+		schema []ParquetColumn
+		rows   []map[string]any
+		err    string
+	}{
+		{[]ParquetColumn{{"id", ParquetInt64}}, []map[string]any{{"id": "1"}}, `cannot write 1 to int64 parquet column "id"`},
+		{[]ParquetColumn{{"id", ParquetInt64}}, []map[string]any{{"id": json.Number("1.5")}}, `cannot write 1.5 to int64 parquet column "id"`},
+		{[]ParquetColumn{{"id", ParquetInt64}}, []map[string]any{{"id": uint64(math.MaxUint64)}}, `cannot write 18446744073709551615 to int64 parquet column "id"`},
+		{[]ParquetColumn{{"n", ParquetDouble}}, []map[string]any{{"n": true}}, `cannot write true to double parquet column "n"`},
+		{[]ParquetColumn{{"n", ParquetDouble}}, []map[string]any{{"n": json.Number("9007199254740993")}}, `cannot write 9007199254740993 to double parquet column "n"`},
+		{[]ParquetColumn{{"b", ParquetBool}}, []map[string]any{{"b": 1}}, `cannot write 1 to bool parquet column "b"`},
+		{[]ParquetColumn{{"a", ParquetBool}, {"a", ParquetString}}, nil, `duplicate parquet column "a"`},
+		{[]ParquetColumn{{"a", ParquetType(9)}}, nil, `unsupported type ParquetType(9) of parquet column "a"`},
+	}
+
+	for _, c := range cases {
+		// Process
+		err := NewParquetWriter(io.Discard, c.schema).Write(c.rows)
+
+		// Check
+		if err == nil || err.Error() != c.err {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", c.err, err)
+		}
+	}
+
+	wr := NewParquetWriter(io.Discard, nil)
+	if err := wr.Close(); err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	if err := wr.Write(nil); err == nil || err.Error() != "parquet writer is closed" {
+		t.Fatalf("It should throw an error with message: parquet writer is closed\ncurrent: %v", err)
+	}
+}
+
+func TestInferParquetSchema(t *testing.T) {
+	// Prepare
+	var ptr *int
+	n := 3
+	arr := []map[string]any{
+		{"int": json.Number("1"), "float": 1.5, "whole": 2.0, "mixed": 1, "text": "a", "bool": true, "null": nil, "big": json.Number("12345678901234567890"), "ptr": ptr},
+		{"int": 2, "float": json.Number("1e3"), "whole": float32(3), "mixed": json.Number("2.5"), "text": 1, "bool": false, "ptr": &n},
+		{"huge": 1e300, "nested": []any{1}, "number": json.Number("2"), "exact": json.Number("9007199254740992"), "inexact": int64(1<<53 + 1)},
+		{"number": 0.5, "exact": 0.5, "inexact": 0.5},
+	}
+
+	// Process
+	schema := InferParquetSchema(arr)
+
+	// Check
+	expected := []ParquetColumn{
+		{"big", ParquetString},
+		{"bool", ParquetBool},
+		{"exact", ParquetDouble},
+		{"float", ParquetDouble},
+		{"huge", ParquetDouble},
+		{"inexact", ParquetString},
+		{"int", ParquetInt64},
+		{"mixed", ParquetDouble},
+		{"nested", ParquetString},
+		{"null", ParquetString},
+		{"number", ParquetDouble},
+		{"ptr", ParquetInt64},
+		{"text", ParquetString},
+		{"whole", ParquetInt64},
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Fatalf("schema is incorrect, %v is not equal expected value %v", schema, expected)
+	}
+}
+
+func TestToParquet(t *testing.T) {
+	// Prepare
+	raw := `[
+		{"id": 1, "user": {"name": "Jon", "age": 30}, "tags": ["a", "b"], "paid": true},
+		{"id": 2, "user": {"name": "Ana", "age": null}, "tags": [], "paid": false, "note": "vip"}
+	]`
+	var arr []map[string]any
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&arr); err != nil {
+		t.Fatalf("failed to decode JSON, err: %v", err)
+	}
+	opt := &ToParquetOption{
+		ToCsvOption: ToCsvOption{
+			FlattenOption:  DefaultFlattenOption,
+			BaseHeaders:    []string{"id"},
+			ExcludeColumns: []string{"tags*"},
+			HeaderNames:    map[string]string{"user__name": "name"},
+		},
+		Compression: ParquetGzip,
+	}
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := ToParquet(buf, arr, opt)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	file := readParquet(t, buf.Bytes())
+	expectedSchema := []ParquetColumn{
+		{"id", ParquetInt64},
+		{"note", ParquetString},
+		{"paid", ParquetBool},
+		{"user__age", ParquetInt64},
+		{"name", ParquetString},
+	}
+	if !reflect.DeepEqual(file.schema, expectedSchema) {
+		t.Fatalf("schema is incorrect, %v is not equal expected value %v", file.schema, expectedSchema)
+	}
+	expected := []map[string]any{
+		{"id": int64(1), "note": nil, "paid": true, "user__age": int64(30), "name": "Jon"},
+		{"id": int64(2), "note": "vip", "paid": false, "user__age": nil, "name": "Ana"},
+	}
+	if !reflect.DeepEqual(file.rows, expected) {
+		t.Fatalf("rows are incorrect, %v is not equal expected value %v", file.rows, expected)
+	}
+}
+
+func TestToParquet_Empty(t *testing.T) {
+	// Prepare
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := ToParquet(buf, nil, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	file := readParquet(t, buf.Bytes())
+	if len(file.schema) != 0 || len(file.rows) != 0 || file.rowGroups != 0 {
+		t.Fatalf("file should have no columns and no rows, current: %v", file)
+	}
+}
+
+func TestToParquet_MixedNumbers(t *testing.T) {
+	// Prepare
+	arr := []map[string]any{{"n": json.Number("9007199254740993")}, {"n": json.Number("1.5")}}
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := ToParquet(buf, arr, nil)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	file := readParquet(t, buf.Bytes())
+	expected := []map[string]any{{"n": "9007199254740993"}, {"n": "1.5"}}
+	if !reflect.DeepEqual(file.rows, expected) {
+		t.Fatalf("rows are incorrect, %v is not equal expected value %v", file.rows, expected)
+	}
+}
+
+func TestToParquet_KeyCollisionError(t *testing.T) {
+	// Prepare
+	opt := &ToParquetOption{ToCsvOption: ToCsvOption{
+		FlattenOption: &FlattenOption{Level: FlattenLevelUnlimited, Gap: "__", KeyCollision: KeyCollisionError},
+	}}
+	arr := []map[string]any{{"a": map[string]any{"b": 1}, "a__b": 2}}
+
+	// Process
+	err := ToParquet(&bytes.Buffer{}, arr, opt)
+
+	// Check
+	if !errors.Is(err, ErrFlattenCollision) {
+		t.Fatalf("It should throw ErrFlattenCollision, current: %v", err)
+	}
+}
+
+func TestToParquetStructs(t *testing.T) {
+	// Prepare
+	type item struct {
+		Sku   string  `json:"sku"`
+		Price float64 `json:"price"`
+		Qty   *int    `json:"qty"`
+	}
+	qty := 2
+	arr := []item{{Sku: "b-1", Price: 9.5, Qty: &qty}, {Sku: "a-2", Price: 10}}
+	opt := &ToParquetOption{ToCsvOption: ToCsvOption{HeaderOrder: HeaderOrderSource}}
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := ToParquetStructs(buf, arr, opt)
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write parquet, err: %v", err)
+	}
+	file := readParquet(t, buf.Bytes())
+	expectedSchema := []ParquetColumn{{"sku", ParquetString}, {"price", ParquetDouble}, {"qty", ParquetInt64}}
+	if !reflect.DeepEqual(file.schema, expectedSchema) {
+		t.Fatalf("schema is incorrect, %v is not equal expected value %v", file.schema, expectedSchema)
+	}
+	expected := []map[string]any{
+		{"sku": "b-1", "price": 9.5, "qty": int64(2)},
+		{"sku": "a-2", "price": 10.0, "qty": nil},
+	}
+	if !reflect.DeepEqual(file.rows, expected) {
+		t.Fatalf("rows are incorrect, %v is not equal expected value %v", file.rows, expected)
+	}
+}
@@ -0,0 +1,416 @@
+package jsonconv
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// An XlsxWriter writes sheets of an Excel workbook (XLSX) to w.
+// Close must be called after the last sheet to complete the workbook.
+type XlsxWriter struct {
+	writer *zip.Writer
+	sheets []string
+	closed bool
+
+	// True to style the first row of every sheet as headers, in bold with a fill.
+	// Set to true by default in NewXlsxWriter
+	HeaderStyle bool
+
+	// Number of top rows and left columns which stay visible when scrolling.
+	// FreezeRows is set to 1 by default in NewXlsxWriter to freeze the headers
+	FreezeRows    int
+	FreezeColumns int
+
+	// True to write numbers, booleans and RFC 3339 dates in string cells of WriteSheet
+	// as typed cells (see InferXlsxValue). Otherwise, they are written as text.
+	// Set to true by default in NewXlsxWriter
+	InferTypes bool
+}
+
+// Limits of Excel workbooks.
+const (
+	// XlsxMaxSheetNameLength is the maximum length of sheet names.
+	XlsxMaxSheetNameLength = 31
+	// XlsxMaxRows is the maximum number of rows of a sheet, including the header row.
+	XlsxMaxRows = 1048576
+	// XlsxMaxColumns is the maximum number of columns of a sheet.
+	XlsxMaxColumns = 16384
+	// XlsxMaxCellLength is the maximum number of characters of a cell.
+	XlsxMaxCellLength = 32767
+)
+
+// ErrXlsxLimit is returned when data exceeds a limit of Excel workbooks.
+var ErrXlsxLimit = errors.New("exceeds Excel limits")
+
+// Styles of cells, the indices of cellXfs in styles.xml.
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeader
+	xlsxStyleDateTime
+	xlsxStyleDate
+)
+
+// xlsxEpoch is the day 0 of Excel dates, which count days from it.
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// NewXlsxWriter returns a new XlsxWriter that writes to w.
+func NewXlsxWriter(w io.Writer) *XlsxWriter {
+	return &XlsxWriter{
+		writer:      zip.NewWriter(w),
+		HeaderStyle: true,
+		FreezeRows:  1,
+		InferTypes:  true,
+	}
+}
+
+// WriteSheet writes a sheet named name with the rows of data, like the ones of ToCsv.
+// The first row is headers, which are always written as text. The name is made
+// valid for Excel: characters []:*?/\ are replaced with '_', it is cut to
+// XlsxMaxSheetNameLength characters and a number is appended if another sheet
+// has the same name. Data exceeding the limits of Excel is an ErrXlsxLimit error,
+// see WriteTypedSheet.
+func (w *XlsxWriter) WriteSheet(name string, data [][]string) error {
+	rows := make([][]any, len(data))
+	for i, record := range data {
+		row := make([]any, len(record))
+		for j, cell := range record {
+			if w.InferTypes && i > 0 {
+				row[j] = InferXlsxValue(cell)
+			} else {
+				row[j] = cell
+			}
+		}
+		rows[i] = row
+	}
+	return w.WriteTypedSheet(name, rows)
+}
+
+// WriteTypedSheet is like WriteSheet for typed cells. A cell can be nil (empty),
+// a string, a bool, a number (json.Number or a Go number) or a time.Time.
+// Other values are written as text formatted by FormatCsvValue.
+// It returns ErrXlsxLimit if data has more than XlsxMaxRows rows, a row has more than
+// XlsxMaxColumns cells or a text cell has more than XlsxMaxCellLength characters.
+func (w *XlsxWriter) WriteTypedSheet(name string, data [][]any) error {
+	if w.closed {
+		return errors.New("xlsx writer is closed")
+	}
+	if len(data) > XlsxMaxRows {
+		return fmt.Errorf("%w: %d rows, the maximum is %d", ErrXlsxLimit, len(data), XlsxMaxRows)
+	}
+	for i, row := range data {
+		if len(row) > XlsxMaxColumns {
+			return fmt.Errorf("%w: %d columns in row %d, the maximum is %d", ErrXlsxLimit, len(row), i+1, XlsxMaxColumns)
+		}
+	}
+	name = w.sheetName(name)
+	fw, err := w.writer.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheets)+1))
+	if err != nil {
+		return err
+	}
+	w.sheets = append(w.sheets, name)
+
+	bw := bufio.NewWriter(fw)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if pane := w.pane(); pane != "" {
+		bw.WriteString(`<sheetViews><sheetView workbookViewId="0">` + pane + `</sheetView></sheetViews>`)
+	}
+	bw.WriteString(`<sheetData>`)
+	for i, row := range data {
+		fmt.Fprintf(bw, `<row r="%d">`, i+1)
+		for j, val := range row {
+			style := xlsxStyleDefault
+			if i == 0 && w.HeaderStyle {
+				style = xlsxStyleHeader
+			}
+			if err := writeXlsxCell(bw, xlsxCellRef(j, i), val, style); err != nil {
+				return err
+			}
+		}
+		bw.WriteString(`</row>`)
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	return bw.Flush()
+}
+
+// Close writes the parts of the workbook which list its sheets and finishes it.
+// It does not close the underlying writer. A workbook needs at least one sheet,
+// so an empty one is written if there is none.
+func (w *XlsxWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	if len(w.sheets) == 0 {
+		if err := w.WriteSheet("Sheet1", nil); err != nil {
+			return err
+		}
+	}
+	w.closed = true
+
+	var ct, wb, rels strings.Builder
+	ct.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	wb.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, name := range w.sheets {
+		n := i + 1
+		fmt.Fprintf(&ct, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&wb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	ct.WriteString(`</Types>`)
+	wb.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`, len(w.sheets)+1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", ct.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", wb.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		fw, err := w.writer.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, p.content); err != nil {
+			return err
+		}
+	}
+	return w.writer.Close()
+}
+
+// sheetName returns name made valid and unique among the sheets of w.
+func (w *XlsxWriter) sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", len(w.sheets)+1)
+	}
+
+	unique := truncateRunes(name, XlsxMaxSheetNameLength)
+	for n := 2; w.hasSheet(unique); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		unique = truncateRunes(name, XlsxMaxSheetNameLength-len(suffix)) + suffix
+	}
+	return unique
+}
+
+// hasSheet reports whether w has a sheet named name, ignoring case like Excel does.
+func (w *XlsxWriter) hasSheet(name string) bool {
+	for _, s := range w.sheets {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pane returns the XML element of the frozen panes of w, or "" if there is none.
+func (w *XlsxWriter) pane() string {
+	rows, cols := max(w.FreezeRows, 0), max(w.FreezeColumns, 0)
+	if rows == 0 && cols == 0 {
+		return ""
+	}
+	active := "bottomRight"
+	switch {
+	case cols == 0:
+		active = "bottomLeft"
+	case rows == 0:
+		active = "topRight"
+	}
+	return fmt.Sprintf(`<pane xSplit="%d" ySplit="%d" topLeftCell="%s" activePane="%s" state="frozen"/><selection pane="%s"/>`,
+		cols, rows, xlsxCellRef(cols, rows), active, active)
+}
+
+// InferXlsxValue returns s as a typed cell of an XLSX sheet: a bool for "true" and "false",
+// a json.Number for a JSON number which Excel keeps exactly (at most 15 significant digits,
+// so numbers like IDs and ones with leading zeros stay text), a time.Time for an RFC 3339
+// date or UTC date-time, or s itself. Date-times with another UTC offset stay text,
+// since Excel dates have no time zone.
+func InferXlsxValue(s string) any {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "":
+		return s
+	}
+	if isExactXlsxNumber(s) {
+		return json.Number(s)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		if _, offset := t.Zone(); offset == 0 {
+			return t
+		}
+		return s
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t
+	}
+	return s
+}
+
+// isExactXlsxNumber reports whether s is a JSON number with at most 15 significant digits,
+// the precision of Excel numbers.
+func isExactXlsxNumber(s string) bool {
+	first, last := s[0], s[len(s)-1]
+	if !(first == '-' || isDigit(first)) || !isDigit(last) || !json.Valid([]byte(s)) {
+		return false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err != nil || math.IsInf(f, 0) {
+		return false
+	}
+	mantissa, _, _ := strings.Cut(strings.ToLower(s), "e")
+	digits := strings.TrimLeft(strings.NewReplacer("-", "", ".", "").Replace(mantissa), "0")
+	if strings.Contains(mantissa, ".") {
+		digits = strings.TrimRight(digits, "0")
+	}
+	return len(digits) <= 15
+}
+
+// writeXlsxCell writes the XML element of the cell at ref with val and style.
+// It returns ErrXlsxLimit if the text of the cell is longer than XlsxMaxCellLength.
+func writeXlsxCell(w *bufio.Writer, ref string, val any, style int) error {
+	attrs := fmt.Sprintf(`r="%s"`, ref)
+	if style != xlsxStyleDefault {
+		attrs += fmt.Sprintf(` s="%d"`, style)
+	}
+	var err error
+	text := func(s string) {
+		if n := utf8.RuneCountInString(s); n > XlsxMaxCellLength {
+			err = fmt.Errorf("%w: %d characters in cell %s, the maximum is %d", ErrXlsxLimit, n, ref, XlsxMaxCellLength)
+			return
+		}
+		fmt.Fprintf(w, `<c %s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, attrs, xmlEscape(s))
+	}
+	number := func(s string) {
+		fmt.Fprintf(w, `<c %s><v>%s</v></c>`, attrs, s)
+	}
+
+	switch v := val.(type) {
+	case nil:
+		if style != xlsxStyleDefault {
+			fmt.Fprintf(w, `<c %s/>`, attrs)
+		}
+	case string:
+		if v != "" || style != xlsxStyleDefault {
+			text(v)
+		}
+	case bool:
+		b := "0"
+		if v {
+			b = "1"
+		}
+		fmt.Fprintf(w, `<c %s t="b"><v>%s</v></c>`, attrs, b)
+	case json.Number:
+		if f, err := v.Float64(); err == nil && !math.IsInf(f, 0) {
+			number(v.String())
+		} else {
+			text(v.String())
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			text(FormatCsvValue(v, ""))
+		} else {
+			number(strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	case float32:
+		number(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		number(fmt.Sprint(v))
+	case time.Time:
+		days, ok := xlsxSerialDate(v)
+		if !ok {
+			text(v.Format(time.RFC3339Nano))
+			break
+		}
+		if style == xlsxStyleDefault {
+			style = xlsxStyleDateTime
+			if days == math.Trunc(days) {
+				style = xlsxStyleDate
+			}
+			attrs += fmt.Sprintf(` s="%d"`, style)
+		}
+		number(strconv.FormatFloat(days, 'f', -1, 64))
+	default:
+		text(FormatCsvValue(v, ""))
+	}
+	return err
+}
+
+// xlsxSerialDate returns the wall clock of t as a number of days since xlsxEpoch,
+// or false if Excel cannot show it (before March 1900, due to its leap year bug).
+func xlsxSerialDate(t time.Time) (float64, bool) {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if wall.Before(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)) || wall.Year() > 9999 {
+		return 0, false
+	}
+	d := wall.Sub(xlsxEpoch)
+	days := float64(d/(24*time.Hour)) + float64(d%(24*time.Hour))/float64(24*time.Hour)
+	return days, true
+}
+
+// xlsxCellRef returns the reference of the cell at zero-based col and row, e.g. "B3".
+func xlsxCellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+// xmlEscape returns s escaped for XML text and attributes. Characters which are
+// invalid in XML are replaced with the Unicode replacement character.
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// truncateRunes returns the first n runes of s.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// xlsxStyles is the styles part of workbooks. Its cellXfs are indexed by the xlsxStyle constants.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
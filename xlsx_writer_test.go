@@ -0,0 +1,254 @@
+package jsonconv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readXlsxParts returns the contents of the parts of the XLSX workbook data,
+// checking that every part is well-formed XML.
+func readXlsxParts(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open XLSX workbook, err: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s, err: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(r)
+		r.Close()
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is invalid XML, err: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = string(b)
+	}
+	return parts
+}
+
+func TestXlsxWriter(t *testing.T) {
+	// Prepare
+	data := [][]string{
+		{"id", "zip", "score", "is active", "created", "note"},
+		{"1", "007", "1.5", "true", "2024-01-02T03:04:05Z", "<Tuấn & Jon>"},
+		{"12345678901234567890", "", "-100", "false", "2024-01-02", ""},
+	}
+	buf := &bytes.Buffer{}
+	wr := NewXlsxWriter(buf)
+
+	// Process
+	err := wr.WriteSheet("orders", data)
+	if err == nil {
+		err = wr.Close()
+	}
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write XLSX, err: %v", err)
+	}
+	parts := readXlsxParts(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, exist := parts[name]; !exist {
+			t.Fatalf("XLSX workbook should have part %s", name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="orders" sheetId="1" r:id="rId1"/>`) {
+		t.Fatalf("workbook should have sheet orders, current: %s", parts["xl/workbook.xml"])
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	expected := []string{
+		`<pane xSplit="0" ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`,
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">007</t></is></c>`,
+		`<c r="C2"><v>1.5</v></c>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+		`<c r="E2" s="2"><v>45293.12783564815</v></c>`,
+		`<c r="F2" t="inlineStr"><is><t xml:space="preserve">&lt;Tuấn &amp; Jon&gt;</t></is></c>`,
+		`<c r="A3" t="inlineStr"><is><t xml:space="preserve">12345678901234567890</t></is></c>`,
+		`<c r="D3" t="b"><v>0</v></c>`,
+		`<c r="E3" s="3"><v>45293</v></c>`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(sheet, exp) {
+			t.Fatalf("sheet should contain %s\ncurrent: %s", exp, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="B3"`) {
+		t.Fatalf("sheet should not contain empty cells, current: %s", sheet)
+	}
+}
+
+func TestXlsxWriter_Options(t *testing.T) {
+	// Prepare
+	buf := &bytes.Buffer{}
+	wr := NewXlsxWriter(buf)
+	wr.HeaderStyle = false
+	wr.FreezeRows = 1
+	wr.FreezeColumns = 2
+	wr.InferTypes = false
+
+	// Process
+	err := wr.WriteSheet("a", [][]string{{"id", "score"}, {"1", "true"}})
+	if err == nil {
+		err = wr.WriteTypedSheet("b", [][]any{{"id", "at"}, {json.Number("2"), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, {nil, 1.5}})
+	}
+	if err == nil {
+		err = wr.Close()
+	}
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write XLSX, err: %v", err)
+	}
+	parts := readXlsxParts(t, buf.Bytes())
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, exp := range []string{
+		`<pane xSplit="2" ySplit="1" topLeftCell="C2" activePane="bottomRight" state="frozen"/>`,
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">true</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, exp) {
+			t.Fatalf("sheet should contain %s\ncurrent: %s", exp, sheet)
+		}
+	}
+	sheet = parts["xl/worksheets/sheet2.xml"]
+	for _, exp := range []string{`<c r="A2"><v>2</v></c>`, `<c r="B2" s="3"><v>45293</v></c>`, `<c r="B3"><v>1.5</v></c>`} {
+		if !strings.Contains(sheet, exp) {
+			t.Fatalf("sheet should contain %s\ncurrent: %s", exp, sheet)
+		}
+	}
+	if err := wr.WriteSheet("c", nil); err == nil {
+		t.Fatalf("It should throw an error when writing to a closed writer")
+	}
+}
+
+func TestXlsxWriter_NoSheet(t *testing.T) {
+	// Prepare
+	buf := &bytes.Buffer{}
+
+	// Process
+	err := NewXlsxWriter(buf).Close()
+
+	// Check
+	if err != nil {
+		t.Fatalf("failed to write XLSX, err: %v", err)
+	}
+	parts := readXlsxParts(t, buf.Bytes())
+	if _, exist := parts["xl/worksheets/sheet1.xml"]; !exist {
+		t.Fatalf("XLSX workbook should have an empty sheet")
+	}
+}
+
+func TestXlsxWriter_SheetNames(t *testing.T) {
+	// Prepare
+	names := []string{"daily/2024:01", "Orders", "orders", "'quoted'", "", strings.Repeat("x", 40), strings.Repeat("x", 40)}
+	wr := NewXlsxWriter(io.Discard)
+
+	// Process
+	for _, name := range names {
+		if err := wr.WriteSheet(name, nil); err != nil {
+			t.Fatalf("failed to write sheet, err: %v", err)
+		}
+	}
+
+	// Check
+	expected := []string{"daily_2024_01", "Orders", "orders (2)", "quoted", "Sheet5", strings.Repeat("x", 31), strings.Repeat("x", 27) + " (2)"}
+	if !reflect.DeepEqual(wr.sheets, expected) {
+		t.Fatalf("sheet names are incorrect, %q is not equal expected value %q", wr.sheets, expected)
+	}
+}
+
+func TestXlsxWriter_Limits(t *testing.T) {
+	// Prepare
+	cases := map[string][][]any{
+		"exceeds Excel limits: 1048577 rows, the maximum is 1048576":              make([][]any, XlsxMaxRows+1),
+		"exceeds Excel limits: 16385 columns in row 2, the maximum is 16384":      {{"id"}, make([]any, XlsxMaxColumns+1)},
+		"exceeds Excel limits: 32768 characters in cell B2, the maximum is 32767": {{"id", "note"}, {1, strings.Repeat("x", XlsxMaxCellLength+1)}},
+		"exceeds Excel limits: 32768 characters in cell A1, the maximum is 32767": {{strings.Repeat("ấ", XlsxMaxCellLength+1)}},
+		"exceeds Excel limits: 32768 characters in cell A2, the maximum is 32767": {{"id"}, {json.Number(strings.Repeat("9", XlsxMaxCellLength+1))}},
+	}
+
+	for expMsg, data := range cases {
+		// Process
+		err := NewXlsxWriter(io.Discard).WriteTypedSheet("Sheet1", data)
+
+		// Check
+		if !errors.Is(err, ErrXlsxLimit) || err.Error() != expMsg {
+			t.Fatalf("It should throw an error with message: %s\ncurrent: %v", expMsg, err)
+		}
+	}
+
+	// Process
+	err := NewXlsxWriter(io.Discard).WriteTypedSheet("Sheet1", [][]any{{strings.Repeat("ấ", XlsxMaxCellLength)}, make([]any, XlsxMaxColumns)})
+
+	// Check
+	if err != nil {
+		t.Fatalf("data within the limits should be written, err: %v", err)
+	}
+}
+
+func TestInferXlsxValue(t *testing.T) {
+	// Prepare
+	cases := map[string]any{
+		"true":                      true,
+		"false":                     false,
+		"":                          "",
+		"-1.5e3":                    json.Number("-1.5e3"),
+		"123456789012345":           json.Number("123456789012345"),
+		"1234567890123456":          "1234567890123456",
+		"0.100000000000000":         json.Number("0.100000000000000"),
+		"007":                       "007",
+		"1e400":                     "1e400",
+		"2024-01-02":                time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"2024-01-02T03:04:05+00:00": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02T03:04:05+07:00": "2024-01-02T03:04:05+07:00",
+		"Jon Doe":                   "Jon Doe",
+	}
+
+	for s, expected := range cases {
+		// Process
+		val := InferXlsxValue(s)
+
+		// Check
+		if tm, ok := expected.(time.Time); ok {
+			if v, ok := val.(time.Time); !ok || !v.Equal(tm) {
+				t.Fatalf("%q should be inferred as %v, current: %v", s, expected, val)
+			}
+			continue
+		}
+		if val != expected {
+			t.Fatalf("%q should be inferred as %#v, current: %#v", s, expected, val)
+		}
+	}
+}
+
+func TestXlsxCellRef(t *testing.T) {
+	// Prepare
+	cases := map[[2]int]string{{0, 0}: "A1", {25, 1}: "Z2", {26, 9}: "AA10", {701, 0}: "ZZ1", {702, 0}: "AAA1"}
+
+	for pos, expected := range cases {
+		// Process
+		ref := xlsxCellRef(pos[0], pos[1])
+
+		// Check
+		if ref != expected {
+			t.Fatalf("cell %v should have reference %s, current: %s", pos, expected, ref)
+		}
+	}
+}